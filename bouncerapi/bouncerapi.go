@@ -0,0 +1,8 @@
+// Package bouncerapi has what the bouncer server and its clients share
+// without depending on each other: the errors sent as response bodies, and
+// the headers of the HTTP API.
+package bouncerapi
+
+// FencingTokenHeader carries the fencing token of a successful acquire. The
+// body is still just the key.
+const FencingTokenHeader = "X-Fencing-Token"
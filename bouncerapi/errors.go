@@ -0,0 +1,35 @@
+package bouncerapi
+
+import (
+	"errors"
+)
+
+var (
+	ErrInvalidSize     = errors.New("request: 'size' must be a positive non-zero integer")
+	ErrInvalidLimit    = errors.New("request: 'limit' must be between 1 and 1000")
+	ErrInvalidCount    = errors.New("request: 'count' must be between 1 and the bucket size")
+	ErrInvalidRate     = errors.New("request: 'rate' and 'per' must be positive, and 'rate' at most 10000 for the sliding window")
+	ErrInvalidAlgo     = errors.New("request: 'algo' must be 'gcra' or 'sliding'")
+	ErrInvalidBurst    = errors.New("request: 'burst' must be at most 1000 times 'rate'")
+	ErrInvalidConfig   = errors.New("request: invalid config")
+	ErrInvalidInterval = errors.New("request: 'interval' must be positive")
+	ErrNotFound        = errors.New("request: object not found")
+	ErrTimedOut        = errors.New("timeout: 'maxwait' exceeded while waiting")
+	ErrKeyError        = errors.New("conflict: key already released or expired")
+	ErrEventClosed     = errors.New("conflict: event was already sent and closed")
+	ErrBarrierClosed   = errors.New("conflict: barrier quorum already reached")
+	ErrNoLeader        = errors.New("unavailable: cluster has no leader")
+	ErrShuttingDown    = errors.New("unavailable: server is shutting down")
+	ErrCanceled        = errors.New("canceled: client went away while waiting")
+	ErrStaleToken      = errors.New("conflict: fencing token is not held")
+	ErrConfigConflict  = errors.New("conflict: parameters differ from the configured ones")
+	ErrUnauthorized    = errors.New("unauthorized: missing or unknown token")
+	ErrForbidden       = errors.New("forbidden: token not allowed to make this call")
+	ErrTooManyObjects  = errors.New("limited: namespace has reached its object limit")
+	ErrInvalidName     = errors.New("request: 'name' must not be empty or contain '/'")
+	ErrNotLeader       = errors.New("unavailable: not the cluster leader")
+	ErrInvalidOp       = errors.New("request: 'op' must be 'subscribe', 'unsubscribe', 'acquire' or 'release'")
+	ErrInvalidType     = errors.New("request: 'type' can't be used with this 'op'")
+	ErrInvalidMessage  = errors.New("request: invalid message")
+	ErrNoSubscriptions = errors.New("request: give at least one 'event', 'watchdog', 'barrier' or 'counter'")
+)
@@ -0,0 +1,261 @@
+package bouncermain
+
+import (
+	"cmp"
+	"context"
+	"crypto/subtle"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// Access levels, each including the ones before it.
+const (
+	accessRead    = "read"    // configs, stats, values and listings
+	accessAcquire = "acquire" // acquire, release, wait, send, kick and count
+	accessAdmin   = "admin"   // PUT, DELETE and counter resets
+)
+
+var accessLevels = []string{accessRead, accessAcquire, accessAdmin}
+
+// TokenEntry is an API token in the config file. Once any token is
+// configured, every request must carry one as "Authorization: Bearer", or a
+// client certificate with a name matching the subject of an entry.
+type TokenEntry struct {
+	Name    string        `json:"name"` // identifies the client in logs
+	Token   string        `json:"token"`
+	Subject string        `json:"subject"` // client certificate CN or SAN
+	Grants  []AccessGrant `json:"grants"`
+}
+
+// AccessGrant allows a token access to the objects of the given types, or
+// of every type, with names starting with prefix.
+type AccessGrant struct {
+	Access string   `json:"access"`
+	Types  []string `json:"types"`
+	Prefix string   `json:"prefix"`
+}
+
+func (g AccessGrant) allows(access string, typeName string, name string) bool {
+	if slices.Index(accessLevels, g.Access) < slices.Index(accessLevels, access) {
+		return false
+	}
+	if len(g.Types) > 0 && !slices.Contains(g.Types, typeName) {
+		return false
+	}
+	return strings.HasPrefix(name, g.Prefix)
+}
+
+func validateTokens(tokens []TokenEntry) error {
+	seen := map[string]bool{}
+	for _, t := range tokens {
+		if t.Name == "" || (t.Token == "") == (t.Subject == "") {
+			return fmt.Errorf("%w: each token needs a 'name' and either a 'token' or a 'subject'", ErrInvalidConfig)
+		}
+		key := "token:" + t.Token
+		if t.Subject != "" {
+			key = "subject:" + t.Subject
+		}
+		if seen[key] {
+			return fmt.Errorf("%w: token of %q used twice", ErrInvalidConfig, t.Name)
+		}
+		seen[key] = true
+
+		for _, g := range t.Grants {
+			if !slices.Contains(accessLevels, g.Access) {
+				return fmt.Errorf("%w: 'access' must be one of %v", ErrInvalidConfig, accessLevels)
+			}
+			for _, typeName := range g.Types {
+				if _, ok := resourceTypes[typeName]; !ok {
+					return fmt.Errorf("%w: unknown type %q", ErrInvalidConfig, typeName)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// findToken returns the entry for a bearer token. Every token is compared
+// in constant time, so the time taken doesn't tell how close a guess was.
+func findToken(tokens []TokenEntry, token string) (entry *TokenEntry) {
+	for i := range tokens {
+		if subtle.ConstantTimeCompare([]byte(tokens[i].Token), []byte(token)) == 1 {
+			entry = &tokens[i]
+		}
+	}
+	return entry
+}
+
+// findSubject returns the entry for a verified client certificate, matching
+// its common name or any of its SANs.
+func findSubject(tokens []TokenEntry, cert *x509.Certificate) *TokenEntry {
+	if cert == nil {
+		return nil
+	}
+
+	names := certNames(cert)
+	for i := range tokens {
+		if tokens[i].Subject != "" && slices.Contains(names, tokens[i].Subject) {
+			return &tokens[i]
+		}
+	}
+	return nil
+}
+
+// authenticate returns the entry for the bearer token in an Authorization
+// header, or else for the verified client certificate, if any.
+func authenticate(tokens []TokenEntry, authorization string, cert *x509.Certificate) *TokenEntry {
+	if token, ok := strings.CutPrefix(authorization, "Bearer "); ok {
+		return findToken(tokens, token)
+	}
+	return findSubject(tokens, cert)
+}
+
+// allows reports whether any grant of the entry allows the call.
+func (t *TokenEntry) allows(access string, typeName string, name string) bool {
+	return slices.ContainsFunc(t.Grants, func(g AccessGrant) bool {
+		return g.allows(access, typeName, name)
+	})
+}
+
+type tokenEntryKey struct{}
+
+// withTokenEntry records the entry a request was authenticated with.
+func withTokenEntry(r *http.Request, entry *TokenEntry) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), tokenEntryKey{}, entry))
+}
+
+// requestTokenEntry returns the entry a request was authenticated with, or
+// nil if no tokens are configured.
+func requestTokenEntry(r *http.Request) *TokenEntry {
+	entry, _ := r.Context().Value(tokenEntryKey{}).(*TokenEntry)
+	return entry
+}
+
+// clientName returns who made a request: the name of its token, or else the
+// identity in its client certificate, if any.
+func clientName(r *http.Request) string {
+	if entry := requestTokenEntry(r); entry != nil {
+		return entry.Name
+	}
+	return certIdentity(r)
+}
+
+// requiredAccess returns the access level needed for a call.
+func requiredAccess(method string, call string) string {
+	switch {
+	case method == http.MethodPut, method == http.MethodDelete, call == "reset":
+		return accessAdmin
+	case call == "", call == "stats", call == "value", call == "validate":
+		return accessRead
+	default:
+		return accessAcquire
+	}
+}
+
+// checkCall checks a call on an object made without its route, over gRPC or
+// a stream: that the name is valid, that entry, if any, is granted the call,
+// and the limit of the namespace. It returns the qualified name.
+func checkCall(entry *TokenEntry, namespace string, typeName string, call string, name string) (string, error) {
+	if name == "" || strings.Contains(name, namespaceSeparator) {
+		return name, ErrInvalidName
+	}
+	name = qualifiedName(namespace, name)
+
+	access := requiredAccess(http.MethodGet, call)
+	if entry != nil && !entry.allows(access, typeName, name) {
+		return name, ErrForbidden
+	}
+	if namespace != "" && access != accessRead {
+		if err := checkNamespaceLimit(typeName, namespace, name); err != nil {
+			return name, err
+		}
+	}
+	return name, nil
+}
+
+// publicPath reports whether a path is served without a token, for probes
+// and the API docs.
+func publicPath(path string) bool {
+	return strings.HasPrefix(path, "/.well-known/") || strings.HasPrefix(path, "/docs/")
+}
+
+// streamPath reports whether a path is a subscription stream, which checks
+// the grants for each object subscribed to itself.
+func streamPath(path string) bool {
+	return path == "/stream" || strings.HasPrefix(path, "/stream/")
+}
+
+// withAuth checks the bearer token or client certificate of each request
+// against the tokens in the config file, if any, and their grants for the
+// type and name of the object called. Listings are checked against their
+// prefix, and endpoints not about a type need a grant for every type.
+func withAuth(router *httprouter.Router, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokens := currentFileConfig().Tokens
+		if len(tokens) == 0 || publicPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// unknown routes are left for the router to answer
+		handle, _, _ := router.Lookup(r.Method, r.URL.Path)
+
+		// objects in a namespace are checked by their qualified names
+		path := r.URL.Path
+		namespace, namespaced := "", false
+		if rest, ok := strings.CutPrefix(path, "/ns/"); ok && rest != "" {
+			namespace, path, _ = strings.Cut(rest, "/")
+			path, namespaced = "/"+path, true
+		}
+
+		var typeName, name, call string
+		access := accessRead
+		parts := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 3)
+		if _, ok := resourceTypes[parts[0]]; ok && len(parts) > 1 {
+			typeName, name = parts[0], parts[1]
+			if len(parts) > 2 {
+				call = parts[2]
+			}
+			access = requiredAccess(r.Method, call)
+		} else {
+			call = strings.TrimPrefix(path, "/")
+			if r.Method == http.MethodDelete {
+				access = accessAdmin
+			}
+		}
+		if name == "" {
+			name = r.URL.Query().Get("prefix")
+		}
+		if namespaced {
+			name = qualifiedName(namespace, name)
+		}
+
+		entry := authenticate(tokens, r.Header.Get("Authorization"), verifiedCert(r))
+
+		var err error
+		if entry == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="bouncer"`)
+			err = ErrUnauthorized
+		} else if handle != nil && !streamPath(path) && !entry.allows(access, typeName, name) {
+			err = ErrForbidden
+		}
+
+		if entry != nil {
+			r = withTokenEntry(r, entry)
+		}
+
+		if err == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rep := newReply()
+		rep.WriteResponse(w, r, err)
+		logRequest(r, rep.Status, typeName, cmp.Or(call, strings.ToLower(r.Method)), name, 0, nil).Send()
+	})
+}
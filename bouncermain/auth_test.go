@@ -0,0 +1,116 @@
+package bouncermain
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testAuthConfig = `
+tokens:
+  - name: team-a
+    token: token-a
+    grants:
+      - access: admin
+        prefix: auth-a.
+      - access: read
+        types: [counter]
+  - name: monitor
+    token: token-monitor
+    grants:
+      - access: read
+`
+
+func TestAuth(t *testing.T) {
+	useConfigFile(t, writeConfigFile(t, "bouncer.yaml", testAuthConfig))
+	defer deleteSemaphore("auth-a.db")
+	defer deleteCounter("auth-b.hits")
+
+	router := Router()
+	server := httptest.NewServer(withAuth(router, router))
+	defer server.Close()
+
+	call := func(method string, path string, token string) int {
+		req, _ := http.NewRequest(method, server.URL+path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rep, err := http.DefaultClient.Do(req)
+		require.Nil(t, err)
+		defer rep.Body.Close()
+		io.ReadAll(rep.Body)
+		return rep.StatusCode
+	}
+
+	for _, c := range []struct {
+		method string
+		path   string
+		token  string
+		status int
+	}{
+		{"GET", "/semaphore/auth-a.db/acquire", "", 401},
+		{"GET", "/semaphore/auth-a.db/acquire", "wrong", 401},
+		{"GET", "/.well-known/ready", "", 200},
+
+		{"GET", "/semaphore/auth-a.db/acquire", "token-a", 200},
+		{"GET", "/semaphore/auth-a.db/stats", "token-a", 200},
+		{"DELETE", "/semaphore/auth-b.db", "token-a", 403},
+		{"GET", "/semaphore/auth-b.db/acquire", "token-a", 403},
+		{"GET", "/semaphore/?prefix=auth-a.", "token-a", 200},
+		{"GET", "/semaphore/", "token-a", 403},
+		{"GET", "/resources?prefix=auth-a.", "token-a", 200},
+		{"GET", "/metrics", "token-a", 403},
+
+		// read on every counter, but no counting or resetting
+		{"GET", "/counter/auth-b.hits/count", "token-a", 403},
+		{"GET", "/counter/auth-b.hits/count", "token-monitor", 403},
+
+		{"GET", "/semaphore/auth-a.db/stats", "token-monitor", 200},
+		{"GET", "/semaphore/auth-a.db/acquire", "token-monitor", 403},
+		{"GET", "/metrics", "token-monitor", 200},
+		{"GET", "/resources", "token-monitor", 200},
+		{"GET", "/nothing", "token-monitor", 404},
+
+		// objects in namespaces are checked by their qualified names
+		{"GET", "/ns/auth-a./semaphore/db/stats", "token-monitor", 404},
+		{"GET", "/ns/team-b/semaphore/db/acquire", "token-a", 403},
+		{"GET", "/ns/team-b/resources", "token-monitor", 200},
+		{"DELETE", "/ns/team-b", "token-monitor", 403},
+		{"DELETE", "/ns/team-b", "token-a", 403},
+	} {
+		require.Equal(t, c.status, call(c.method, c.path, c.token), "%s %s with %q", c.method, c.path, c.token)
+	}
+
+	_, err := getCounter("auth-b.hits")
+	require.Nil(t, err)
+	require.Equal(t, 200, call("GET", "/counter/auth-b.hits/value", "token-a"))
+	require.Equal(t, 403, call("GET", "/counter/auth-b.hits/reset", "token-a"))
+	require.Equal(t, 204, call("DELETE", "/semaphore/auth-a.db", "token-a"))
+}
+
+func TestAuthDisabled(t *testing.T) {
+	router := Router()
+	server := httptest.NewServer(withAuth(router, router))
+	defer server.Close()
+
+	rep, err := http.Get(server.URL + "/metrics")
+	require.Nil(t, err)
+	rep.Body.Close()
+	require.Equal(t, 200, rep.StatusCode)
+}
+
+func TestAuthInvalidConfig(t *testing.T) {
+	for _, content := range []string{
+		`{"tokens": [{"name": "a"}]}`,
+		`{"tokens": [{"name": "a", "token": "x", "subject": "a.example.com"}]}`,
+		`{"tokens": [{"name": "a", "token": "x"}, {"name": "b", "token": "x"}]}`,
+		`{"tokens": [{"name": "a", "token": "x", "grants": [{"access": "write"}]}]}`,
+		`{"tokens": [{"name": "a", "token": "x", "grants": [{"access": "read", "types": ["mutex"]}]}]}`,
+	} {
+		_, err := readConfigFile(writeConfigFile(t, "bouncer.json", content))
+		require.NotNil(t, err, content)
+	}
+}
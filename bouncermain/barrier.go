@@ -1,6 +1,8 @@
 package bouncermain
 
 import (
+	"cmp"
+	"context"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -11,20 +13,28 @@ type BarrierStats struct {
 	Size            uint64  `json:"size"`
 	TotalWaited     uint64  `json:"total_waited"`
 	TimedOut        uint64  `json:"timed_out"`
+	Canceled        uint64  `json:"canceled"`
 	Triggered       uint64  `json:"triggered"`
 	TotalWaitTime   uint64  `json:"total_wait_time"`
 	CreatedAt       string  `json:"created_at"`
 	AverageWaitTime float64 `json:"average_wait_time"`
 }
 
+type BarrierConfig struct {
+	Size uint64 `json:"size"`
+}
+
 type Barrier struct {
-	Name    string
-	Size    uint64
-	mu      *sync.RWMutex
-	waiting int64
-	done    bool
-	waitC   chan bool
-	Stats   *BarrierStats
+	Name     string
+	Size     uint64
+	mu       *sync.RWMutex
+	waiting  int64
+	done     bool
+	waitC    chan struct{}
+	watchers int64 // clients waiting for the release without joining
+	Stats    *BarrierStats
+	declared bool // configured explicitly, protected by mu
+	idleTracker
 }
 
 var barriers = map[string]*Barrier{}
@@ -39,19 +49,30 @@ func newBarrier(name string, size uint64) *Barrier {
 			CreatedAt: time.Now().Format(time.RFC3339),
 			Size:      size,
 		},
-		waitC: make(chan bool),
+		waitC: make(chan struct{}),
 	}
+	barrier.touch()
 	barriers[name] = barrier
 	return barrier
 }
 
+// getBarrier returns the barrier, created on first use with the configured
+// size, or the size given, or 2. A size of 0 isn't given.
 func getBarrier(name string, size uint64) (*Barrier, error) {
 	barriersMutex.RLock()
 	barrier, ok := barriers[name]
+	if ok {
+		barrier.touch()
+	}
 	barriersMutex.RUnlock()
 
 	if ok {
-		return barrier, nil
+		return barrier, barrier.checkSize(size)
+	}
+
+	config, configured := configuredBarrier(name)
+	if strictMode && !configured {
+		return nil, ErrNotFound
 	}
 
 	// Barrier doesn't exist, need to create it
@@ -61,14 +82,32 @@ func getBarrier(name string, size uint64) (*Barrier, error) {
 	// Check again in case another goroutine created it
 	barrier, ok = barriers[name]
 	if ok {
+		barrier.touch()
 		return barrier, nil
 	}
 
-	barrier = newBarrier(name, size)
-	return barrier, nil
+	if !configured {
+		return newBarrier(name, cmp.Or(size, 2)), nil
+	}
+
+	barrier = newBarrier(name, config.Size)
+	barrier.declared = true
+	return barrier, barrier.checkSize(size)
+}
+
+// checkSize compares the size given in a wait with that of a barrier
+// configured explicitly. Other barriers keep the size they were created with.
+func (b *Barrier) checkSize(size uint64) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if size == 0 || size == b.Size || !b.declared {
+		return nil
+	}
+	return paramsConflict("barrier", b.Name, BarrierConfig{Size: size}, BarrierConfig{Size: b.Size})
 }
 
-func (b *Barrier) Wait(maxwait time.Duration) error {
+func (b *Barrier) Wait(ctx context.Context, maxwait time.Duration) error {
 	started := time.Now()
 	atomic.AddUint64(&b.Stats.Waiting, 1)
 	defer atomic.AddUint64(&b.Stats.Waiting, ^uint64(0)) // decrement
@@ -79,58 +118,118 @@ func (b *Barrier) Wait(maxwait time.Duration) error {
 		return ErrBarrierClosed
 	}
 	count := atomic.AddInt64(&b.waiting, 1)
+	triggered := count >= int64(b.Size)
+	if triggered {
+		b.trigger()
+	}
 	b.mu.Unlock()
 
-	if count == int64(b.Size) {
-		b.mu.Lock()
-		close(b.waitC)
-		b.done = true
-		b.mu.Unlock()
-
+	if triggered {
 		// Update stats before returning
 		atomic.AddUint64(&b.Stats.TotalWaited, 1)
 		atomic.AddUint64(&b.Stats.Triggered, 1)
 		wait := uint64(time.Since(started) / time.Millisecond)
 		atomic.AddUint64(&b.Stats.TotalWaitTime, wait)
+		observeWait("barrier", b.Name, time.Since(started))
 		return nil
 	}
 
-	var err error
-	switch {
-	case maxwait < 0:
-		<-b.waitC
-	case maxwait == 0:
-		select {
-		case <-b.waitC:
-		default:
-			atomic.AddInt64(&b.waiting, -1)
-			atomic.AddUint64(&b.Stats.TimedOut, 1)
-			return ErrTimedOut
-		}
-	default:
-		timer := time.NewTimer(maxwait)
-		defer timer.Stop()
-		select {
-		case <-b.waitC:
-		case <-timer.C:
-			atomic.AddInt64(&b.waiting, -1)
-			atomic.AddUint64(&b.Stats.TimedOut, 1)
-			return ErrTimedOut
-		}
+	if err := waitFor(ctx, b.waitC, maxwait); err != nil {
+		// give up our position, unless the barrier was just triggered
+		atomic.AddInt64(&b.waiting, -1)
+		countGiveUp(err, &b.Stats.TimedOut, &b.Stats.Canceled)
+		return err
 	}
 
 	// Update stats before returning
 	atomic.AddUint64(&b.Stats.TotalWaited, 1)
 	wait := uint64(time.Since(started) / time.Millisecond)
 	atomic.AddUint64(&b.Stats.TotalWaitTime, wait)
+	observeWait("barrier", b.Name, time.Since(started))
+
+	return nil
+}
+
+// watch waits for the barrier to be released, without counting towards its
+// size.
+func (b *Barrier) watch(ctx context.Context) error {
+	atomic.AddInt64(&b.watchers, 1)
+	defer atomic.AddInt64(&b.watchers, -1)
+
+	return waitFor(ctx, b.waitC, -1)
+}
+
+// trigger releases every waiter. Must be called with the barrier mutex held.
+func (b *Barrier) trigger() {
+	close(b.waitC)
+	b.done = true
+}
+
+// configureBarrier creates or resizes a barrier. Lowering the size to the
+// number of clients already waiting triggers it.
+func configureBarrier(name string, body []byte) (created bool, err error) {
+	config := &BarrierConfig{Size: 2}
+	if err = decodeConfig(body, config); err != nil {
+		return false, err
+	}
+	if config.Size == 0 {
+		return false, ErrInvalidSize
+	}
+
+	return declareBarrier(name, *config), nil
+}
 
-	return err
+func declareBarrier(name string, config BarrierConfig) (created bool) {
+	barriersMutex.Lock()
+	barrier, ok := barriers[name]
+	if ok {
+		barrier.touch()
+	} else {
+		barrier = newBarrier(name, config.Size)
+	}
+	barriersMutex.Unlock()
+
+	barrier.mu.Lock()
+	defer barrier.mu.Unlock()
+
+	barrier.Size = config.Size
+	barrier.Stats.Size = config.Size
+	barrier.declared = true
+	if !barrier.done && atomic.LoadInt64(&barrier.waiting) >= int64(config.Size) {
+		barrier.trigger()
+		atomic.AddUint64(&barrier.Stats.Triggered, 1)
+	}
+	return !ok
+}
+
+// undeclareBarrier lets acquires change the barrier again, once it's no
+// longer in the config file.
+func undeclareBarrier(name string) {
+	barriersMutex.RLock()
+	barrier, ok := barriers[name]
+	barriersMutex.RUnlock()
+	if !ok {
+		return
+	}
+
+	barrier.mu.Lock()
+	defer barrier.mu.Unlock()
+	barrier.declared = false
+}
+
+func (b *Barrier) busy() bool {
+	return atomic.LoadUint64(&b.Stats.Waiting) > 0 || atomic.LoadInt64(&b.watchers) > 0
 }
 
 func deleteBarrier(name string) error {
 	barriersMutex.Lock()
 	defer barriersMutex.Unlock()
 
+	return removeBarrier(name)
+}
+
+// removeBarrier must be called with barriersMutex held.
+func removeBarrier(name string) error {
 	barrier, ok := barriers[name]
 	if !ok {
 		return ErrNotFound
@@ -145,9 +244,25 @@ func deleteBarrier(name string) error {
 	barrier.mu.Unlock()
 
 	delete(barriers, name)
+	forgetMetrics("barrier", name)
 	return nil
 }
 
+func getBarrierConfig(name string) (interface{}, error) {
+	barriersMutex.RLock()
+	defer barriersMutex.RUnlock()
+
+	barrier, ok := barriers[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	barrier.mu.RLock()
+	defer barrier.mu.RUnlock()
+
+	return &BarrierConfig{Size: barrier.Size}, nil
+}
+
 func getBarrierStats(name string) (interface{}, error) {
 	barriersMutex.Lock()
 	defer barriersMutex.Unlock()
@@ -158,7 +273,9 @@ func getBarrierStats(name string) (interface{}, error) {
 	}
 
 	// Create a copy of stats and calculate average
+	barrier.mu.RLock()
 	stats := *barrier.Stats
+	barrier.mu.RUnlock()
 	totalWaited := atomic.LoadUint64(&barrier.Stats.TotalWaited)
 	if totalWaited > 0 {
 		stats.AverageWaitTime = float64(atomic.LoadUint64(&barrier.Stats.TotalWaitTime)) / float64(totalWaited)
@@ -17,14 +17,20 @@ type BarrierWaitRequest struct {
 
 func newBarrierWaitRequest() *BarrierWaitRequest {
 	return &BarrierWaitRequest{
-		Size:    2,
+		Size:    0, // as configured
 		MaxWait: -1,
 		ID:      "",
 	}
 }
 
 func (r *BarrierWaitRequest) Decode(values url.Values) error {
-	return decoder.Decode(r, values)
+	if err := decoder.Decode(r, values); err != nil {
+		return err
+	}
+	if r.Size == 0 && values.Has("size") {
+		return ErrInvalidSize
+	}
+	return nil
 }
 
 // BarrierWaitHandler godoc
@@ -38,7 +44,8 @@ func (r *BarrierWaitRequest) Decode(values url.Values) error {
 // @Param id query string false "Optional request identifier for logging"
 // @Success 204 "Barrier completed successfully"
 // @Failure 408 {string} Reply "Request Timeout - maxwait exceeded"
-// @Failure 409 {string} Reply "Conflict - barrier already completed"
+// @Failure 409 {string} Reply "Conflict - barrier already completed, or parameters differ from the configured ones"
+// @Failure 503 {string} Reply "Service Unavailable - server is shutting down"
 // @Router /barrier/{name}/wait [get]
 func BarrierWaitHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	var err error
@@ -55,7 +62,7 @@ func BarrierWaitHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Pa
 
 	if err == nil {
 		start := time.Now()
-		err = barrier.Wait(req.MaxWait)
+		err = barrier.Wait(r.Context(), req.MaxWait)
 		wait = time.Since(start)
 
 		if errors.Is(err, ErrTimedOut) {
@@ -69,7 +76,38 @@ func BarrierWaitHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Pa
 	}
 
 	rep.WriteResponse(w, r, err)
-	logRequest(rep.Status, "barrier", "wait", ps[0].Value, wait, req).Send()
+	logRequest(r, rep.Status, "barrier", "wait", ps[0].Value, wait, req).Send()
+}
+
+// BarrierConfigureHandler godoc
+// @Summary Create or reconfigure a barrier
+// @description.markdown barrier_configure.md
+// @Tags Barrier
+// @Accept json
+// @Produce json
+// @Param name path string true "Barrier name"
+// @Param config body BarrierConfig false "Barrier configuration"
+// @Success 200 {object} BarrierConfig "Barrier reconfigured"
+// @Success 201 {object} BarrierConfig "Barrier created"
+// @Failure 400 {string} Reply "Bad Request - invalid configuration"
+// @Router /barrier/{name} [put]
+func BarrierConfigureHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	status := ConfigureHandler(w, r, ps, configureBarrier, getBarrierConfig)
+	logRequest(r, status, "barrier", "configure", ps[0].Value, 0, nil).Send()
+}
+
+// BarrierConfigHandler godoc
+// @Summary Get barrier configuration
+// @Description Get the current configuration of a barrier
+// @Tags Barrier
+// @Produce json
+// @Param name path string true "Barrier name"
+// @Success 200 {object} BarrierConfig "Barrier configuration"
+// @Failure 404 {string} Reply "Not Found - barrier not found"
+// @Router /barrier/{name} [get]
+func BarrierConfigHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	status := ConfigHandler(w, r, ps, getBarrierConfig)
+	logRequest(r, status, "barrier", "config", ps[0].Value, 0, nil).Send()
 }
 
 // BarrierDeleteHandler godoc
@@ -83,7 +121,7 @@ func BarrierWaitHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Pa
 // @Router /barrier/{name} [delete]
 func BarrierDeleteHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	status := DeleteHandler(w, r, ps, deleteBarrier)
-	logRequest(status, "barrier", "delete", ps[0].Value, 0, nil).Send()
+	logRequest(r, status, "barrier", "delete", ps[0].Value, 0, nil).Send()
 }
 
 // BarrierStatsHandler godoc
@@ -97,5 +135,21 @@ func BarrierDeleteHandler(w http.ResponseWriter, r *http.Request, ps httprouter.
 // @Router /barrier/{name}/stats [get]
 func BarrierStatsHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	status := StatsHandler(w, r, ps, getBarrierStats)
-	logRequest(status, "barrier", "stats", ps[0].Value, 0, nil).Send()
+	logRequest(r, status, "barrier", "stats", ps[0].Value, 0, nil).Send()
+}
+
+// BarrierListHandler godoc
+// @Summary List barriers
+// @Description List existing barriers with their configuration and stats, ordered by name
+// @Tags Barrier
+// @Produce json
+// @Param prefix query string false "Only list names starting with prefix"
+// @Param limit query int false "Maximum number of items" default(100)
+// @Param after query string false "Only list names after this one, as returned in 'next'"
+// @Success 200 {object} ResourceList "Barriers"
+// @Failure 400 {string} Reply "Bad Request - invalid parameters"
+// @Router /barrier/ [get]
+func BarrierListHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	status := ListHandler(w, r, "barrier")
+	logRequest(r, status, "barrier", "list", "", 0, nil).Send()
 }
@@ -2,8 +2,10 @@ package bouncermain_test
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -83,3 +85,20 @@ func TestBarrierReuse(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestBarrierAbandonedWaitGivesUpPosition(t *testing.T) {
+	baseURL := fmt.Sprintf("%s/barrier/abandoned-test", server.URL)
+
+	err := AbandonRequest(baseURL+"/wait?size=2", 50*time.Millisecond)
+	require.NotNil(t, err)
+
+	require.Eventually(t, func() bool {
+		_, body, _ := GetRequest(baseURL + "/stats")
+		return strings.Contains(body, `"canceled":1`)
+	}, time.Second, 10*time.Millisecond)
+
+	// the abandoned waiter doesn't count towards the barrier size
+	status, _, err := GetRequest(baseURL + "/wait?size=2&maxwait=100")
+	require.Nil(t, err)
+	require.Equal(t, 408, status)
+}
@@ -3,7 +3,11 @@ package bouncermain
 import (
 	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
 	"runtime"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/spf13/viper"
@@ -29,6 +33,25 @@ func loadConfig() {
 	viper.SetDefault("readTimeout", 30)
 	viper.SetDefault("writeTimeout", 30)
 	viper.SetDefault("maxSleepDuration", 5000) // 5 seconds in milliseconds
+	viper.SetDefault("dataDir", "")
+	viper.SetDefault("snapshotInterval", 60)
+	viper.SetDefault("walSync", false)
+	viper.SetDefault("clusterID", "")
+	viper.SetDefault("clusterBind", "")
+	viper.SetDefault("clusterPeers", "")
+	viper.SetDefault("clusterRedirect", false)
+	viper.SetDefault("idleTTL", 0)
+	viper.SetDefault("janitorInterval", 60)
+	viper.SetDefault("shutdownGrace", 10)
+	viper.SetDefault("strict", false)
+	viper.SetDefault("configFile", "")
+	viper.SetDefault("configWatch", true)
+	viper.SetDefault("tlsCert", "")
+	viper.SetDefault("tlsKey", "")
+	viper.SetDefault("tlsClientCA", "")
+	viper.SetDefault("tlsClientAuth", "require")
+	viper.SetDefault("namespaceMaxObjects", 0)
+	viper.SetDefault("grpcPort", 0)
 
 	viper.BindEnv("myHost", "BOUNCER_HOST")
 	viper.BindEnv("myPort", "BOUNCER_PORT")
@@ -36,27 +59,170 @@ func loadConfig() {
 	viper.BindEnv("readTimeout", "BOUNCER_READ_TIMEOUT")
 	viper.BindEnv("writeTimeout", "BOUNCER_WRITE_TIMEOUT")
 	viper.BindEnv("maxSleepDuration", "BOUNCER_MAX_SLEEP_DURATION")
+	viper.BindEnv("dataDir", "BOUNCER_DATA_DIR")
+	viper.BindEnv("snapshotInterval", "BOUNCER_SNAPSHOT_INTERVAL")
+	viper.BindEnv("walSync", "BOUNCER_WAL_SYNC")
+	viper.BindEnv("clusterID", "BOUNCER_CLUSTER_ID")
+	viper.BindEnv("clusterBind", "BOUNCER_CLUSTER_BIND")
+	viper.BindEnv("clusterPeers", "BOUNCER_CLUSTER_PEERS")
+	viper.BindEnv("clusterRedirect", "BOUNCER_CLUSTER_REDIRECT")
+	viper.BindEnv("idleTTL", "BOUNCER_IDLE_TTL")
+	viper.BindEnv("janitorInterval", "BOUNCER_JANITOR_INTERVAL")
+	viper.BindEnv("shutdownGrace", "BOUNCER_SHUTDOWN_GRACE")
+	viper.BindEnv("strict", "BOUNCER_STRICT")
+	viper.BindEnv("configFile", "BOUNCER_CONFIG_FILE")
+	viper.BindEnv("configWatch", "BOUNCER_CONFIG_WATCH")
+	viper.BindEnv("tlsCert", "BOUNCER_TLS_CERT")
+	viper.BindEnv("tlsKey", "BOUNCER_TLS_KEY")
+	viper.BindEnv("tlsClientCA", "BOUNCER_TLS_CLIENT_CA")
+	viper.BindEnv("tlsClientAuth", "BOUNCER_TLS_CLIENT_AUTH")
+	viper.BindEnv("namespaceMaxObjects", "BOUNCER_NAMESPACE_MAX_OBJECTS")
+	viper.BindEnv("grpcPort", "BOUNCER_GRPC_PORT")
+	viper.BindEnv("barrierIdleTTL", "BOUNCER_BARRIER_IDLE_TTL")
+	viper.BindEnv("counterIdleTTL", "BOUNCER_COUNTER_IDLE_TTL")
+	viper.BindEnv("eventIdleTTL", "BOUNCER_EVENT_IDLE_TTL")
+	viper.BindEnv("ratelimitIdleTTL", "BOUNCER_RATELIMIT_IDLE_TTL")
+	viper.BindEnv("rwlockIdleTTL", "BOUNCER_RWLOCK_IDLE_TTL")
+	viper.BindEnv("semaphoreIdleTTL", "BOUNCER_SEMAPHORE_IDLE_TTL")
+	viper.BindEnv("tokenbucketIdleTTL", "BOUNCER_TOKENBUCKET_IDLE_TTL")
+	viper.BindEnv("watchdogIdleTTL", "BOUNCER_WATCHDOG_IDLE_TTL")
 }
 
-var maxSleepDuration = time.Duration(viper.GetInt("maxSleepDuration")) * time.Millisecond
+// durationSetting holds a setting that can change on reload while requests
+// read it.
+type durationSetting struct {
+	v atomic.Int64
+}
+
+func newDurationSetting(d time.Duration) *durationSetting {
+	s := &durationSetting{}
+	s.Set(d)
+	return s
+}
+
+func (s *durationSetting) Get() time.Duration {
+	return time.Duration(s.v.Load())
+}
 
+func (s *durationSetting) Set(d time.Duration) {
+	s.v.Store(int64(d))
+}
+
+var maxSleepDuration = newDurationSetting(5 * time.Second)
+var readTimeout = newDurationSetting(30 * time.Second)
+var writeTimeout = newDurationSetting(30 * time.Second)
+
+func setupTimeouts() {
+	maxSleepDuration.Set(time.Duration(viper.GetInt("maxSleepDuration")) * time.Millisecond)
+	readTimeout.Set(time.Duration(viper.GetInt("readTimeout")) * time.Second)
+	writeTimeout.Set(time.Duration(viper.GetInt("writeTimeout")) * time.Second)
+}
+
+// withTimeouts sets the deadlines of each request from the current timeouts,
+// which the server would only read on startup.
+func withTimeouts(next http.Handler) http.Handler {
+	deadline := func(start time.Time, timeout time.Duration) time.Time {
+		if timeout <= 0 {
+			return time.Time{}
+		}
+		return start.Add(timeout)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc := http.NewResponseController(w)
+		start := time.Now()
+		rc.SetReadDeadline(deadline(start, readTimeout.Get()))
+		rc.SetWriteDeadline(deadline(start, writeTimeout.Get()))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Main runs the server until it gets SIGINT or SIGTERM, then shuts down
+// gracefully. SIGHUP reloads the config file.
 func Main() {
 	runtime.LockOSThread()
 	loadConfig()
+
+	if err := setupConfigFile(); err != nil {
+		log.Fatal().Err(err).Msg("could not load config file")
+	}
+
 	setupLogging()
+	setupTimeouts()
+	strictMode = viper.GetBool("strict")
 
 	addr := fmt.Sprintf("%v:%v", viper.GetString("myHost"), viper.GetInt("myPort"))
 
 	log.Info().Msg("Starting...")
+
+	if err := setupJournal(); err != nil {
+		log.Fatal().Err(err).Msg("could not open journal")
+	}
+
+	if err := setupCluster(); err != nil {
+		log.Fatal().Err(err).Msg("could not join cluster")
+	}
+
+	// after the journal, so the configured settings win over the restored ones
+	applyFileObjects(currentFileConfig())
+	watchConfigFile()
+
+	if err := setupJanitor(); err != nil {
+		log.Fatal().Err(err).Msg("could not start janitor")
+	}
+
+	tlsConfig, err := setupTLS()
+	if err != nil {
+		log.Fatal().Err(err).Msg("could not load TLS files")
+	}
+
+	router := Router()
+	var handler http.Handler = router
+	if cluster != nil {
+		handler = cluster.Handler(handler)
+	}
+	handler = withTimeouts(withAuth(router, handler))
 	log.Info().Msgf("Listening on %v", addr)
 
 	server := &http.Server{
 		Addr:         addr,
-		Handler:      Router(),
-		ReadTimeout:  time.Duration(viper.GetInt("readTimeout")) * time.Second,
-		WriteTimeout: time.Duration(viper.GetInt("writeTimeout")) * time.Second,
+		Handler:      handler,
+		ReadTimeout:  readTimeout.Get(),
+		WriteTimeout: writeTimeout.Get(),
+		TLSConfig:    tlsConfig,
+	}
+
+	errC := make(chan error, 2)
+	rpcServer, err := setupGRPC(tlsConfig, errC)
+	if err != nil {
+		log.Fatal().Err(err).Msg("could not serve gRPC")
 	}
 
-	log.Fatal().Err(server.ListenAndServe())
+	go func() {
+		if tlsConfig != nil {
+			// the certificate comes from the config
+			errC <- server.ListenAndServeTLS("", "")
+		} else {
+			errC <- server.ListenAndServe()
+		}
+	}()
 
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case err := <-errC:
+			log.Fatal().Err(err).Msg("server failed")
+		case sig := <-signals:
+			log.Info().Str("signal", sig.String()).Msg("received signal")
+			if sig == syscall.SIGHUP {
+				reloadConfigFile()
+				continue
+			}
+			shutdown(server, rpcServer)
+			return
+		}
+	}
 }
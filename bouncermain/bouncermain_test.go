@@ -4,6 +4,8 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"time"
 
 	"github.com/pjwerneck/bouncer/bouncermain"
 )
@@ -60,3 +62,69 @@ func DeleteRequest(url string) (status int, body string, err error) {
 
 	return
 }
+
+// AbandonRequest sends a request and hangs up after d, like a client that
+// went away while waiting.
+func AbandonRequest(url string, d time.Duration) error {
+	client := &http.Client{Timeout: d}
+	rep, err := client.Get(url)
+	if err == nil {
+		rep.Body.Close()
+	}
+	return err
+}
+
+// AcquireFenced sends an acquire request and returns the key with its
+// fencing token.
+func AcquireFenced(url string) (status int, key string, token string, err error) {
+	rep, err := http.Get(url)
+	if err != nil {
+		return
+	}
+	defer rep.Body.Close()
+
+	bs, err := io.ReadAll(rep.Body)
+	if err != nil {
+		return
+	}
+
+	return rep.StatusCode, string(bs), rep.Header.Get(bouncermain.FencingTokenHeader), nil
+}
+
+// GetRequestWithHeader is GetRequest that also returns the response headers.
+func GetRequestWithHeader(url string) (status int, body string, header http.Header, err error) {
+	rep, err := http.Get(url)
+	if err != nil {
+		return
+	}
+	defer rep.Body.Close()
+
+	bs, err := io.ReadAll(rep.Body)
+	if err != nil {
+		return
+	}
+
+	return rep.StatusCode, string(bs), rep.Header, nil
+}
+
+// PutRequest sends a PUT request with a JSON body.
+func PutRequest(url string, body string) (status int, reply string, err error) {
+	req, err := http.NewRequest("PUT", url, strings.NewReader(body))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rep, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return
+	}
+	defer rep.Body.Close()
+
+	bs, err := io.ReadAll(rep.Body)
+	if err != nil {
+		return
+	}
+
+	return rep.StatusCode, string(bs), nil
+}
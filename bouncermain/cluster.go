@@ -0,0 +1,723 @@
+package bouncermain
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/raft"
+	raftboltdb "github.com/hashicorp/raft-boltdb/v2"
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+const clusterApplyTimeout = 5 * time.Second
+
+type clusterPeer struct {
+	ID       string `json:"id"`
+	RaftAddr string `json:"raft_addr"`
+	HTTPAddr string `json:"http_addr"`
+}
+
+type clusterConfig struct {
+	ID       string
+	Bind     string
+	Peers    []clusterPeer
+	DataDir  string // raft log and snapshots are kept in memory if empty
+	Redirect bool   // redirect clients to the leader instead of proxying
+}
+
+type ClusterStatus struct {
+	ID     string        `json:"id"`
+	State  string        `json:"state"`
+	Leader string        `json:"leader"`
+	Peers  []clusterPeer `json:"peers"`
+}
+
+// Cluster replicates state changes to the other nodes through a raft log.
+// Only the leader serves requests; followers forward them to the leader.
+// The leader applies changes before they are committed, but holds back the
+// replies until they are.
+type Cluster struct {
+	id        string
+	origin    atomic.Pointer[string] // tags records replicated by this process
+	fsm       raft.FSM
+	raft      *raft.Raft
+	transport *raft.NetworkTransport
+	peers     map[string]clusterPeer
+	redirect  bool
+	leading   atomic.Bool
+	notifyC   chan bool
+	doneC     chan struct{}
+	closers   []io.Closer
+
+	applyMu  sync.Mutex
+	appliedC *sync.Cond
+	pending  []raft.ApplyFuture // handed to raft, in log order
+	issued   uint64             // records handed to raft
+	resolved uint64             // records known to be committed or failed
+	failed   uint64             // last record that failed to commit
+	closed   bool
+}
+
+// resyncer is an FSM that can rebuild the local state from the committed
+// records, undoing the changes of the leader that were never committed.
+type resyncer interface {
+	resync()
+}
+
+var cluster *Cluster
+
+// parsePeers parses a comma separated list of id=raft_addr=http_addr entries.
+func parsePeers(s string) (peers []clusterPeer, err error) {
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.Split(entry, "=")
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("cluster: invalid peer %q, expected id=raft_addr=http_addr", entry)
+		}
+
+		httpAddr := parts[2]
+		if !strings.Contains(httpAddr, "://") {
+			httpAddr = "http://" + httpAddr
+		}
+
+		peers = append(peers, clusterPeer{ID: parts[0], RaftAddr: parts[1], HTTPAddr: httpAddr})
+	}
+	return peers, nil
+}
+
+func newCluster(config clusterConfig, fsm raft.FSM) (c *Cluster, err error) {
+	c = &Cluster{
+		id:       config.ID,
+		fsm:      fsm,
+		peers:    make(map[string]clusterPeer),
+		redirect: config.Redirect,
+		notifyC:  make(chan bool, 8),
+		doneC:    make(chan struct{}),
+	}
+	c.appliedC = sync.NewCond(&c.applyMu)
+	c.newOrigin()
+
+	servers := []raft.Server{}
+	for _, peer := range config.Peers {
+		c.peers[peer.ID] = peer
+		servers = append(servers, raft.Server{
+			ID:      raft.ServerID(peer.ID),
+			Address: raft.ServerAddress(peer.RaftAddr),
+		})
+	}
+
+	self, ok := c.peers[config.ID]
+	if !ok {
+		return nil, fmt.Errorf("cluster: node %q is not in the peer list", config.ID)
+	}
+
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:       "raft",
+		Level:      hclog.Warn,
+		Output:     os.Stderr,
+		JSONFormat: true,
+	})
+
+	rconf := raft.DefaultConfig()
+	rconf.LocalID = raft.ServerID(config.ID)
+	rconf.Logger = logger
+	rconf.NotifyCh = c.notifyC
+
+	advertise, err := net.ResolveTCPAddr("tcp", self.RaftAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	bind := config.Bind
+	if bind == "" {
+		bind = self.RaftAddr
+	}
+
+	c.transport, err = raft.NewTCPTransportWithLogger(bind, advertise, 3, 10*time.Second, logger)
+	if err != nil {
+		return nil, err
+	}
+	c.closers = append(c.closers, c.transport)
+
+	var logs raft.LogStore
+	var stable raft.StableStore
+	var snapshots raft.SnapshotStore
+
+	if config.DataDir == "" {
+		store := raft.NewInmemStore()
+		logs, stable = store, store
+		snapshots = raft.NewInmemSnapshotStore()
+	} else {
+		dir := filepath.Join(config.DataDir, "raft")
+		if err = os.MkdirAll(dir, 0o755); err != nil {
+			c.close()
+			return nil, err
+		}
+
+		store, err := raftboltdb.NewBoltStore(filepath.Join(dir, "raft.db"))
+		if err != nil {
+			c.close()
+			return nil, err
+		}
+		c.closers = append(c.closers, store)
+		logs, stable = store, store
+
+		snapshots, err = raft.NewFileSnapshotStoreWithLogger(dir, 2, logger)
+		if err != nil {
+			c.close()
+			return nil, err
+		}
+	}
+
+	// Every node bootstraps with the same configuration, which raft
+	// accepts as long as the node has no existing state.
+	hasState, err := raft.HasExistingState(logs, stable, snapshots)
+	if err != nil {
+		c.close()
+		return nil, err
+	}
+	if !hasState {
+		err = raft.BootstrapCluster(rconf, logs, stable, snapshots, c.transport, raft.Configuration{Servers: servers})
+		if err != nil {
+			c.close()
+			return nil, err
+		}
+	}
+
+	c.raft, err = raft.NewRaft(rconf, fsm, logs, stable, snapshots, c.transport)
+	if err != nil {
+		c.close()
+		return nil, err
+	}
+
+	go c.watchLeadership()
+	go c.watchApplies()
+
+	return c, nil
+}
+
+// newOrigin tags the records replicated from now on apart from the ones
+// before, which the local state no longer has once rebuilt.
+func (c *Cluster) newOrigin() {
+	origin := fmt.Sprintf("%s/%d", c.id, time.Now().UnixNano())
+	c.origin.Store(&origin)
+}
+
+// ownRecord reports whether a record was replicated by this node, which
+// applied it before replicating it.
+func (c *Cluster) ownRecord(rec stateRecord) bool {
+	return rec.Origin == *c.origin.Load()
+}
+
+// watchLeadership starts serving requests only after a new leader applied
+// every entry committed by previous leaders, so local changes never race
+// with older replicated ones.
+func (c *Cluster) watchLeadership() {
+	for {
+		select {
+		case leader := <-c.notifyC:
+			c.leading.Store(false)
+			if !leader {
+				log.Info().Str("id", c.id).Msg("cluster: lost leadership")
+				continue
+			}
+
+			c.lead()
+
+		case <-c.doneC:
+			return
+		}
+	}
+}
+
+func (c *Cluster) lead() {
+	if err := c.raft.Barrier(clusterApplyTimeout).Error(); err != nil {
+		log.Error().Err(err).Msg("cluster: waiting for log to be applied")
+		return
+	}
+
+	c.leading.Store(c.raft.State() == raft.Leader)
+	log.Info().Str("id", c.id).Msg("cluster: became leader")
+}
+
+// watchApplies follows the records handed to raft in log order, so replies
+// can wait for theirs to be committed. Once one fails, the node stops
+// serving and, when nothing else is pending, rebuilds its state from the
+// committed records.
+func (c *Cluster) watchApplies() {
+	c.applyMu.Lock()
+	defer c.applyMu.Unlock()
+
+	diverged := false
+	for {
+		for len(c.pending) == 0 && !c.closed {
+			c.appliedC.Wait()
+		}
+		if c.closed {
+			return
+		}
+
+		future := c.pending[0]
+		c.pending = c.pending[1:]
+
+		c.applyMu.Unlock()
+		err := future.Error()
+		c.applyMu.Lock()
+
+		c.resolved++
+		if err != nil {
+			log.Error().Err(err).Msg("cluster: replicating record")
+			c.failed = c.resolved
+			if _, ok := c.fsm.(resyncer); ok {
+				c.leading.Store(false)
+				diverged = true
+			}
+		}
+		c.appliedC.Broadcast()
+
+		if diverged && len(c.pending) == 0 {
+			diverged = false
+			c.applyMu.Unlock()
+			c.resync()
+			c.applyMu.Lock()
+		}
+	}
+}
+
+// resync undoes the changes that were applied but never committed, and
+// serves again if still the leader.
+func (c *Cluster) resync() {
+	log.Warn().Str("id", c.id).Msg("cluster: rebuilding state from the log")
+	c.fsm.(resyncer).resync()
+
+	if c.raft.State() == raft.Leader {
+		c.lead()
+	}
+}
+
+// mark returns the number of records handed to raft so far, for commit.
+func (c *Cluster) mark() uint64 {
+	c.applyMu.Lock()
+	defer c.applyMu.Unlock()
+	return c.issued
+}
+
+// commit waits for every record handed to raft so far to be committed, and
+// fails if any after mark wasn't.
+func (c *Cluster) commit(mark uint64) error {
+	c.applyMu.Lock()
+	defer c.applyMu.Unlock()
+
+	target := c.issued
+	for c.resolved < target && !c.closed {
+		c.appliedC.Wait()
+	}
+	if c.resolved < target || c.failed > mark {
+		return ErrNoLeader
+	}
+	return nil
+}
+
+// replicationMark and replicationDone hold back a reply made without the
+// cluster handler until the changes it reports are committed.
+func replicationMark() uint64 {
+	if cluster == nil {
+		return 0
+	}
+	return cluster.mark()
+}
+
+func replicationDone(mark uint64) error {
+	if cluster == nil {
+		return nil
+	}
+	return cluster.commit(mark)
+}
+
+func (c *Cluster) close() {
+	for i := len(c.closers) - 1; i >= 0; i-- {
+		c.closers[i].Close()
+	}
+}
+
+// Shutdown leaves the cluster and releases the raft stores.
+func (c *Cluster) Shutdown() error {
+	err := c.raft.Shutdown().Error()
+	close(c.doneC)
+
+	c.applyMu.Lock()
+	c.closed = true
+	c.appliedC.Broadcast()
+	c.applyMu.Unlock()
+
+	c.leading.Store(false)
+	c.close()
+	return err
+}
+
+// IsLeader reports whether this node is the leader and ready to serve.
+func (c *Cluster) IsLeader() bool {
+	return c.leading.Load()
+}
+
+// Leader returns the current leader, if any is known.
+func (c *Cluster) Leader() (peer clusterPeer, ok bool) {
+	_, id := c.raft.LeaderWithID()
+	peer, ok = c.peers[string(id)]
+	return peer, ok
+}
+
+func (c *Cluster) Status() *ClusterStatus {
+	status := &ClusterStatus{
+		ID:    c.id,
+		State: strings.ToLower(c.raft.State().String()),
+		Peers: []clusterPeer{},
+	}
+
+	if leader, ok := c.Leader(); ok {
+		status.Leader = leader.ID
+	}
+
+	for _, peer := range c.peers {
+		status.Peers = append(status.Peers, peer)
+	}
+
+	return status
+}
+
+// replicate hands a state change already applied by the leader to raft,
+// without waiting for it to be committed, since the object may still be
+// locked. Changes applied on followers come from the log itself and are not
+// replicated.
+func (c *Cluster) replicate(rec stateRecord) {
+	if !c.IsLeader() {
+		return
+	}
+
+	rec.Origin = *c.origin.Load()
+	buf, err := json.Marshal(rec)
+	if err != nil {
+		log.Error().Err(err).Msg("cluster: encoding record")
+		return
+	}
+
+	// handed over under the lock, so pending keeps the log order
+	c.applyMu.Lock()
+	c.issued++
+	c.pending = append(c.pending, c.raft.Apply(buf, clusterApplyTimeout))
+	c.appliedC.Broadcast()
+	c.applyMu.Unlock()
+}
+
+// commitWriter holds back the reply to a request until the changes it made
+// are committed, replying 503 instead if they can't be.
+type commitWriter struct {
+	http.ResponseWriter
+	r       *http.Request
+	commit  func() error
+	checked bool
+	failed  bool
+}
+
+func (w *commitWriter) check() {
+	if w.checked {
+		return
+	}
+	w.checked = true
+
+	if err := w.commit(); err != nil {
+		w.failed = true
+		header := w.ResponseWriter.Header()
+		for key := range header {
+			delete(header, key)
+		}
+		rep := newReply()
+		rep.WriteResponse(w.ResponseWriter, w.r, err)
+	}
+}
+
+func (w *commitWriter) WriteHeader(status int) {
+	w.check()
+	if !w.failed {
+		w.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (w *commitWriter) Write(b []byte) (int, error) {
+	w.check()
+	if w.failed {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *commitWriter) Flush() {
+	w.check()
+	if !w.failed {
+		http.NewResponseController(w.ResponseWriter).Flush()
+	}
+}
+
+func (w *commitWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Handler serves requests on the leader and forwards or redirects them to
+// the leader on followers.
+func (c *Cluster) Handler(next http.Handler) http.Handler {
+	transport := proxyTransport()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !clusterForwarded(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if c.IsLeader() {
+			mark := c.mark()
+			next.ServeHTTP(&commitWriter{ResponseWriter: w, r: r, commit: func() error { return c.commit(mark) }}, r)
+			return
+		}
+
+		leader, ok := c.Leader()
+		if !ok || leader.ID == c.id {
+			rep := newReply()
+			rep.Status = http.StatusServiceUnavailable
+			rep.WriteResponse(w, r, ErrNoLeader)
+			return
+		}
+
+		target, err := url.Parse(leader.HTTPAddr)
+		if err != nil {
+			rep := newReply()
+			rep.WriteResponse(w, r, err)
+			return
+		}
+
+		if c.redirect {
+			location := *r.URL
+			location.Scheme = target.Scheme
+			location.Host = target.Host
+			http.Redirect(w, r, location.String(), http.StatusTemporaryRedirect)
+			return
+		}
+
+		proxy := httputil.NewSingleHostReverseProxy(target)
+		proxy.Transport = transport
+		proxy.FlushInterval = -1
+		proxy.ServeHTTP(w, r)
+	})
+}
+
+// proxyTransport returns the transport to forward requests to the leader
+// with, which presents this node's certificate when serving TLS.
+func proxyTransport() http.RoundTripper {
+	if peerTLS == nil {
+		return http.DefaultTransport
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = peerTLS
+	return transport
+}
+
+// clusterForwarded reports whether a request must be served by the leader.
+// Node-local endpoints are always served by the node that received them.
+func clusterForwarded(path string) bool {
+	return !strings.HasPrefix(path, "/.well-known/") &&
+		!strings.HasPrefix(path, "/cluster/") &&
+		!strings.HasPrefix(path, "/docs/") &&
+		path != "/metrics"
+}
+
+// bouncerFSM applies replicated records to the package state. It keeps the
+// latest committed record of each object and key, which rebuild the state
+// without the changes the leader applied but failed to commit.
+type bouncerFSM struct {
+	mu        sync.Mutex
+	seq       uint64
+	committed map[committedKey]committedRecord
+}
+
+type committedKey struct {
+	fence bool
+	typ   string
+	name  string
+	key   string
+}
+
+type committedRecord struct {
+	seq uint64
+	rec stateRecord
+}
+
+// keep records a committed record. Must be called with f.mu held.
+func (f *bouncerFSM) keep(rec stateRecord) {
+	if f.committed == nil {
+		f.committed = make(map[committedKey]committedRecord)
+	}
+
+	switch rec.Op {
+	case opDelete:
+		for k := range f.committed {
+			if k.typ == rec.Type && k.name == rec.Name {
+				delete(f.committed, k)
+			}
+		}
+	case opSemaphoreDelKey, opRWLockDelKey:
+		delete(f.committed, committedKey{typ: rec.Type, name: rec.Name, key: rec.Key})
+	default:
+		f.seq++
+		k := committedKey{fence: rec.Op == opFence, typ: rec.Type, name: rec.Name, key: rec.Key}
+		f.committed[k] = committedRecord{seq: f.seq, rec: rec}
+	}
+}
+
+func (f *bouncerFSM) Apply(l *raft.Log) interface{} {
+	var rec stateRecord
+	if err := json.Unmarshal(l.Data, &rec); err != nil {
+		log.Error().Err(err).Msg("cluster: decoding record")
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	own := cluster != nil && cluster.ownRecord(rec)
+	rec.Origin = ""
+	f.keep(rec)
+
+	// the leader applied its own changes before replicating them
+	if !own {
+		applyRecord(rec)
+	}
+	return nil
+}
+
+func (f *bouncerFSM) resync() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	records := make([]committedRecord, 0, len(f.committed))
+	for _, c := range f.committed {
+		records = append(records, c)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].seq < records[j].seq })
+
+	resetState()
+	for _, c := range records {
+		applyRecord(c.rec)
+	}
+
+	// own records still in flight are no longer applied locally
+	if cluster != nil {
+		cluster.newOrigin()
+	}
+}
+
+func (f *bouncerFSM) Snapshot() (raft.FSMSnapshot, error) {
+	return &fsmSnapshot{records: captureState()}, nil
+}
+
+func (f *bouncerFSM) Restore(rc io.ReadCloser) error {
+	defer rc.Close()
+
+	var records []stateRecord
+	if err := json.NewDecoder(rc).Decode(&records); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.committed = nil
+	resetState()
+	for _, rec := range records {
+		f.keep(rec)
+		applyRecord(rec)
+	}
+	return nil
+}
+
+type fsmSnapshot struct {
+	records []stateRecord
+}
+
+func (s *fsmSnapshot) Persist(sink raft.SnapshotSink) error {
+	err := json.NewEncoder(sink).Encode(s.records)
+	if err != nil {
+		sink.Cancel()
+		return err
+	}
+	return sink.Close()
+}
+
+func (s *fsmSnapshot) Release() {}
+
+func setupCluster() error {
+	id := viper.GetString("clusterID")
+	if id == "" {
+		return nil
+	}
+
+	peers, err := parsePeers(viper.GetString("clusterPeers"))
+	if err != nil {
+		return err
+	}
+
+	c, err := newCluster(clusterConfig{
+		ID:       id,
+		Bind:     viper.GetString("clusterBind"),
+		Peers:    peers,
+		DataDir:  viper.GetString("dataDir"),
+		Redirect: viper.GetBool("clusterRedirect"),
+	}, &bouncerFSM{})
+	if err != nil {
+		return err
+	}
+
+	cluster = c
+	log.Info().Str("id", id).Int("peers", len(peers)).Msg("cluster: joined")
+	return nil
+}
+
+// ClusterStatusHandler godoc
+// @Summary Cluster status
+// @Description Get the raft state of this node and the current leader
+// @Tags Health
+// @Produce json
+// @Success 200 {object} ClusterStatus "Cluster status"
+// @Failure 404 {string} Reply "Not Found - cluster mode is disabled"
+// @Router /cluster/status [get]
+func ClusterStatusHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	rep := newReply()
+
+	var err error
+	if cluster == nil {
+		err = ErrNotFound
+	} else {
+		buf, _ := json.Marshal(cluster.Status())
+		rep.Body = string(buf)
+	}
+
+	rep.WriteResponse(w, r, err)
+}
@@ -0,0 +1,324 @@
+package bouncermain
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingFSM keeps replicated records instead of applying them to the
+// package state, so several nodes can run in the same process.
+type recordingFSM struct {
+	mu      sync.Mutex
+	records []stateRecord
+}
+
+func (f *recordingFSM) Apply(l *raft.Log) interface{} {
+	var rec stateRecord
+	json.Unmarshal(l.Data, &rec)
+	f.mu.Lock()
+	f.records = append(f.records, rec)
+	f.mu.Unlock()
+	return nil
+}
+
+func (f *recordingFSM) Snapshot() (raft.FSMSnapshot, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return &fsmSnapshot{records: append([]stateRecord{}, f.records...)}, nil
+}
+
+func (f *recordingFSM) Restore(rc io.ReadCloser) error {
+	defer rc.Close()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return json.NewDecoder(rc).Decode(&f.records)
+}
+
+func (f *recordingFSM) names() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	names := []string{}
+	for _, rec := range f.records {
+		names = append(names, rec.Name)
+	}
+	return names
+}
+
+type memorySink struct {
+	bytes.Buffer
+}
+
+func (s *memorySink) ID() string    { return "memory" }
+func (s *memorySink) Cancel() error { return nil }
+func (s *memorySink) Close() error  { return nil }
+
+func freeAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer l.Close()
+	return l.Addr().String()
+}
+
+func waitForLeader(t *testing.T, nodes map[string]*Cluster) *Cluster {
+	var leader *Cluster
+	require.Eventually(t, func() bool {
+		for _, node := range nodes {
+			if node.IsLeader() {
+				leader = node
+				return true
+			}
+		}
+		return false
+	}, 10*time.Second, 50*time.Millisecond)
+	return leader
+}
+
+func TestParsePeers(t *testing.T) {
+	peers, err := parsePeers("a=127.0.0.1:7001=127.0.0.1:5501, b=127.0.0.1:7002=https://b:5502")
+	require.Nil(t, err)
+	require.Equal(t, []clusterPeer{
+		{ID: "a", RaftAddr: "127.0.0.1:7001", HTTPAddr: "http://127.0.0.1:5501"},
+		{ID: "b", RaftAddr: "127.0.0.1:7002", HTTPAddr: "https://b:5502"},
+	}, peers)
+
+	_, err = parsePeers("a=127.0.0.1:7001")
+	require.NotNil(t, err)
+}
+
+func TestClusterReplicationAndFailover(t *testing.T) {
+	ids := []string{"n1", "n2", "n3"}
+	peers := []clusterPeer{}
+	servers := map[string]*httptest.Server{}
+	nodes := map[string]*Cluster{}
+	fsms := map[string]*recordingFSM{}
+	var nodesMu sync.Mutex
+
+	for _, id := range ids {
+		id := id
+		servers[id] = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			nodesMu.Lock()
+			node := nodes[id]
+			nodesMu.Unlock()
+			node.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, id)
+			})).ServeHTTP(w, r)
+		}))
+		defer servers[id].Close()
+		peers = append(peers, clusterPeer{ID: id, RaftAddr: freeAddr(t), HTTPAddr: servers[id].URL})
+	}
+
+	for _, id := range ids {
+		fsms[id] = &recordingFSM{}
+		node, err := newCluster(clusterConfig{ID: id, Peers: peers}, fsms[id])
+		require.Nil(t, err)
+		nodesMu.Lock()
+		nodes[id] = node
+		nodesMu.Unlock()
+	}
+	defer func() {
+		for _, node := range nodes {
+			node.Shutdown()
+		}
+	}()
+
+	leader := waitForLeader(t, nodes)
+	leader.replicate(stateRecord{Op: opCounterSet, Type: "counter", Name: "first", Value: 1})
+
+	for _, id := range ids {
+		require.Eventually(t, func() bool {
+			return len(fsms[id].names()) == 1
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+
+	// followers forward requests to the leader
+	for id, node := range nodes {
+		if node == leader {
+			continue
+		}
+		status, body := get(t, servers[id].URL+"/counter/first/value")
+		require.Equal(t, 200, status)
+		require.Equal(t, leader.id, body)
+	}
+
+	// a new leader is elected and keeps the replicated log
+	require.Nil(t, leader.Shutdown())
+	nodesMu.Lock()
+	delete(nodes, leader.id)
+	nodesMu.Unlock()
+
+	leader = waitForLeader(t, nodes)
+	leader.replicate(stateRecord{Op: opCounterSet, Type: "counter", Name: "second", Value: 2})
+
+	for id := range nodes {
+		require.Eventually(t, func() bool {
+			names := fsms[id].names()
+			return len(names) == 2 && names[0] == "first" && names[1] == "second"
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+}
+
+func TestClusterForwardsWithMutualTLS(t *testing.T) {
+	tt, config := setupTestTLS(t, "require")
+
+	ids := []string{"n1", "n2"}
+	peers := []clusterPeer{}
+	listeners := map[string]net.Listener{}
+	nodes := map[string]*Cluster{}
+
+	for _, id := range ids {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.Nil(t, err)
+		listeners[id] = listener
+		peers = append(peers, clusterPeer{ID: id, RaftAddr: freeAddr(t), HTTPAddr: "https://" + listener.Addr().String()})
+	}
+
+	for _, id := range ids {
+		id := id
+		node, err := newCluster(clusterConfig{ID: id, Peers: peers}, &recordingFSM{})
+		require.Nil(t, err)
+		defer node.Shutdown()
+		nodes[id] = node
+
+		server := &http.Server{Handler: node.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, id)
+		}))}
+		go server.Serve(tls.NewListener(listeners[id], config))
+		defer server.Close()
+	}
+
+	// the follower presents its certificate to the leader, which requires one
+	leader := waitForLeader(t, nodes)
+	for id := range nodes {
+		if nodes[id] == leader {
+			continue
+		}
+		tt.url = "https://" + listeners[id].Addr().String()
+		status, body, err := tt.get("/counter/first/value", tt.client, "")
+		require.Nil(t, err)
+		require.Equal(t, 200, status)
+		require.Equal(t, leader.id, body)
+	}
+}
+
+// lostFuture is a record that failed to commit.
+type lostFuture struct{}
+
+func (lostFuture) Error() error          { return raft.ErrLeadershipLost }
+func (lostFuture) Index() uint64         { return 0 }
+func (lostFuture) Response() interface{} { return nil }
+
+func TestClusterCommitBeforeReply(t *testing.T) {
+	fsm := &recordingFSM{}
+	self := clusterPeer{ID: "solo", RaftAddr: freeAddr(t), HTTPAddr: "http://127.0.0.1:1"}
+	node, err := newCluster(clusterConfig{ID: self.ID, Peers: []clusterPeer{self}}, fsm)
+	require.Nil(t, err)
+	defer node.Shutdown()
+	waitForLeader(t, map[string]*Cluster{self.ID: node})
+
+	server := httptest.NewServer(node.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/lost" {
+			node.applyMu.Lock()
+			node.issued++
+			node.pending = append(node.pending, lostFuture{})
+			node.appliedC.Broadcast()
+			node.applyMu.Unlock()
+		} else {
+			node.replicate(stateRecord{Op: opCounterSet, Type: "counter", Name: r.URL.Path, Value: 1})
+		}
+		w.Header().Set("X-Fencing-Token", "1")
+		fmt.Fprint(w, "ok")
+	})))
+	defer server.Close()
+
+	// the reply is sent once the record is committed
+	status, body := get(t, server.URL+"/first")
+	require.Equal(t, 200, status)
+	require.Equal(t, "ok", body)
+	require.Equal(t, []string{"/first"}, fsm.names())
+
+	rep, err := http.Get(server.URL + "/lost")
+	require.Nil(t, err)
+	buf, _ := io.ReadAll(rep.Body)
+	rep.Body.Close()
+	require.Equal(t, 503, rep.StatusCode)
+	require.Equal(t, ErrNoLeader.Error(), string(buf))
+	require.Empty(t, rep.Header.Get("X-Fencing-Token"))
+
+	// later requests don't fail for it
+	status, _ = get(t, server.URL+"/second")
+	require.Equal(t, 200, status)
+	require.Equal(t, []string{"/first", "/second"}, fsm.names())
+}
+
+func TestBouncerFSMResync(t *testing.T) {
+	defer deleteCounter("fsm-resync")
+	defer deleteSemaphore("fsm-resync")
+
+	fsm := &bouncerFSM{}
+	buf, _ := json.Marshal(stateRecord{Op: opCounterSet, Type: "counter", Name: "fsm-resync", Value: 5})
+	require.Nil(t, fsm.Apply(&raft.Log{Data: buf}))
+
+	// changes applied by a leader that never committed them
+	counter, _ := getCounter("fsm-resync")
+	require.Equal(t, int64(5), counter.Value())
+	counter.Count(2)
+	semaphore, _ := getSemaphore("fsm-resync", 1)
+	_, _, err := semaphore.Acquire(context.Background(), 0, time.Minute, "")
+	require.Nil(t, err)
+
+	fsm.resync()
+
+	counter, _ = getCounter("fsm-resync")
+	require.Equal(t, int64(5), counter.Value())
+	_, err = findSemaphore("fsm-resync")
+	require.Equal(t, ErrNotFound, err)
+}
+
+func get(t *testing.T, url string) (int, string) {
+	rep, err := http.Get(url)
+	require.Nil(t, err)
+	defer rep.Body.Close()
+	buf, err := io.ReadAll(rep.Body)
+	require.Nil(t, err)
+	return rep.StatusCode, string(buf)
+}
+
+func TestBouncerFSMSnapshotRestore(t *testing.T) {
+	counter, _ := getCounter("fsm-counter")
+	counter.Reset(42)
+	semaphore, _ := getSemaphore("fsm-semaphore", 3)
+	_, _, err := semaphore.Acquire(context.Background(), 0, time.Minute, "fsm-key")
+	require.Nil(t, err)
+
+	fsm := &bouncerFSM{}
+	snapshot, err := fsm.Snapshot()
+	require.Nil(t, err)
+	sink := &memorySink{}
+	require.Nil(t, snapshot.Persist(sink))
+
+	deleteCounter("fsm-counter")
+	deleteSemaphore("fsm-semaphore")
+	defer deleteCounter("fsm-counter")
+	defer deleteSemaphore("fsm-semaphore")
+
+	require.Nil(t, fsm.Restore(io.NopCloser(&sink.Buffer)))
+
+	counter, _ = getCounter("fsm-counter")
+	require.Equal(t, int64(42), counter.Value())
+	semaphore, _ = getSemaphore("fsm-semaphore", 3)
+	_, ok := semaphore.getKey("fsm-key")
+	require.True(t, ok)
+}
@@ -0,0 +1,48 @@
+package bouncermain
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// strictMode disables creating objects on first use. Objects must be
+// configured with PUT first, and calls on any other name return 404.
+var strictMode = false
+
+// maxConfigSize limits the body of a configure request.
+const maxConfigSize = 64 * 1024
+
+// configureFunc creates or reconfigures an object from its JSON config, and
+// reports whether it was created.
+type configureFunc = func(name string, body []byte) (created bool, err error)
+
+// decodeConfig decodes a JSON config into v, which holds the defaults. An
+// empty body keeps them all.
+func decodeConfig(body []byte, v interface{}) error {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	return nil
+}
+
+// noConfig returns the config getter of a type without settings, which only
+// checks that the object exists.
+func noConfig[T any](m map[string]T, mu *sync.RWMutex) ConfigGetter {
+	return func(name string) (interface{}, error) {
+		mu.RLock()
+		defer mu.RUnlock()
+
+		if _, ok := m[name]; !ok {
+			return nil, ErrNotFound
+		}
+		return struct{}{}, nil
+	}
+}
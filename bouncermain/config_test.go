@@ -0,0 +1,155 @@
+package bouncermain_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSemaphoreConfigure(t *testing.T) {
+	url := fmt.Sprintf("%s/semaphore/configured", server.URL)
+
+	status, body, err := PutRequest(url, `{"size": 2}`)
+	require.Nil(t, err)
+	require.Equal(t, 201, status)
+	require.JSONEq(t, `{"size": 2}`, body)
+
+	// acquire doesn't shrink it back to its default size
+	for i := 0; i < 2; i++ {
+		status, _, err = GetRequest(url + "/acquire?maxwait=0")
+		require.Nil(t, err)
+		require.Equal(t, 200, status)
+	}
+
+	status, _, err = GetRequest(url + "/acquire?maxwait=0")
+	require.Nil(t, err)
+	require.Equal(t, 408, status)
+
+	// growing it lets one more in
+	status, body, err = PutRequest(url, `{"size": 3}`)
+	require.Nil(t, err)
+	require.Equal(t, 200, status)
+	require.JSONEq(t, `{"size": 3}`, body)
+
+	status, _, err = GetRequest(url + "/acquire?maxwait=0&size=1")
+	require.Nil(t, err)
+	require.Equal(t, 200, status)
+
+	status, body, err = GetRequest(url)
+	require.Nil(t, err)
+	require.Equal(t, 200, status)
+	require.JSONEq(t, `{"size": 3}`, body)
+}
+
+func TestTokenBucketConfigure(t *testing.T) {
+	url := fmt.Sprintf("%s/tokenbucket/configured", server.URL)
+
+	status, body, err := PutRequest(url, `{"size": 3, "interval": 60000}`)
+	require.Nil(t, err)
+	require.Equal(t, 201, status)
+	require.JSONEq(t, `{"size": 3, "interval": 60000}`, body)
+
+	for i := 0; i < 3; i++ {
+		status, _, err = GetRequest(url + "/acquire?maxwait=0")
+		require.Nil(t, err)
+		require.Equal(t, 204, status)
+	}
+
+	status, _, err = GetRequest(url + "/acquire?maxwait=0")
+	require.Nil(t, err)
+	require.Equal(t, 408, status)
+}
+
+func TestRateLimitConfigure(t *testing.T) {
+	url := fmt.Sprintf("%s/ratelimit/configured", server.URL)
+
+	status, body, err := PutRequest(url, `{"algo": "sliding", "rate": 2, "per": 60000}`)
+	require.Nil(t, err)
+	require.Equal(t, 201, status)
+	require.JSONEq(t, `{"algo": "sliding", "rate": 2, "per": 60000}`, body)
+
+	// the default gcra parameters of acquire are ignored
+	for i := 0; i < 2; i++ {
+		status, _, err = GetRequest(url + "/acquire?maxwait=0")
+		require.Nil(t, err)
+		require.Equal(t, 204, status)
+	}
+
+	status, _, err = GetRequest(url + "/acquire?maxwait=0")
+	require.Nil(t, err)
+	require.Equal(t, 408, status)
+}
+
+func TestBarrierConfigureTriggersWaiting(t *testing.T) {
+	url := fmt.Sprintf("%s/barrier/configured", server.URL)
+
+	status, _, err := PutRequest(url, `{"size": 3}`)
+	require.Nil(t, err)
+	require.Equal(t, 201, status)
+
+	done := make(chan int)
+	go func() {
+		status, _, _ := GetRequest(url + "/wait?maxwait=5000")
+		done <- status
+	}()
+
+	require.Eventually(t, func() bool {
+		_, body, _ := GetRequest(url + "/stats")
+		return strings.Contains(body, `"waiting":1`)
+	}, time.Second, 10*time.Millisecond)
+
+	status, _, err = PutRequest(url, `{"size": 1}`)
+	require.Nil(t, err)
+	require.Equal(t, 200, status)
+	require.Equal(t, 204, <-done)
+}
+
+func TestConfigureWithoutSettings(t *testing.T) {
+	for _, typeName := range []string{"counter", "event", "rwlock"} {
+		url := fmt.Sprintf("%s/%s/configured", server.URL, typeName)
+
+		status, _, err := GetRequest(url)
+		require.Nil(t, err)
+		require.Equal(t, 404, status, typeName)
+
+		status, _, err = PutRequest(url, "")
+		require.Nil(t, err)
+		require.Equal(t, 201, status, typeName)
+
+		status, _, err = PutRequest(url, "{}")
+		require.Nil(t, err)
+		require.Equal(t, 200, status, typeName)
+
+		status, _, err = GetRequest(url)
+		require.Nil(t, err)
+		require.Equal(t, 200, status, typeName)
+	}
+}
+
+func TestConfigureInvalid(t *testing.T) {
+	cases := []struct {
+		path string
+		body string
+	}{
+		{"semaphore/invalid", `{"size": 0}`},
+		{"semaphore/invalid", `{"size": "ten"}`},
+		{"semaphore/invalid", `{"limit": 10}`},
+		{"tokenbucket/invalid", `{"interval": 0}`},
+		{"ratelimit/invalid", `{"algo": "leaky"}`},
+		{"counter/invalid", `{"value": 1}`},
+		{"watchdog/invalid", `{"expires": -1}`},
+	}
+
+	for _, c := range cases {
+		status, _, err := PutRequest(fmt.Sprintf("%s/%s", server.URL, c.path), c.body)
+		require.Nil(t, err)
+		require.Equal(t, 400, status, c.body)
+	}
+
+	status, _, err := GetRequest(fmt.Sprintf("%s/semaphore/invalid", server.URL))
+	require.Nil(t, err)
+	require.Equal(t, 404, status)
+}
@@ -0,0 +1,422 @@
+package bouncermain
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+// Conflict policies, for calls with parameters different from those of a
+// configured object.
+const (
+	conflictWarn   = "warn"
+	conflictReject = "reject"
+)
+
+// objectMatch selects the objects an entry of the config file applies to,
+// by exact name or by a glob pattern like "api.*".
+type objectMatch struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+}
+
+func (m objectMatch) validate() error {
+	if (m.Name == "") == (m.Pattern == "") {
+		return fmt.Errorf("%w: each entry needs either 'name' or 'pattern'", ErrInvalidConfig)
+	}
+	if _, err := path.Match(m.Pattern, ""); err != nil {
+		return fmt.Errorf("%w: pattern %q: %v", ErrInvalidConfig, m.Pattern, err)
+	}
+	return nil
+}
+
+type SemaphoreEntry struct {
+	objectMatch
+	SemaphoreConfig
+}
+
+type TokenBucketEntry struct {
+	objectMatch
+	TokenBucketConfig
+}
+
+type RateLimiterEntry struct {
+	objectMatch
+	RateLimiterConfig
+}
+
+type BarrierEntry struct {
+	objectMatch
+	BarrierConfig
+}
+
+type WatchdogEntry struct {
+	objectMatch
+	WatchdogConfig
+}
+
+// FileConfig is the declarative configuration loaded from BOUNCER_CONFIG_FILE.
+// Objects listed by name are created on startup, and objects matching a
+// pattern get its configuration when created on first use. Settings take the
+// same keys as the environment, which overrides them.
+type FileConfig struct {
+	Settings     map[string]interface{} `json:"settings"`
+	Conflicts    string                 `json:"conflicts"`
+	Tokens       []TokenEntry           `json:"tokens"`
+	Semaphores   []SemaphoreEntry       `json:"semaphores"`
+	TokenBuckets []TokenBucketEntry     `json:"tokenbuckets"`
+	RateLimits   []RateLimiterEntry     `json:"ratelimits"`
+	Barriers     []BarrierEntry         `json:"barriers"`
+	Watchdogs    []WatchdogEntry        `json:"watchdogs"`
+	Namespaces   []NamespaceEntry       `json:"namespaces"`
+}
+
+var fileConfig = &FileConfig{Conflicts: conflictWarn}
+var fileConfigMutex = &sync.RWMutex{}
+
+// readConfigFile reads a YAML, TOML or JSON config file, as told by its
+// extension, and checks every entry.
+func readConfigFile(filename string) (*FileConfig, error) {
+	v := viper.New()
+	v.SetConfigFile(filename)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+
+	// viper decodes every format to maps, so go through JSON to reuse the
+	// config types and their tags
+	buf, err := json.Marshal(v.AllSettings())
+	if err != nil {
+		return nil, err
+	}
+
+	config := &FileConfig{Conflicts: conflictWarn}
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(config); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+
+	return config, config.validate()
+}
+
+// validate checks every entry and fills in the defaults used by PUT.
+func (c *FileConfig) validate() error {
+	if c.Conflicts != conflictWarn && c.Conflicts != conflictReject {
+		return fmt.Errorf("%w: 'conflicts' must be 'warn' or 'reject'", ErrInvalidConfig)
+	}
+
+	if err := validateTokens(c.Tokens); err != nil {
+		return err
+	}
+
+	known := viper.AllKeys()
+	for key := range c.Settings {
+		if !slices.Contains(known, strings.ToLower(key)) || strings.EqualFold(key, "configFile") {
+			return fmt.Errorf("%w: unknown setting %q", ErrInvalidConfig, key)
+		}
+	}
+
+	for i := range c.Semaphores {
+		e := &c.Semaphores[i]
+		if err := e.validate(); err != nil {
+			return err
+		}
+		e.Size = cmp.Or(e.Size, 1)
+	}
+
+	for i := range c.TokenBuckets {
+		e := &c.TokenBuckets[i]
+		if err := e.validate(); err != nil {
+			return err
+		}
+		e.Size = cmp.Or(e.Size, 1)
+		e.Interval = cmp.Or(e.Interval, 1000)
+		if e.Interval < 0 {
+			return ErrInvalidInterval
+		}
+	}
+
+	for i := range c.RateLimits {
+		e := &c.RateLimits[i]
+		if err := e.validate(); err != nil {
+			return err
+		}
+		e.RateLimiterConfig = rateLimiterDefaults.merge(e.RateLimiterConfig)
+		if err := e.normalize(); err != nil {
+			return err
+		}
+	}
+
+	for i := range c.Barriers {
+		e := &c.Barriers[i]
+		if err := e.validate(); err != nil {
+			return err
+		}
+		e.Size = cmp.Or(e.Size, 2)
+	}
+
+	for i := range c.Watchdogs {
+		e := &c.Watchdogs[i]
+		if err := e.validate(); err != nil {
+			return err
+		}
+		e.Expires = cmp.Or(e.Expires, 60000)
+		if e.Expires < 0 || e.ExpiresAt != "" {
+			return fmt.Errorf("%w: watchdogs take only a positive 'expires'", ErrInvalidConfig)
+		}
+	}
+
+	for _, e := range c.Namespaces {
+		if err := e.validate(); err != nil {
+			return err
+		}
+		if e.MaxObjects < 0 {
+			return fmt.Errorf("%w: 'max_objects' can't be negative", ErrInvalidConfig)
+		}
+	}
+
+	return nil
+}
+
+type matcher interface {
+	match() objectMatch
+}
+
+func (m objectMatch) match() objectMatch { return m }
+
+// findEntry returns the entry for name, preferring an exact name to the
+// first matching pattern.
+func findEntry[T matcher](entries []T, name string) (entry T, ok bool) {
+	for _, e := range entries {
+		if e.match().Name == name {
+			return e, true
+		}
+	}
+	for _, e := range entries {
+		if p := e.match().Pattern; p != "" {
+			if matched, _ := path.Match(p, name); matched {
+				return e, true
+			}
+		}
+	}
+	return entry, false
+}
+
+func currentFileConfig() *FileConfig {
+	fileConfigMutex.RLock()
+	defer fileConfigMutex.RUnlock()
+	return fileConfig
+}
+
+func configuredSemaphore(name string) (SemaphoreConfig, bool) {
+	e, ok := findEntry(currentFileConfig().Semaphores, name)
+	return e.SemaphoreConfig, ok
+}
+
+func configuredTokenBucket(name string) (TokenBucketConfig, bool) {
+	e, ok := findEntry(currentFileConfig().TokenBuckets, name)
+	return e.TokenBucketConfig, ok
+}
+
+func configuredRateLimiter(name string) (RateLimiterConfig, bool) {
+	e, ok := findEntry(currentFileConfig().RateLimits, name)
+	return e.RateLimiterConfig, ok
+}
+
+func configuredBarrier(name string) (BarrierConfig, bool) {
+	e, ok := findEntry(currentFileConfig().Barriers, name)
+	return e.BarrierConfig, ok
+}
+
+func configuredWatchdog(name string) (WatchdogConfig, bool) {
+	e, ok := findEntry(currentFileConfig().Watchdogs, name)
+	return e.WatchdogConfig, ok
+}
+
+// paramsConflict handles a call with parameters different from those of a
+// configured object, which are used instead unless the call is rejected.
+func paramsConflict(typeName string, name string, given interface{}, configured interface{}) error {
+	if currentFileConfig().Conflicts == conflictReject {
+		return ErrConfigConflict
+	}
+
+	log.Warn().
+		Str("type", typeName).
+		Str("name", name).
+		Interface("given", given).
+		Interface("configured", configured).
+		Msg("parameters differ from the configured ones, which are used instead")
+	return nil
+}
+
+// applyFileObjects creates the objects listed by name in the config file,
+// and reconfigures the existing ones matching its patterns.
+func applyFileObjects(c *FileConfig) {
+	for _, e := range c.Semaphores {
+		if e.Name != "" {
+			declareSemaphore(e.Name, e.SemaphoreConfig)
+		}
+	}
+	for _, name := range mapNames(semaphores, semaphoresMutex) {
+		if e, ok := findEntry(c.Semaphores, name); ok && e.Name == "" {
+			declareSemaphore(name, e.SemaphoreConfig)
+		}
+	}
+
+	for _, e := range c.TokenBuckets {
+		if e.Name != "" {
+			declareTokenBucket(e.Name, e.TokenBucketConfig)
+		}
+	}
+	for _, name := range mapNames(buckets, bucketsMutex) {
+		if e, ok := findEntry(c.TokenBuckets, name); ok && e.Name == "" {
+			declareTokenBucket(name, e.TokenBucketConfig)
+		}
+	}
+
+	for _, e := range c.RateLimits {
+		if e.Name != "" {
+			declareRateLimiter(e.Name, e.RateLimiterConfig)
+		}
+	}
+	for _, name := range mapNames(rateLimiters, rateLimitersMutex) {
+		if e, ok := findEntry(c.RateLimits, name); ok && e.Name == "" {
+			declareRateLimiter(name, e.RateLimiterConfig)
+		}
+	}
+
+	for _, e := range c.Barriers {
+		if e.Name != "" {
+			declareBarrier(e.Name, e.BarrierConfig)
+		}
+	}
+	for _, name := range mapNames(barriers, barriersMutex) {
+		if e, ok := findEntry(c.Barriers, name); ok && e.Name == "" {
+			declareBarrier(name, e.BarrierConfig)
+		}
+	}
+
+	// kicks set their own expiration, so existing watchdogs are left alone
+	for _, e := range c.Watchdogs {
+		if e.Name != "" {
+			declareWatchdog(e.Name, e.WatchdogConfig)
+		}
+	}
+}
+
+// undeclareFileObjects clears the declaration of the objects the previous
+// config file applied to and the current one doesn't, removed from it or no
+// longer matching a pattern, so they stop rejecting acquires that change
+// them. Watchdogs aren't declared.
+func undeclareFileObjects(previous *FileConfig, c *FileConfig) {
+	undeclareRemoved(previous.Semaphores, c.Semaphores, mapNames(semaphores, semaphoresMutex), undeclareSemaphore)
+	undeclareRemoved(previous.TokenBuckets, c.TokenBuckets, mapNames(buckets, bucketsMutex), undeclareTokenBucket)
+	undeclareRemoved(previous.RateLimits, c.RateLimits, mapNames(rateLimiters, rateLimitersMutex), undeclareRateLimiter)
+	undeclareRemoved(previous.Barriers, c.Barriers, mapNames(barriers, barriersMutex), undeclareBarrier)
+}
+
+func undeclareRemoved[T matcher](previous []T, current []T, names []string, undeclare func(string)) {
+	for _, name := range names {
+		if _, ok := findEntry(previous, name); !ok {
+			continue
+		}
+		if _, ok := findEntry(current, name); !ok {
+			undeclare(name)
+		}
+	}
+}
+
+// setupConfigFile loads the config file, if any. Its objects are applied
+// later, once the journal is restored.
+func setupConfigFile() error {
+	filename := viper.GetString("configFile")
+	if filename == "" {
+		return nil
+	}
+
+	if _, err := loadConfigFile(filename); err != nil {
+		return err
+	}
+
+	log.Info().Str("file", filename).Msg("loaded config file")
+	return nil
+}
+
+// loadConfigFile reads the config file and makes it current, with its
+// settings under those in the environment.
+func loadConfigFile(filename string) (*FileConfig, error) {
+	config, err := readConfigFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	buf, err := json.Marshal(config.Settings)
+	if err != nil {
+		return nil, err
+	}
+	viper.SetConfigType("json")
+	if err := viper.ReadConfig(bytes.NewReader(buf)); err != nil {
+		return nil, err
+	}
+
+	fileConfigMutex.Lock()
+	fileConfig = config
+	fileConfigMutex.Unlock()
+
+	return config, nil
+}
+
+var reloadMutex = &sync.Mutex{}
+
+// reloadConfigFile applies the config file again, without a restart. The log
+// level, timeouts and object configurations change live, and objects are
+// resized with their holders and waiters in place. Objects dropped from the
+// file keep their configuration, but acquires can change it again. An
+// invalid file is ignored, and the current configuration kept.
+func reloadConfigFile() {
+	reloadMutex.Lock()
+	defer reloadMutex.Unlock()
+
+	filename := viper.GetString("configFile")
+	if filename == "" {
+		log.Warn().Msg("no config file to reload")
+		return
+	}
+
+	previous := currentFileConfig()
+	config, err := loadConfigFile(filename)
+	if err != nil {
+		log.Error().Err(err).Str("file", filename).Msg("could not reload config file, keeping the current one")
+		return
+	}
+
+	setupLogging()
+	setupTimeouts()
+	applyFileObjects(config)
+	undeclareFileObjects(previous, config)
+	log.Info().Str("file", filename).Msg("reloaded config file")
+}
+
+// watchConfigFile reloads the config file when it changes, unless disabled
+// with BOUNCER_CONFIG_WATCH.
+func watchConfigFile() {
+	filename := viper.GetString("configFile")
+	if filename == "" || !viper.GetBool("configWatch") {
+		return
+	}
+
+	v := viper.New()
+	v.SetConfigFile(filename)
+	v.OnConfigChange(func(fsnotify.Event) { reloadConfigFile() })
+	v.WatchConfig()
+}
@@ -0,0 +1,306 @@
+package bouncermain
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfigFile = `
+conflicts: reject
+semaphores:
+  - name: file-db
+    size: 5
+  - pattern: file-api.*
+    size: 20
+tokenbuckets:
+  - pattern: file-bucket.*
+    size: 10
+    interval: 500
+ratelimits:
+  - name: file-limit
+    algo: sliding
+    rate: 100
+barriers:
+  - pattern: file-barrier.*
+    size: 3
+watchdogs:
+  - name: file-watchdog
+    expires: 5000
+`
+
+func writeConfigFile(t *testing.T, name string, content string) string {
+	filename := filepath.Join(t.TempDir(), name)
+	require.Nil(t, os.WriteFile(filename, []byte(content), 0o644))
+	return filename
+}
+
+func useConfigFile(t *testing.T, filename string) {
+	loadConfig()
+	viper.Set("configFile", filename)
+	require.Nil(t, setupConfigFile())
+	applyFileObjects(currentFileConfig())
+
+	t.Cleanup(func() {
+		viper.Set("configFile", "")
+		viper.ReadConfig(strings.NewReader("{}"))
+		setupTimeouts()
+		fileConfigMutex.Lock()
+		fileConfig = &FileConfig{Conflicts: conflictWarn}
+		fileConfigMutex.Unlock()
+	})
+}
+
+func TestConfigFile(t *testing.T) {
+	useConfigFile(t, writeConfigFile(t, "bouncer.yaml", testConfigFile))
+	defer deleteSemaphore("file-db")
+	defer deleteSemaphore("file-api.users")
+	defer deleteSemaphore("file-other")
+	defer deleteTokenBucket("file-bucket.a")
+	defer deleteRateLimiter("file-limit")
+	defer deleteBarrier("file-barrier.a")
+	defer deleteWatchdog("file-watchdog")
+
+	// named objects are created on startup
+	config, err := getSemaphoreConfig("file-db")
+	require.Nil(t, err)
+	require.Equal(t, &SemaphoreConfig{Size: 5}, config)
+
+	config, err = getRateLimiterConfig("file-limit")
+	require.Nil(t, err)
+	require.Equal(t, &RateLimiterConfig{Algorithm: algoSliding, Rate: 100, Per: 1000}, config)
+
+	config, err = getWatchdogConfig("file-watchdog")
+	require.Nil(t, err)
+	require.Equal(t, int64(5000), config.(*WatchdogConfig).Expires)
+
+	// patterns apply on first use
+	semaphore, err := getSemaphore("file-api.users", 0)
+	require.Nil(t, err)
+	require.Equal(t, uint64(20), semaphore.Size)
+
+	bucket, err := getTokenBucket("file-bucket.a", 0, 0)
+	require.Nil(t, err)
+	require.Equal(t, uint64(10), bucket.Size)
+	require.Equal(t, 500*time.Millisecond, bucket.interval)
+
+	barrier, err := getBarrier("file-barrier.a", 0)
+	require.Nil(t, err)
+	require.Equal(t, uint64(3), barrier.Size)
+
+	// the same parameters aren't a conflict, different ones are
+	_, err = getSemaphore("file-api.users", 20)
+	require.Nil(t, err)
+	_, err = getSemaphore("file-api.users", 2)
+	require.ErrorIs(t, err, ErrConfigConflict)
+	_, err = getTokenBucket("file-bucket.a", 0, time.Second)
+	require.ErrorIs(t, err, ErrConfigConflict)
+	_, err = getRateLimiter("file-limit", RateLimiterConfig{Algorithm: algoGCRA})
+	require.ErrorIs(t, err, ErrConfigConflict)
+	_, err = getBarrier("file-barrier.a", 2)
+	require.ErrorIs(t, err, ErrConfigConflict)
+	require.Equal(t, uint64(20), semaphore.Size)
+
+	// other names are left alone
+	semaphore, err = getSemaphore("file-other", 2)
+	require.Nil(t, err)
+	require.Equal(t, uint64(2), semaphore.Size)
+}
+
+func TestConfigFileWarnsOnConflict(t *testing.T) {
+	useConfigFile(t, writeConfigFile(t, "bouncer.toml", `
+[[semaphores]]
+pattern = "file-warn.*"
+size = 4
+`))
+	defer deleteSemaphore("file-warn.a")
+
+	semaphore, err := getSemaphore("file-warn.a", 1)
+	require.Nil(t, err)
+	require.Equal(t, uint64(4), semaphore.Size)
+
+	semaphore, err = getSemaphore("file-warn.a", 2)
+	require.Nil(t, err)
+	require.Equal(t, uint64(4), semaphore.Size)
+}
+
+func TestConfigFileStrictMode(t *testing.T) {
+	useConfigFile(t, writeConfigFile(t, "bouncer.json", `{"semaphores": [{"pattern": "file-strict.*"}]}`))
+	defer deleteSemaphore("file-strict.a")
+
+	strictMode = true
+	defer func() { strictMode = false }()
+
+	semaphore, err := getSemaphore("file-strict.a", 0)
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), semaphore.Size)
+
+	_, err = getSemaphore("file-undeclared", 0)
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestConfigFileInvalid(t *testing.T) {
+	for _, content := range []string{
+		`{"semaphores": [{"size": 1}]}`,
+		`{"semaphores": [{"name": "a", "pattern": "a.*"}]}`,
+		`{"semaphores": [{"pattern": "[", "size": 1}]}`,
+		`{"semaphores": [{"name": "a", "sise": 1}]}`,
+		`{"tokenbuckets": [{"name": "a", "interval": -1}]}`,
+		`{"ratelimits": [{"name": "a", "algo": "leaky"}]}`,
+		`{"conflicts": "ignore"}`,
+		`{"counters": []}`,
+		`{"settings": {"logLevl": "debug"}}`,
+		`{"namespaces": [{"name": "a", "max_objects": -1}]}`,
+	} {
+		_, err := readConfigFile(writeConfigFile(t, "bouncer.json", content))
+		require.NotNil(t, err, content)
+	}
+}
+
+func TestConfigFileNamespaces(t *testing.T) {
+	useConfigFile(t, writeConfigFile(t, "bouncer.yaml", `
+settings:
+  namespaceMaxObjects: 100
+namespaces:
+  - pattern: small-*
+    max_objects: 1
+  - name: unlimited
+`))
+
+	require.Equal(t, 1, namespaceLimit("small-a"))
+	require.Equal(t, 0, namespaceLimit("unlimited"))
+	require.Equal(t, 100, namespaceLimit("other"))
+}
+
+func TestConfigFileReload(t *testing.T) {
+	filename := writeConfigFile(t, "bouncer.yaml", `
+semaphores:
+  - pattern: reload.*
+    size: 1
+tokenbuckets:
+  - name: reload-bucket
+    size: 10
+    interval: 60000
+`)
+	useConfigFile(t, filename)
+	defer deleteSemaphore("reload.a")
+	defer deleteTokenBucket("reload-bucket")
+
+	level := zerolog.GlobalLevel()
+	defer zerolog.SetGlobalLevel(level)
+
+	semaphore, err := getSemaphore("reload.a", 0)
+	require.Nil(t, err)
+	_, _, err = semaphore.Acquire(context.Background(), 0, time.Minute, "")
+	require.Nil(t, err)
+
+	// a waiter gets the slot added by the reload
+	acquired := make(chan error, 1)
+	go func() {
+		_, _, err := semaphore.Acquire(context.Background(), time.Second, time.Minute, "")
+		acquired <- err
+	}()
+
+	bucket, err := getTokenBucket("reload-bucket", 0, 0)
+	require.Nil(t, err)
+	require.Nil(t, bucket.Acquire(context.Background(), 1, 0, time.Now()))
+
+	require.Nil(t, os.WriteFile(filename, []byte(`
+settings:
+  logLevel: error
+  maxSleepDuration: 1000
+semaphores:
+  - pattern: reload.*
+    size: 2
+tokenbuckets:
+  - name: reload-bucket
+    size: 5
+    interval: 1000
+`), 0o644))
+	reloadConfigFile()
+
+	require.Nil(t, <-acquired)
+	require.Equal(t, uint64(2), semaphore.Size)
+	require.Equal(t, zerolog.ErrorLevel, zerolog.GlobalLevel())
+	require.Equal(t, time.Second, maxSleepDuration.Get())
+
+	// the tokens left are brought within the new size, and the next refill
+	// within the new interval
+	require.Equal(t, uint64(5), bucket.Size)
+	require.LessOrEqual(t, bucket.available, int64(5))
+	require.LessOrEqual(t, time.Until(time.Unix(0, bucket.nextRefill)), time.Second)
+
+	// an invalid file leaves the configuration as it was
+	require.Nil(t, os.WriteFile(filename, []byte(`semaphores: [{pattern: "["}]`), 0o644))
+	reloadConfigFile()
+
+	config, ok := configuredSemaphore("reload.a")
+	require.True(t, ok)
+	require.Equal(t, uint64(2), config.Size)
+}
+
+func TestConfigFileReloadRemovedEntry(t *testing.T) {
+	filename := writeConfigFile(t, "bouncer.yaml", `
+conflicts: reject
+semaphores:
+  - name: removed-sem
+    size: 2
+ratelimits:
+  - pattern: removed-limit.*
+    rate: 10
+barriers:
+  - name: kept-barrier
+    size: 3
+`)
+	useConfigFile(t, filename)
+	defer deleteSemaphore("removed-sem")
+	defer deleteRateLimiter("removed-limit.a")
+	defer deleteBarrier("kept-barrier")
+
+	_, err := getSemaphore("removed-sem", 3)
+	require.ErrorIs(t, err, ErrConfigConflict)
+	_, err = getRateLimiter("removed-limit.a", RateLimiterConfig{Rate: 20, Per: 1000})
+	require.ErrorIs(t, err, ErrConfigConflict)
+
+	require.Nil(t, os.WriteFile(filename, []byte(`
+conflicts: reject
+barriers:
+  - name: kept-barrier
+    size: 3
+`), 0o644))
+	reloadConfigFile()
+
+	// objects dropped from the file keep their configuration, but acquires
+	// can change it again
+	semaphore, err := getSemaphore("removed-sem", 3)
+	require.Nil(t, err)
+	require.Equal(t, uint64(3), semaphore.Size)
+
+	_, err = getRateLimiter("removed-limit.a", RateLimiterConfig{Rate: 20, Per: 1000})
+	require.Nil(t, err)
+
+	// those still in it don't
+	_, err = getBarrier("kept-barrier", 4)
+	require.ErrorIs(t, err, ErrConfigConflict)
+}
+
+func TestConfigFileWatch(t *testing.T) {
+	filename := writeConfigFile(t, "bouncer.yaml", "semaphores: [{name: watched, size: 1}]")
+	useConfigFile(t, filename)
+	defer deleteSemaphore("watched")
+
+	watchConfigFile()
+	require.Nil(t, os.WriteFile(filename, []byte("semaphores: [{name: watched, size: 3}]"), 0o644))
+
+	require.Eventually(t, func() bool {
+		config, _ := getSemaphoreConfig("watched")
+		return config.(*SemaphoreConfig).Size == 3
+	}, 5*time.Second, 10*time.Millisecond)
+}
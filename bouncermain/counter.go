@@ -1,6 +1,7 @@
 package bouncermain
 
 import (
+	"context"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -14,10 +15,13 @@ type CounterStats struct {
 }
 
 type Counter struct {
-	Name  string
-	value int64
-	mutex *sync.RWMutex
-	Stats *CounterStats
+	Name     string
+	value    int64
+	mutex    *sync.RWMutex
+	Stats    *CounterStats
+	changedC chan struct{} // closed on the next change, nil if nobody waits
+	watchers int64
+	idleTracker
 }
 
 var counters = map[string]*Counter{}
@@ -29,6 +33,7 @@ func newCounter(name string) *Counter {
 		mutex: &sync.RWMutex{},
 		Stats: &CounterStats{CreatedAt: time.Now().Format(time.RFC3339)},
 	}
+	counter.touch()
 	counters[name] = counter
 	return counter
 }
@@ -36,19 +41,28 @@ func newCounter(name string) *Counter {
 func getCounter(name string) (*Counter, error) {
 	countersMutex.RLock()
 	counter, ok := counters[name]
+	if ok {
+		counter.touch()
+	}
 	countersMutex.RUnlock()
 
 	if ok {
 		return counter, nil
 	}
 
+	if strictMode {
+		return nil, ErrNotFound
+	}
+
 	// Counter doesn't exist, need to create it
 	countersMutex.Lock()
 	defer countersMutex.Unlock()
 
 	// Check again in case another goroutine created it
 	counter, ok = counters[name]
-	if !ok {
+	if ok {
+		counter.touch()
+	} else {
 		counter = newCounter(name)
 	}
 
@@ -56,31 +70,93 @@ func getCounter(name string) (*Counter, error) {
 }
 
 func (c *Counter) Count(amount int64) int64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
 	val := atomic.AddInt64(&c.value, amount)
 	atomic.AddUint64(&c.Stats.Value, 1)
 	atomic.AddUint64(&c.Stats.Increments, 1)
+
+	c.notifyChange()
+	recordChange(stateRecord{Op: opCounterSet, Type: "counter", Name: c.Name, Value: val})
 	return val
 }
 
 func (c *Counter) Reset(value int64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
 	atomic.StoreInt64(&c.value, value)
 	atomic.StoreUint64(&c.Stats.Value, 0)
 	atomic.AddUint64(&c.Stats.Resets, 1)
+
+	c.notifyChange()
+	recordChange(stateRecord{Op: opCounterSet, Type: "counter", Name: c.Name, Value: value})
 }
 
 func (c *Counter) Value() int64 {
 	return atomic.LoadInt64(&c.value)
 }
 
+// changed returns a channel closed on the next change of the value.
+func (c *Counter) changed() <-chan struct{} {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.changedC == nil {
+		c.changedC = make(chan struct{})
+	}
+	return c.changedC
+}
+
+// notifyChange wakes the clients waiting for a change. Must be called with
+// the counter mutex held.
+func (c *Counter) notifyChange() {
+	if c.changedC != nil {
+		close(c.changedC)
+		c.changedC = nil
+	}
+}
+
+// watch calls notify with the value, then again after each change, until
+// ctx is done or notify fails. Changes in quick succession may be notified
+// once, with the last value.
+func (c *Counter) watch(ctx context.Context, notify func(value int64) error) error {
+	atomic.AddInt64(&c.watchers, 1)
+	defer atomic.AddInt64(&c.watchers, -1)
+
+	for {
+		changedC := c.changed()
+		if err := notify(c.Value()); err != nil {
+			return err
+		}
+		if err := waitFor(ctx, changedC, -1); err != nil {
+			return err
+		}
+	}
+}
+
+// busy reports whether clients are watching the counter for changes.
+func (c *Counter) busy() bool {
+	return atomic.LoadInt64(&c.watchers) > 0
+}
+
 func deleteCounter(name string) error {
 	countersMutex.Lock()
 	defer countersMutex.Unlock()
 
+	return removeCounter(name)
+}
+
+// removeCounter must be called with countersMutex held.
+func removeCounter(name string) error {
 	if _, ok := counters[name]; !ok {
 		return ErrNotFound
 	}
 
 	delete(counters, name)
+	forgetMetrics("counter", name)
+	recordChange(stateRecord{Op: opDelete, Type: "counter", Name: name})
 	return nil
 }
 
@@ -95,3 +171,49 @@ func getCounterStats(name string) (interface{}, error) {
 
 	return counter.Stats, nil
 }
+
+// configureCounter creates a counter. Counters have no settings.
+func configureCounter(name string, body []byte) (created bool, err error) {
+	if err = decodeConfig(body, &struct{}{}); err != nil {
+		return false, err
+	}
+
+	countersMutex.Lock()
+	defer countersMutex.Unlock()
+
+	counter, ok := counters[name]
+	if ok {
+		counter.touch()
+	} else {
+		newCounter(name)
+	}
+	return !ok, nil
+}
+
+func restoreCounter(rec stateRecord) {
+	// restored even in strict mode, so not through getCounter
+	countersMutex.Lock()
+	counter, ok := counters[rec.Name]
+	if !ok {
+		counter = newCounter(rec.Name)
+	}
+	countersMutex.Unlock()
+
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+
+	atomic.StoreInt64(&counter.value, rec.Value)
+	counter.notifyChange()
+	recordChange(rec)
+}
+
+func captureCounters() []stateRecord {
+	countersMutex.RLock()
+	defer countersMutex.RUnlock()
+
+	records := make([]stateRecord, 0, len(counters))
+	for name, counter := range counters {
+		records = append(records, stateRecord{Op: opCounterSet, Type: "counter", Name: name, Value: counter.Value()})
+	}
+	return records
+}
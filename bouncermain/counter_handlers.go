@@ -74,7 +74,7 @@ func CounterCountHandler(w http.ResponseWriter, r *http.Request, ps httprouter.P
 	}
 
 	rep.WriteResponse(w, r, err)
-	logRequest(rep.Status, "counter", "count", ps[0].Value, 0, req).Send()
+	logRequest(r, rep.Status, "counter", "count", ps[0].Value, 0, req).Send()
 }
 
 // CounterResetHandler godoc
@@ -107,7 +107,7 @@ func CounterResetHandler(w http.ResponseWriter, r *http.Request, ps httprouter.P
 	}
 
 	rep.WriteResponse(w, r, err)
-	logRequest(rep.Status, "counter", "reset", ps[0].Value, 0, req).Send()
+	logRequest(r, rep.Status, "counter", "reset", ps[0].Value, 0, req).Send()
 }
 
 // CounterValueHandler godoc
@@ -133,7 +133,37 @@ func CounterValueHandler(w http.ResponseWriter, r *http.Request, ps httprouter.P
 	}
 
 	rep.WriteResponse(w, r, err)
-	logRequest(rep.Status, "counter", "value", ps[0].Value, 0, nil).Send()
+	logRequest(r, rep.Status, "counter", "value", ps[0].Value, 0, nil).Send()
+}
+
+// CounterConfigureHandler godoc
+// @Summary Create a counter
+// @Description Create a counter explicitly, as required in strict mode. Counters have no settings, so the body is empty or `{}`
+// @Tags Counter
+// @Accept json
+// @Produce json
+// @Param name path string true "Counter name"
+// @Success 200 {object} object "Counter reconfigured"
+// @Success 201 {object} object "Counter created"
+// @Failure 400 {string} Reply "Bad Request - invalid configuration"
+// @Router /counter/{name} [put]
+func CounterConfigureHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	status := ConfigureHandler(w, r, ps, configureCounter, noConfig(counters, countersMutex))
+	logRequest(r, status, "counter", "configure", ps[0].Value, 0, nil).Send()
+}
+
+// CounterConfigHandler godoc
+// @Summary Get counter configuration
+// @Description Get the current configuration of a counter
+// @Tags Counter
+// @Produce json
+// @Param name path string true "Counter name"
+// @Success 200 {object} object "Counter configuration"
+// @Failure 404 {string} Reply "Not Found - counter not found"
+// @Router /counter/{name} [get]
+func CounterConfigHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	status := ConfigHandler(w, r, ps, noConfig(counters, countersMutex))
+	logRequest(r, status, "counter", "config", ps[0].Value, 0, nil).Send()
 }
 
 // CounterDeleteHandler godoc
@@ -147,7 +177,7 @@ func CounterValueHandler(w http.ResponseWriter, r *http.Request, ps httprouter.P
 // @Router /counter/{name} [delete]
 func CounterDeleteHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	status := DeleteHandler(w, r, ps, deleteCounter)
-	logRequest(status, "counter", "delete", ps[0].Value, 0, nil).Send()
+	logRequest(r, status, "counter", "delete", ps[0].Value, 0, nil).Send()
 }
 
 // CounterStatsHandler godoc
@@ -161,5 +191,21 @@ func CounterDeleteHandler(w http.ResponseWriter, r *http.Request, ps httprouter.
 // @Router /counter/{name}/stats [get]
 func CounterStatsHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	status := StatsHandler(w, r, ps, getCounterStats)
-	logRequest(status, "counter", "stats", ps[0].Value, 0, nil).Send()
+	logRequest(r, status, "counter", "stats", ps[0].Value, 0, nil).Send()
+}
+
+// CounterListHandler godoc
+// @Summary List counters
+// @Description List existing counters with their configuration and stats, ordered by name
+// @Tags Counter
+// @Produce json
+// @Param prefix query string false "Only list names starting with prefix"
+// @Param limit query int false "Maximum number of items" default(100)
+// @Param after query string false "Only list names after this one, as returned in 'next'"
+// @Success 200 {object} ResourceList "Counters"
+// @Failure 400 {string} Reply "Bad Request - invalid parameters"
+// @Router /counter/ [get]
+func CounterListHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	status := ListHandler(w, r, "counter")
+	logRequest(r, status, "counter", "list", "", 0, nil).Send()
 }
@@ -1,14 +1,37 @@
 package bouncermain
 
 import (
-	"errors"
+	"github.com/pjwerneck/bouncer/bouncerapi"
 )
 
+// The errors are defined in bouncerapi, so clients can match them without
+// importing the server.
 var (
-	ErrInvalidSize   = errors.New("request: 'size' must be a positive non-zero integer")
-	ErrNotFound      = errors.New("request: object not found")
-	ErrTimedOut      = errors.New("timeout: 'maxwait' exceeded while waiting")
-	ErrKeyError      = errors.New("conflict: key already released or expired")
-	ErrEventClosed   = errors.New("conflict: event was already sent and closed")
-	ErrBarrierClosed = errors.New("conflict: barrier quorum already reached")
+	ErrInvalidSize     = bouncerapi.ErrInvalidSize
+	ErrInvalidLimit    = bouncerapi.ErrInvalidLimit
+	ErrInvalidCount    = bouncerapi.ErrInvalidCount
+	ErrInvalidRate     = bouncerapi.ErrInvalidRate
+	ErrInvalidAlgo     = bouncerapi.ErrInvalidAlgo
+	ErrInvalidBurst    = bouncerapi.ErrInvalidBurst
+	ErrInvalidConfig   = bouncerapi.ErrInvalidConfig
+	ErrInvalidInterval = bouncerapi.ErrInvalidInterval
+	ErrNotFound        = bouncerapi.ErrNotFound
+	ErrTimedOut        = bouncerapi.ErrTimedOut
+	ErrKeyError        = bouncerapi.ErrKeyError
+	ErrEventClosed     = bouncerapi.ErrEventClosed
+	ErrBarrierClosed   = bouncerapi.ErrBarrierClosed
+	ErrNoLeader        = bouncerapi.ErrNoLeader
+	ErrShuttingDown    = bouncerapi.ErrShuttingDown
+	ErrCanceled        = bouncerapi.ErrCanceled
+	ErrStaleToken      = bouncerapi.ErrStaleToken
+	ErrConfigConflict  = bouncerapi.ErrConfigConflict
+	ErrUnauthorized    = bouncerapi.ErrUnauthorized
+	ErrForbidden       = bouncerapi.ErrForbidden
+	ErrTooManyObjects  = bouncerapi.ErrTooManyObjects
+	ErrInvalidName     = bouncerapi.ErrInvalidName
+	ErrNotLeader       = bouncerapi.ErrNotLeader
+	ErrInvalidOp       = bouncerapi.ErrInvalidOp
+	ErrInvalidType     = bouncerapi.ErrInvalidType
+	ErrInvalidMessage  = bouncerapi.ErrInvalidMessage
+	ErrNoSubscriptions = bouncerapi.ErrNoSubscriptions
 )
@@ -1,6 +1,7 @@
 package bouncermain
 
 import (
+	"context"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -9,6 +10,7 @@ import (
 type EventStats struct {
 	Waited          uint64  `json:"waited"`
 	TimedOut        uint64  `json:"timed_out"`
+	Canceled        uint64  `json:"canceled"`
 	Triggered       uint64  `json:"triggered"`
 	TotalWaitTime   uint64  `json:"total_wait_time"`
 	AverageWaitTime float64 `json:"average_wait_time"`
@@ -21,7 +23,9 @@ type Event struct {
 	sendL   *sync.Mutex
 	closed  bool
 	waitC   chan struct{} // Changed from acquireC
+	waiters int64
 	Stats   *EventStats
+	idleTracker
 }
 
 var events = map[string]*Event{}
@@ -35,6 +39,7 @@ func newEvent(name string) (event *Event) {
 		Stats: &EventStats{CreatedAt: time.Now().Format(time.RFC3339)},
 	}
 
+	event.touch()
 	events[name] = event
 
 	return event
@@ -43,12 +48,19 @@ func newEvent(name string) (event *Event) {
 func getEvent(name string) (event *Event, err error) {
 	eventsMutex.RLock()
 	event, ok := events[name]
+	if ok {
+		event.touch()
+	}
 	eventsMutex.RUnlock()
 
 	if ok {
 		return event, nil
 	}
 
+	if strictMode {
+		return nil, ErrNotFound
+	}
+
 	// Event doesn't exist, need to create it
 	eventsMutex.Lock()
 	defer eventsMutex.Unlock()
@@ -56,6 +68,7 @@ func getEvent(name string) (event *Event, err error) {
 	// Check again in case another goroutine created it
 	event, ok = events[name]
 	if ok {
+		event.touch()
 		return event, nil
 	}
 
@@ -63,28 +76,15 @@ func getEvent(name string) (event *Event, err error) {
 	return event, nil
 }
 
-func (event *Event) Wait(maxwait time.Duration) (message string, err error) {
+func (event *Event) Wait(ctx context.Context, maxwait time.Duration) (message string, err error) {
 	started := time.Now()
 
-	switch {
-	case maxwait < 0:
-		<-event.waitC
-	case maxwait == 0:
-		select {
-		case <-event.waitC:
-		default:
-			atomic.AddUint64(&event.Stats.TimedOut, 1)
-			return "", ErrTimedOut
-		}
-	default:
-		timer := time.NewTimer(maxwait)
-		defer timer.Stop()
-		select {
-		case <-event.waitC:
-		case <-timer.C:
-			atomic.AddUint64(&event.Stats.TimedOut, 1)
-			return "", ErrTimedOut
-		}
+	atomic.AddInt64(&event.waiters, 1)
+	defer atomic.AddInt64(&event.waiters, -1)
+
+	if err = waitFor(ctx, event.waitC, maxwait); err != nil {
+		countGiveUp(err, &event.Stats.TimedOut, &event.Stats.Canceled)
+		return "", err
 	}
 
 	// Get message after successful wait
@@ -95,6 +95,7 @@ func (event *Event) Wait(maxwait time.Duration) (message string, err error) {
 	wait := uint64(time.Since(started) / time.Millisecond)
 	atomic.AddUint64(&event.Stats.Waited, 1)
 	atomic.AddUint64(&event.Stats.TotalWaitTime, wait)
+	observeWait("event", event.Name, time.Since(started))
 
 	return message, nil
 }
@@ -112,6 +113,7 @@ func (event *Event) Send(message string) (err error) {
 	event.closed = true
 
 	atomic.AddUint64(&event.Stats.Triggered, 1)
+	recordChange(stateRecord{Op: opEventSend, Type: "event", Name: event.Name, Message: message})
 	return nil
 }
 
@@ -124,25 +126,94 @@ func getEventStats(name string) (interface{}, error) {
 		return nil, ErrNotFound
 	}
 
-	// Create a copy and calculate average
-	stats := &EventStats{}
-	*stats = *event.Stats
-	waited := atomic.LoadUint64(&event.Stats.Waited)
-	if waited > 0 {
-		stats.AverageWaitTime = float64(atomic.LoadUint64(&event.Stats.TotalWaitTime)) / float64(waited)
+	stats := &EventStats{
+		Waited:        atomic.LoadUint64(&event.Stats.Waited),
+		TimedOut:      atomic.LoadUint64(&event.Stats.TimedOut),
+		Canceled:      atomic.LoadUint64(&event.Stats.Canceled),
+		Triggered:     atomic.LoadUint64(&event.Stats.Triggered),
+		TotalWaitTime: atomic.LoadUint64(&event.Stats.TotalWaitTime),
+		CreatedAt:     event.Stats.CreatedAt,
+	}
+	if stats.Waited > 0 {
+		stats.AverageWaitTime = float64(stats.TotalWaitTime) / float64(stats.Waited)
 	}
 
 	return stats, nil
 }
 
+func (event *Event) busy() bool {
+	return atomic.LoadInt64(&event.waiters) > 0
+}
+
 func deleteEvent(name string) error {
 	eventsMutex.Lock()
 	defer eventsMutex.Unlock()
 
+	return removeEvent(name)
+}
+
+// removeEvent must be called with eventsMutex held.
+func removeEvent(name string) error {
 	if _, ok := events[name]; !ok {
 		return ErrNotFound
 	}
 
 	delete(events, name)
+	forgetMetrics("event", name)
+	recordChange(stateRecord{Op: opDelete, Type: "event", Name: name})
 	return nil
 }
+
+// configureEvent creates an event. Events have no settings.
+func configureEvent(name string, body []byte) (created bool, err error) {
+	if err = decodeConfig(body, &struct{}{}); err != nil {
+		return false, err
+	}
+
+	eventsMutex.Lock()
+	defer eventsMutex.Unlock()
+
+	event, ok := events[name]
+	if ok {
+		event.touch()
+	} else {
+		newEvent(name)
+	}
+	return !ok, nil
+}
+
+func restoreEvent(rec stateRecord) {
+	// restored even in strict mode, so not through getEvent
+	eventsMutex.Lock()
+	event, ok := events[rec.Name]
+	if !ok {
+		event = newEvent(rec.Name)
+	}
+	eventsMutex.Unlock()
+
+	event.sendL.Lock()
+	defer event.sendL.Unlock()
+
+	// an event can only be sent once, so a replayed send is a no-op
+	if !event.closed {
+		event.message = rec.Message
+		close(event.waitC)
+		event.closed = true
+		recordChange(rec)
+	}
+}
+
+func captureEvents() []stateRecord {
+	eventsMutex.RLock()
+	defer eventsMutex.RUnlock()
+
+	records := []stateRecord{}
+	for name, event := range events {
+		event.sendL.Lock()
+		if event.closed {
+			records = append(records, stateRecord{Op: opEventSend, Type: "event", Name: name, Message: event.message})
+		}
+		event.sendL.Unlock()
+	}
+	return records
+}
@@ -54,6 +54,7 @@ func (r *EventSendRequest) Decode(values url.Values) error {
 // @Failure 400 {string} Reply "Bad Request - invalid parameters"
 // @Failure 404 {string} Reply "Not Found - event handler not found"
 // @Failure 408 {string} Reply "Request timeout"
+// @Failure 503 {string} Reply "Service Unavailable - server is shutting down"
 // @Router /event/{name}/wait [get]
 func EventWaitHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	var err error
@@ -69,8 +70,9 @@ func EventWaitHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Para
 	}
 
 	if err == nil {
+		var message string
 		start := time.Now()
-		message, err := event.Wait(req.MaxWait)
+		message, err = event.Wait(r.Context(), req.MaxWait)
 		wait = time.Since(start)
 
 		if errors.Is(err, ErrTimedOut) {
@@ -83,7 +85,7 @@ func EventWaitHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Para
 	}
 
 	rep.WriteResponse(w, r, err)
-	logRequest(rep.Status, "event", "wait", ps[0].Value, wait, req).Send()
+	logRequest(r, rep.Status, "event", "wait", ps[0].Value, wait, req).Send()
 }
 
 // EventSendHandler godoc
@@ -123,7 +125,37 @@ func EventSendHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Para
 	}
 
 	rep.WriteResponse(w, r, err)
-	logRequest(rep.Status, "event", "send", ps[0].Value, 0, req).Send()
+	logRequest(r, rep.Status, "event", "send", ps[0].Value, 0, req).Send()
+}
+
+// EventConfigureHandler godoc
+// @Summary Create an event
+// @Description Create an event explicitly, as required in strict mode. Events have no settings, so the body is empty or `{}`
+// @Tags Event
+// @Accept json
+// @Produce json
+// @Param name path string true "Event name"
+// @Success 200 {object} object "Event reconfigured"
+// @Success 201 {object} object "Event created"
+// @Failure 400 {string} Reply "Bad Request - invalid configuration"
+// @Router /event/{name} [put]
+func EventConfigureHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	status := ConfigureHandler(w, r, ps, configureEvent, noConfig(events, eventsMutex))
+	logRequest(r, status, "event", "configure", ps[0].Value, 0, nil).Send()
+}
+
+// EventConfigHandler godoc
+// @Summary Get event configuration
+// @Description Get the current configuration of an event
+// @Tags Event
+// @Produce json
+// @Param name path string true "Event name"
+// @Success 200 {object} object "Event configuration"
+// @Failure 404 {string} Reply "Not Found - event not found"
+// @Router /event/{name} [get]
+func EventConfigHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	status := ConfigHandler(w, r, ps, noConfig(events, eventsMutex))
+	logRequest(r, status, "event", "config", ps[0].Value, 0, nil).Send()
 }
 
 // EventDeleteHandler godoc
@@ -137,7 +169,7 @@ func EventSendHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Para
 // @Router /event/{name} [delete]
 func EventDeleteHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	status := DeleteHandler(w, r, ps, deleteEvent)
-	logRequest(status, "event", "delete", ps[0].Value, 0, nil).Send()
+	logRequest(r, status, "event", "delete", ps[0].Value, 0, nil).Send()
 }
 
 // EventStatsHandler godoc
@@ -151,5 +183,21 @@ func EventDeleteHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Pa
 // @Router /event/{name}/stats [get]
 func EventStatsHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	status := StatsHandler(w, r, ps, getEventStats)
-	logRequest(status, "event", "stats", ps[0].Value, 0, nil).Send()
+	logRequest(r, status, "event", "stats", ps[0].Value, 0, nil).Send()
+}
+
+// EventListHandler godoc
+// @Summary List events
+// @Description List existing events with their configuration and stats, ordered by name
+// @Tags Event
+// @Produce json
+// @Param prefix query string false "Only list names starting with prefix"
+// @Param limit query int false "Maximum number of items" default(100)
+// @Param after query string false "Only list names after this one, as returned in 'next'"
+// @Success 200 {object} ResourceList "Events"
+// @Failure 400 {string} Reply "Bad Request - invalid parameters"
+// @Router /event/ [get]
+func EventListHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	status := ListHandler(w, r, "event")
+	logRequest(r, status, "event", "list", "", 0, nil).Send()
 }
@@ -0,0 +1,121 @@
+package bouncermain
+
+import (
+	"net/url"
+	"sync/atomic"
+
+	"github.com/pjwerneck/bouncer/bouncerapi"
+)
+
+const FencingTokenHeader = bouncerapi.FencingTokenHeader
+
+type ValidateRequest struct {
+	Key   string `schema:"key"`
+	Token uint64 `schema:"token"`
+	ID    string `schema:"id"`
+}
+
+func newValidateRequest() *ValidateRequest {
+	return &ValidateRequest{
+		ID: "",
+	}
+}
+
+func (r *ValidateRequest) Decode(values url.Values) error {
+	return decoder.Decode(r, values)
+}
+
+// fencing hands out increasing tokens to the holders of a semaphore or
+// rwlock. A client paused past its key expiration still thinks it holds the
+// lock, so downstream services check its token before accepting a write.
+// Must be used with the owner's mutex held.
+type fencing struct {
+	counter *fenceCounter
+	tokens  map[string]uint64
+}
+
+// next returns the token for the next holder.
+func (f *fencing) next() uint64 {
+	return f.counter.next()
+}
+
+// hold records the token of key, as issued or restored from the journal.
+func (f *fencing) hold(key string, token uint64) {
+	if f.tokens == nil {
+		f.tokens = make(map[string]uint64)
+	}
+	f.tokens[key] = token
+	f.counter.raise(token)
+}
+
+func (f *fencing) drop(key string) {
+	delete(f.tokens, key)
+}
+
+// validate checks that token belongs to a current holder, and to key if
+// given.
+func (f *fencing) validate(key string, token uint64) error {
+	if key != "" {
+		if held, ok := f.tokens[key]; ok && held == token {
+			return nil
+		}
+		return ErrStaleToken
+	}
+
+	for _, held := range f.tokens {
+		if held == token {
+			return nil
+		}
+	}
+	return ErrStaleToken
+}
+
+// fenceCounter issues the tokens of every object of a type. Tokens only
+// need to increase for each name, but drawing them from a single sequence
+// keeps them increasing after a delete or an eviction by the janitor, without
+// remembering every name ever used.
+type fenceCounter struct {
+	last uint64 // accessed atomically
+}
+
+var semaphoreFence = &fenceCounter{}
+var rwlockFence = &fenceCounter{}
+
+var fenceCounters = map[string]*fenceCounter{
+	"semaphore": semaphoreFence,
+	"rwlock":    rwlockFence,
+}
+
+func (c *fenceCounter) next() uint64 {
+	return atomic.AddUint64(&c.last, 1)
+}
+
+// raise makes sure tokens issued from now on are above token.
+func (c *fenceCounter) raise(token uint64) {
+	for {
+		last := atomic.LoadUint64(&c.last)
+		if token <= last || atomic.CompareAndSwapUint64(&c.last, last, token) {
+			return
+		}
+	}
+}
+
+// captureFences records the last token issued for each type.
+func captureFences() []stateRecord {
+	records := []stateRecord{}
+	for typ, counter := range fenceCounters {
+		if last := atomic.LoadUint64(&counter.last); last > 0 {
+			records = append(records, stateRecord{Op: opFence, Type: typ, Token: last})
+		}
+	}
+	return records
+}
+
+// restoreFence restores the last token issued for a type, so tokens keep
+// increasing after a restart even if no one holds them. Records from before
+// the counters were per type name an object, but raise them just the same.
+func restoreFence(rec stateRecord) {
+	if counter, ok := fenceCounters[rec.Type]; ok {
+		counter.raise(rec.Token)
+	}
+}
@@ -0,0 +1,516 @@
+package bouncermain
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pjwerneck/bouncer/bouncerpb"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// rpcCodes maps the HTTP status of an error to its gRPC code.
+var rpcCodes = map[int]codes.Code{
+	http.StatusBadRequest:         codes.InvalidArgument,
+	http.StatusUnauthorized:       codes.Unauthenticated,
+	http.StatusForbidden:          codes.PermissionDenied,
+	http.StatusNotFound:           codes.NotFound,
+	http.StatusRequestTimeout:     codes.DeadlineExceeded,
+	http.StatusConflict:           codes.FailedPrecondition,
+	http.StatusTooManyRequests:    codes.ResourceExhausted,
+	http.StatusServiceUnavailable: codes.Unavailable,
+	StatusClientClosedRequest:     codes.Canceled,
+}
+
+// rpcDuration converts an optional time in milliseconds, or returns def if
+// it wasn't given.
+func rpcDuration(ms *int64, def time.Duration) time.Duration {
+	if ms == nil {
+		return def
+	}
+	return time.Duration(*ms) * time.Millisecond
+}
+
+// rpcName returns the key of an object within the namespace of a call.
+func rpcName(ctx context.Context, name string) string {
+	namespace, _ := ctx.Value(namespaceKey{}).(string)
+	return qualifiedName(namespace, name)
+}
+
+// rpcNames returns the object names in a request.
+func rpcNames(req interface{}) []string {
+	switch req := req.(type) {
+	case interface{ GetName() string }:
+		return []string{req.GetName()}
+	case interface{ GetNames() []string }:
+		return req.GetNames()
+	}
+	return nil
+}
+
+// rpcCert returns the verified client certificate of a call, if any.
+func rpcCert(ctx context.Context) *x509.Certificate {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 {
+		return nil
+	}
+	return info.State.VerifiedChains[0][0]
+}
+
+// rpcCall is a call being checked, with what's needed to log it.
+type rpcCall struct {
+	typeName string
+	call     string
+	client   string
+	names    []string
+}
+
+func newRPCCall(ctx context.Context, fullMethod string) *rpcCall {
+	service, method := path.Split(fullMethod)
+	service = strings.TrimSuffix(service, "/")
+	service = service[strings.LastIndex(service, ".")+1:]
+
+	return &rpcCall{
+		typeName: strings.ToLower(service),
+		call:     strings.ToLower(method),
+		client:   certName(rpcCert(ctx)),
+	}
+}
+
+// check does for a call what the HTTP middleware does for a request: it
+// checks the names, the token or client certificate and its grants, that
+// this node is the leader, and the namespace limit. It returns the context
+// the call runs with.
+func (c *rpcCall) check(ctx context.Context, req interface{}) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	first := func(key string) string {
+		if values := md.Get(key); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+
+	namespace := first("bouncer-namespace")
+	if strings.Contains(namespace, namespaceSeparator) {
+		return ctx, ErrInvalidName
+	}
+	ctx = context.WithValue(ctx, namespaceKey{}, namespace)
+
+	c.names = slices.Clone(rpcNames(req))
+	if len(c.names) == 0 {
+		return ctx, ErrInvalidName
+	}
+
+	var entry *TokenEntry
+	if tokens := currentFileConfig().Tokens; len(tokens) > 0 {
+		if entry = authenticate(tokens, first("authorization"), rpcCert(ctx)); entry == nil {
+			return ctx, ErrUnauthorized
+		}
+		c.client = entry.Name
+	}
+
+	// followers don't forward calls like they do HTTP requests, clients
+	// must call the leader
+	if cluster != nil && !cluster.IsLeader() {
+		if _, ok := cluster.Leader(); !ok {
+			return ctx, ErrNoLeader
+		}
+		return ctx, ErrNotLeader
+	}
+
+	for i, name := range c.names {
+		var err error
+		if c.names[i], err = checkCall(entry, namespace, c.typeName, c.call, name); err != nil {
+			return ctx, err
+		}
+	}
+
+	return ctx, nil
+}
+
+// done logs a call and returns its error as a gRPC status.
+func (c *rpcCall) done(err error, wait time.Duration) error {
+	st := http.StatusOK
+	if err != nil {
+		st = errorStatus(err)
+	}
+	logCall(c.client, st, c.typeName, c.call, strings.Join(c.names, ","), wait, nil).Send()
+
+	if err == nil {
+		return nil
+	}
+	return status.Error(rpcCodes[st], err.Error())
+}
+
+func unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	c := newRPCCall(ctx, info.FullMethod)
+	ctx, err := c.check(ctx, req)
+	if err != nil {
+		return nil, c.done(err, 0)
+	}
+
+	start := time.Now()
+	mark := replicationMark()
+	rep, err := handler(ctx, req)
+	if err == nil {
+		err = replicationDone(mark)
+	}
+	return rep, c.done(err, time.Since(start))
+}
+
+// checkedStream checks the request of a server-streaming call as the
+// handler receives it.
+type checkedStream struct {
+	grpc.ServerStream
+	c   *rpcCall
+	ctx context.Context
+}
+
+func (s *checkedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *checkedStream) RecvMsg(m interface{}) (err error) {
+	if err = s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	s.ctx, err = s.c.check(s.ctx, m)
+	return err
+}
+
+func streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	c := newRPCCall(ss.Context(), info.FullMethod)
+	start := time.Now()
+	err := handler(srv, &checkedStream{ServerStream: ss, c: c, ctx: ss.Context()})
+	return c.done(err, time.Since(start))
+}
+
+// streamSender serializes the messages sent on a stream by many watches.
+func streamSender[T any](stream grpc.ServerStreamingServer[T]) func(*T) error {
+	mu := &sync.Mutex{}
+	return func(m *T) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if err := stream.Send(m); err != nil {
+			return ErrCanceled
+		}
+		return nil
+	}
+}
+
+type semaphoreService struct {
+	bouncerpb.UnimplementedSemaphoreServer
+}
+
+func (semaphoreService) Acquire(ctx context.Context, req *bouncerpb.SemaphoreAcquireRequest) (*bouncerpb.KeyReply, error) {
+	semaphore, err := getSemaphore(rpcName(ctx, req.Name), req.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	key, token, err := semaphore.Acquire(ctx, rpcDuration(req.Maxwait, -1), rpcDuration(req.Expires, time.Minute), "")
+	if err != nil {
+		return nil, err
+	}
+	return &bouncerpb.KeyReply{Key: key, FencingToken: token}, nil
+}
+
+func (semaphoreService) Release(ctx context.Context, req *bouncerpb.KeyRequest) (*bouncerpb.Empty, error) {
+	semaphore, err := findSemaphore(rpcName(ctx, req.Name))
+	if err == nil {
+		err = semaphore.Release(req.Key)
+	}
+	return &bouncerpb.Empty{}, err
+}
+
+func (semaphoreService) Renew(ctx context.Context, req *bouncerpb.RenewRequest) (*bouncerpb.Empty, error) {
+	semaphore, err := findSemaphore(rpcName(ctx, req.Name))
+	if err == nil {
+		err = semaphore.Renew(req.Key, rpcDuration(req.Expires, time.Minute))
+	}
+	return &bouncerpb.Empty{}, err
+}
+
+type rwlockService struct {
+	bouncerpb.UnimplementedRWLockServer
+}
+
+func (rwlockService) acquire(ctx context.Context, req *bouncerpb.LockRequest, write bool) (*bouncerpb.KeyReply, error) {
+	rwlock, err := getRWLock(rpcName(ctx, req.Name))
+	if err != nil {
+		return nil, err
+	}
+
+	key, token, err := rwlock.Acquire(ctx, write, rpcDuration(req.Maxwait, -1), rpcDuration(req.Expires, time.Minute), "")
+	if err != nil {
+		return nil, err
+	}
+	return &bouncerpb.KeyReply{Key: key, FencingToken: token}, nil
+}
+
+func (s rwlockService) RLock(ctx context.Context, req *bouncerpb.LockRequest) (*bouncerpb.KeyReply, error) {
+	return s.acquire(ctx, req, false)
+}
+
+func (s rwlockService) Lock(ctx context.Context, req *bouncerpb.LockRequest) (*bouncerpb.KeyReply, error) {
+	return s.acquire(ctx, req, true)
+}
+
+func (rwlockService) Unlock(ctx context.Context, req *bouncerpb.KeyRequest) (*bouncerpb.Empty, error) {
+	rwlock, err := findRWLock(rpcName(ctx, req.Name))
+	if err == nil {
+		err = rwlock.Release(req.Key)
+	}
+	return &bouncerpb.Empty{}, err
+}
+
+type tokenBucketService struct {
+	bouncerpb.UnimplementedTokenBucketServer
+}
+
+func (tokenBucketService) Acquire(ctx context.Context, req *bouncerpb.TokenBucketAcquireRequest) (*bouncerpb.Empty, error) {
+	arrival := time.Now()
+	if req.Interval < 0 {
+		return nil, ErrInvalidInterval
+	}
+
+	bucket, err := getTokenBucket(rpcName(ctx, req.Name), req.Size, time.Duration(req.Interval)*time.Millisecond)
+	if err == nil {
+		count := uint64(1)
+		if req.Count != nil {
+			count = *req.Count
+		}
+		err = bucket.Acquire(ctx, count, rpcDuration(req.Maxwait, -1), arrival)
+	}
+	return &bouncerpb.Empty{}, err
+}
+
+type rateLimitService struct {
+	bouncerpb.UnimplementedRateLimitServer
+}
+
+func (rateLimitService) Acquire(ctx context.Context, req *bouncerpb.RateLimitAcquireRequest) (*bouncerpb.Empty, error) {
+	arrival := time.Now()
+	if req.Per < 0 {
+		return nil, ErrInvalidRate
+	}
+
+	limiter, err := getRateLimiter(rpcName(ctx, req.Name), RateLimiterConfig{
+		Algorithm: req.Algo,
+		Rate:      req.Rate,
+		Per:       req.Per,
+		Burst:     req.Burst,
+	})
+	if err == nil {
+		err = limiter.Acquire(ctx, rpcDuration(req.Maxwait, -1), arrival)
+	}
+	return &bouncerpb.Empty{}, err
+}
+
+type eventService struct {
+	bouncerpb.UnimplementedEventServer
+}
+
+func (eventService) Wait(ctx context.Context, req *bouncerpb.WaitRequest) (*bouncerpb.EventMessage, error) {
+	event, err := getEvent(rpcName(ctx, req.Name))
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := event.Wait(ctx, rpcDuration(req.Maxwait, -1))
+	if err != nil {
+		return nil, err
+	}
+	return &bouncerpb.EventMessage{Name: req.Name, Message: message}, nil
+}
+
+func (eventService) Send(ctx context.Context, req *bouncerpb.SendRequest) (*bouncerpb.Empty, error) {
+	event, err := getEvent(rpcName(ctx, req.Name))
+	if err == nil {
+		err = event.Send(req.Message)
+	}
+	return &bouncerpb.Empty{}, err
+}
+
+func (eventService) Watch(req *bouncerpb.WatchRequest, stream grpc.ServerStreamingServer[bouncerpb.EventMessage]) error {
+	ctx := stream.Context()
+
+	// get them all first, so a missing one fails before anything is sent
+	events := make([]*Event, len(req.Names))
+	for i, name := range req.Names {
+		var err error
+		if events[i], err = getEvent(rpcName(ctx, name)); err != nil {
+			return err
+		}
+	}
+
+	send := streamSender(stream)
+	return watchEach(ctx, len(events), func(ctx context.Context, i int) error {
+		message, err := events[i].Wait(ctx, -1)
+		if err != nil {
+			return err
+		}
+		return send(&bouncerpb.EventMessage{Name: req.Names[i], Message: message})
+	})
+}
+
+type watchdogService struct {
+	bouncerpb.UnimplementedWatchdogServer
+}
+
+func (watchdogService) Kick(ctx context.Context, req *bouncerpb.KickRequest) (*bouncerpb.Empty, error) {
+	name := rpcName(ctx, req.Name)
+	expires := kickExpiry(name, rpcDuration(req.Expires, time.Minute), req.Expires != nil)
+
+	watchdog, err := getWatchdog(name, expires)
+	if err == nil {
+		watchdog.Kick(expires)
+	}
+	return &bouncerpb.Empty{}, err
+}
+
+func (watchdogService) Wait(ctx context.Context, req *bouncerpb.WaitRequest) (*bouncerpb.Empty, error) {
+	watchdog, err := getWatchdog(rpcName(ctx, req.Name), 0)
+	if err == nil {
+		err = watchdog.Wait(ctx, rpcDuration(req.Maxwait, -1))
+	}
+	return &bouncerpb.Empty{}, err
+}
+
+func (watchdogService) Watch(req *bouncerpb.WatchRequest, stream grpc.ServerStreamingServer[bouncerpb.WatchdogExpiration]) error {
+	ctx := stream.Context()
+
+	watchdogs := make([]*Watchdog, len(req.Names))
+	for i, name := range req.Names {
+		var err error
+		if watchdogs[i], err = getWatchdog(rpcName(ctx, name), 0); err != nil {
+			return err
+		}
+	}
+
+	send := streamSender(stream)
+	return watchEach(ctx, len(watchdogs), func(ctx context.Context, i int) error {
+		var last chan struct{}
+		for {
+			var expiredAt time.Time
+			var err error
+			if last, expiredAt, err = watchdogs[i].waitExpiry(ctx, last); err != nil {
+				return err
+			}
+			if err = send(&bouncerpb.WatchdogExpiration{Name: req.Names[i], ExpiredAt: expiredAt.UnixMilli()}); err != nil {
+				return err
+			}
+		}
+	})
+}
+
+type counterService struct {
+	bouncerpb.UnimplementedCounterServer
+}
+
+func (counterService) Count(ctx context.Context, req *bouncerpb.CountRequest) (*bouncerpb.CounterValue, error) {
+	counter, err := getCounter(rpcName(ctx, req.Name))
+	if err != nil {
+		return nil, err
+	}
+
+	amount := int64(1)
+	if req.Amount != nil {
+		amount = *req.Amount
+	}
+	return &bouncerpb.CounterValue{Value: counter.Count(amount)}, nil
+}
+
+func (counterService) Reset(ctx context.Context, req *bouncerpb.ResetRequest) (*bouncerpb.Empty, error) {
+	counter, err := getCounter(rpcName(ctx, req.Name))
+	if err == nil {
+		counter.Reset(req.Value)
+	}
+	return &bouncerpb.Empty{}, err
+}
+
+func (counterService) Value(ctx context.Context, req *bouncerpb.NameRequest) (*bouncerpb.CounterValue, error) {
+	counter, err := getCounter(rpcName(ctx, req.Name))
+	if err != nil {
+		return nil, err
+	}
+	return &bouncerpb.CounterValue{Value: counter.Value()}, nil
+}
+
+type barrierService struct {
+	bouncerpb.UnimplementedBarrierServer
+}
+
+func (barrierService) Wait(ctx context.Context, req *bouncerpb.BarrierWaitRequest) (*bouncerpb.Empty, error) {
+	barrier, err := getBarrier(rpcName(ctx, req.Name), req.Size)
+	if err == nil {
+		err = barrier.Wait(ctx, rpcDuration(req.Maxwait, -1))
+	}
+	return &bouncerpb.Empty{}, err
+}
+
+// newGRPCServer returns the gRPC server with every service registered,
+// serving TLS with the given config, if any.
+func newGRPCServer(tlsConfig *tls.Config) *grpc.Server {
+	opts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(unaryInterceptor),
+		grpc.StreamInterceptor(streamInterceptor),
+	}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	server := grpc.NewServer(opts...)
+	bouncerpb.RegisterSemaphoreServer(server, semaphoreService{})
+	bouncerpb.RegisterRWLockServer(server, rwlockService{})
+	bouncerpb.RegisterTokenBucketServer(server, tokenBucketService{})
+	bouncerpb.RegisterRateLimitServer(server, rateLimitService{})
+	bouncerpb.RegisterEventServer(server, eventService{})
+	bouncerpb.RegisterWatchdogServer(server, watchdogService{})
+	bouncerpb.RegisterCounterServer(server, counterService{})
+	bouncerpb.RegisterBarrierServer(server, barrierService{})
+	return server
+}
+
+// setupGRPC starts the gRPC server on BOUNCER_GRPC_PORT, if set, sending
+// its error to errC if it stops. It returns nil if disabled.
+func setupGRPC(tlsConfig *tls.Config, errC chan<- error) (*grpc.Server, error) {
+	port := viper.GetInt("grpcPort")
+	if port == 0 {
+		return nil, nil
+	}
+
+	addr := fmt.Sprintf("%v:%v", viper.GetString("myHost"), port)
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	server := newGRPCServer(tlsConfig)
+	log.Info().Msgf("Serving gRPC on %v", addr)
+	go func() {
+		if err := server.Serve(ln); err != nil {
+			errC <- err
+		}
+	}()
+	return server, nil
+}
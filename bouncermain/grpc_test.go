@@ -0,0 +1,242 @@
+package bouncermain
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pjwerneck/bouncer/bouncerpb"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// serveGRPC serves the gRPC API, with TLS if config isn't nil, and returns
+// a client connection to it.
+func serveGRPC(t *testing.T, config *tls.Config, creds credentials.TransportCredentials) *grpc.ClientConn {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	server := newGRPCServer(config)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	if creds == nil {
+		creds = insecure.NewCredentials()
+	}
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(creds))
+	require.Nil(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func ms(v int64) *int64 {
+	return &v
+}
+
+func requireCode(t *testing.T, code codes.Code, err error) {
+	t.Helper()
+	require.NotNil(t, err)
+	require.Equal(t, code, status.Code(err), err.Error())
+}
+
+func TestGRPCSemaphore(t *testing.T) {
+	defer deleteSemaphore("grpc-db")
+	client := bouncerpb.NewSemaphoreClient(serveGRPC(t, nil, nil))
+	ctx := context.Background()
+
+	rep, err := client.Acquire(ctx, &bouncerpb.SemaphoreAcquireRequest{Name: "grpc-db", Size: 1})
+	require.Nil(t, err)
+	require.NotEmpty(t, rep.Key)
+	require.NotZero(t, rep.FencingToken)
+
+	_, err = client.Acquire(ctx, &bouncerpb.SemaphoreAcquireRequest{Name: "grpc-db", Maxwait: ms(0)})
+	requireCode(t, codes.DeadlineExceeded, err)
+
+	_, err = client.Renew(ctx, &bouncerpb.RenewRequest{Name: "grpc-db", Key: rep.Key, Expires: ms(1000)})
+	require.Nil(t, err)
+
+	_, err = client.Release(ctx, &bouncerpb.KeyRequest{Name: "grpc-db", Key: rep.Key})
+	require.Nil(t, err)
+
+	_, err = client.Release(ctx, &bouncerpb.KeyRequest{Name: "grpc-db", Key: rep.Key})
+	requireCode(t, codes.FailedPrecondition, err)
+
+	_, err = client.Release(ctx, &bouncerpb.KeyRequest{Name: "grpc-none", Key: rep.Key})
+	requireCode(t, codes.NotFound, err)
+
+	_, err = client.Acquire(ctx, &bouncerpb.SemaphoreAcquireRequest{Name: "grpc/db"})
+	requireCode(t, codes.InvalidArgument, err)
+}
+
+func TestGRPCRWLock(t *testing.T) {
+	defer deleteRWLock("grpc-table")
+	client := bouncerpb.NewRWLockClient(serveGRPC(t, nil, nil))
+	ctx := context.Background()
+
+	rep, err := client.Lock(ctx, &bouncerpb.LockRequest{Name: "grpc-table"})
+	require.Nil(t, err)
+	require.NotZero(t, rep.FencingToken)
+
+	_, err = client.RLock(ctx, &bouncerpb.LockRequest{Name: "grpc-table", Maxwait: ms(0)})
+	requireCode(t, codes.DeadlineExceeded, err)
+
+	_, err = client.Unlock(ctx, &bouncerpb.KeyRequest{Name: "grpc-table", Key: rep.Key})
+	require.Nil(t, err)
+
+	// unlocking doesn't create a lock
+	_, err = client.Unlock(ctx, &bouncerpb.KeyRequest{Name: "grpc-no-table", Key: rep.Key})
+	requireCode(t, codes.NotFound, err)
+	_, err = findRWLock("grpc-no-table")
+	require.Equal(t, ErrNotFound, err)
+}
+
+func TestGRPCCanceled(t *testing.T) {
+	defer deleteEvent("grpc-canceled")
+	client := bouncerpb.NewEventClient(serveGRPC(t, nil, nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := client.Wait(ctx, &bouncerpb.WaitRequest{Name: "grpc-canceled"})
+	requireCode(t, codes.DeadlineExceeded, err)
+
+	event, err := getEvent("grpc-canceled")
+	require.Nil(t, err)
+	require.Eventually(t, func() bool { return atomic.LoadUint64(&event.Stats.Canceled) == 1 }, time.Second, 10*time.Millisecond)
+}
+
+func TestGRPCEventWatch(t *testing.T) {
+	defer deleteEvent("grpc-a")
+	defer deleteEvent("grpc-b")
+	client := bouncerpb.NewEventClient(serveGRPC(t, nil, nil))
+	ctx := context.Background()
+
+	stream, err := client.Watch(ctx, &bouncerpb.WatchRequest{Names: []string{"grpc-a", "grpc-b"}})
+	require.Nil(t, err)
+
+	// the watch has started once both events exist
+	require.Eventually(t, func() bool {
+		_, errA := getEventStats("grpc-a")
+		_, errB := getEventStats("grpc-b")
+		return errA == nil && errB == nil
+	}, time.Second, 10*time.Millisecond)
+
+	_, err = client.Send(ctx, &bouncerpb.SendRequest{Name: "grpc-b", Message: "second"})
+	require.Nil(t, err)
+	msg, err := stream.Recv()
+	require.Nil(t, err)
+	require.Equal(t, "grpc-b", msg.Name)
+	require.Equal(t, "second", msg.Message)
+
+	_, err = client.Send(ctx, &bouncerpb.SendRequest{Name: "grpc-a", Message: "first"})
+	require.Nil(t, err)
+	msg, err = stream.Recv()
+	require.Nil(t, err)
+	require.Equal(t, "grpc-a", msg.Name)
+
+	_, err = stream.Recv()
+	require.Equal(t, io.EOF, err)
+
+	_, err = client.Send(ctx, &bouncerpb.SendRequest{Name: "grpc-a"})
+	requireCode(t, codes.FailedPrecondition, err)
+}
+
+func TestGRPCWatchdogWatch(t *testing.T) {
+	defer deleteWatchdog("grpc-dog")
+	client := bouncerpb.NewWatchdogClient(serveGRPC(t, nil, nil))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := client.Kick(ctx, &bouncerpb.KickRequest{Name: "grpc-dog", Expires: ms(50)})
+	require.Nil(t, err)
+
+	stream, err := client.Watch(ctx, &bouncerpb.WatchRequest{Names: []string{"grpc-dog"}})
+	require.Nil(t, err)
+
+	exp, err := stream.Recv()
+	require.Nil(t, err)
+	require.Equal(t, "grpc-dog", exp.Name)
+	first := exp.ExpiredAt
+
+	// each expiration is sent once, the next one after a kick
+	_, err = client.Kick(ctx, &bouncerpb.KickRequest{Name: "grpc-dog", Expires: ms(50)})
+	require.Nil(t, err)
+	exp, err = stream.Recv()
+	require.Nil(t, err)
+	require.Greater(t, exp.ExpiredAt, first)
+
+	_, err = client.Wait(ctx, &bouncerpb.WaitRequest{Name: "grpc-dog", Maxwait: ms(0)})
+	require.Nil(t, err)
+}
+
+func TestGRPCNamespaceAndAuth(t *testing.T) {
+	useConfigFile(t, writeConfigFile(t, "bouncer.yaml", `
+tokens:
+  - name: team-a
+    token: token-a
+    grants:
+      - access: acquire
+        prefix: team-a/
+  - name: monitor
+    token: token-monitor
+    grants:
+      - access: read
+`))
+	defer deleteCounter("team-a/grpc-hits")
+	client := bouncerpb.NewCounterClient(serveGRPC(t, nil, nil))
+
+	call := func(token string, namespace string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(),
+			"authorization", "Bearer "+token, "bouncer-namespace", namespace)
+	}
+
+	_, err := client.Count(context.Background(), &bouncerpb.CountRequest{Name: "grpc-hits"})
+	requireCode(t, codes.Unauthenticated, err)
+
+	_, err = client.Count(call("token-a", ""), &bouncerpb.CountRequest{Name: "grpc-hits"})
+	requireCode(t, codes.PermissionDenied, err)
+
+	rep, err := client.Count(call("token-a", "team-a"), &bouncerpb.CountRequest{Name: "grpc-hits", Amount: ms(5)})
+	require.Nil(t, err)
+	require.Equal(t, int64(5), rep.Value)
+
+	counter, err := getCounter("team-a/grpc-hits")
+	require.Nil(t, err)
+	require.Equal(t, int64(5), counter.Value())
+
+	rep, err = client.Value(call("token-monitor", "team-a"), &bouncerpb.NameRequest{Name: "grpc-hits"})
+	require.Nil(t, err)
+	require.Equal(t, int64(5), rep.Value)
+
+	_, err = client.Reset(call("token-a", "team-a"), &bouncerpb.ResetRequest{Name: "grpc-hits"})
+	requireCode(t, codes.PermissionDenied, err)
+}
+
+func TestGRPCTLSClientCert(t *testing.T) {
+	useConfigFile(t, writeConfigFile(t, "bouncer.yaml", `
+tokens:
+  - name: workers
+    subject: worker-1
+    grants:
+      - access: acquire
+`))
+	defer deleteCounter("grpc-tls")
+
+	tt, config := setupTestTLS(t, "optional")
+	roots := x509.NewCertPool()
+	roots.AddCert(tt.ca.cert)
+	creds := credentials.NewTLS(&tls.Config{RootCAs: roots, Certificates: []tls.Certificate{tt.client.pair}})
+	client := bouncerpb.NewCounterClient(serveGRPC(t, config, creds))
+
+	rep, err := client.Count(context.Background(), &bouncerpb.CountRequest{Name: "grpc-tls"})
+	require.Nil(t, err)
+	require.Equal(t, int64(1), rep.Value)
+}
@@ -8,8 +8,12 @@
 
 // @tag.name TokenBucket
 // @tag.description Rate limiting and traffic shaping
+// @tag.name RateLimit
+// @tag.description GCRA and sliding-window rate limiting
 // @tag.name Semaphore
 // @tag.description Resource access control and concurrency limits
+// @tag.name RWLock
+// @tag.description Shared and exclusive access to resources
 // @tag.name Event
 // @tag.description One-time broadcast notifications
 // @tag.name Watchdog
@@ -18,6 +22,12 @@
 // @tag.description Distributed atomic counters
 // @tag.name Barrier
 // @tag.description Multi-client synchronization points
+// @tag.name Stream
+// @tag.description Subscriptions over WebSocket and Server-Sent Events
+// @tag.name Resources
+// @tag.description Discovery of existing objects
+// @tag.name Namespace
+// @tag.description Isolated sets of objects, under /ns/{namespace}
 // @tag.name Health
 // @tag.description Service health checks
 
@@ -26,6 +36,7 @@ package bouncermain
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"reflect"
 	"time"
@@ -55,6 +66,29 @@ func DeleteHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params,
 	return rep.Status
 }
 
+// Common fencing token validation function type
+type validateFunc func(name string, key string, token uint64) error
+
+// ValidateHandler checks a fencing token against the current holders of a
+// semaphore or lock
+func ValidateHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params, vf validateFunc) (status int, req *ValidateRequest) {
+	req = newValidateRequest()
+	rep := newReply()
+
+	err := req.Decode(r.URL.Query())
+	if err == nil {
+		err = vf(ps[0].Value, req.Key, req.Token)
+	}
+
+	if err == nil {
+		rep.Status = http.StatusNoContent
+	}
+
+	rep.WriteResponse(w, r, err)
+
+	return rep.Status, req
+}
+
 // StatsGetter is a function type for getting stats of any synchronization primitive
 type StatsGetter = func(name string) (interface{}, error)
 
@@ -78,13 +112,88 @@ func StatsHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params,
 	return rep.Status
 }
 
+// ConfigHandler gets the configuration of a synchronization primitive
+func ConfigHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params, getter ConfigGetter) (status int) {
+	return StatsHandler(w, r, ps, getter)
+}
+
+// ConfigureHandler creates or reconfigures a synchronization primitive from
+// the JSON config in the request body, and replies with the resulting
+// configuration
+func ConfigureHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params, configure configureFunc, getter ConfigGetter) (status int) {
+	rep := newReply()
+
+	var created bool
+	var config interface{}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxConfigSize))
+	if err == nil {
+		created, err = configure(ps[0].Value, body)
+	}
+
+	if err == nil {
+		config, err = getter(ps[0].Value)
+	}
+
+	if err == nil {
+		buf, _ := json.Marshal(config)
+		rep.Body = string(buf)
+		rep.Status = http.StatusOK
+		if created {
+			rep.Status = http.StatusCreated
+		}
+	}
+
+	rep.WriteResponse(w, r, err)
+
+	return rep.Status
+}
+
+// ListHandler lists objects of the given types
+func ListHandler(w http.ResponseWriter, r *http.Request, typeNames ...string) (status int) {
+	req := newListRequest()
+	rep := newReply()
+
+	err := req.Decode(r.URL.Query())
+	if err == nil {
+		buf, _ := json.Marshal(listResources(typeNames, requestNamespace(r), req))
+		rep.Body = string(buf)
+		rep.Status = http.StatusOK
+	}
+
+	rep.WriteResponse(w, r, err)
+
+	return rep.Status
+}
+
+// ResourcesHandler godoc
+// @Summary List all objects
+// @Description List objects of every type with their configuration and stats, ordered by type and name
+// @Tags Resources
+// @Produce json
+// @Param prefix query string false "Only list names starting with prefix"
+// @Param limit query int false "Maximum number of items" default(100)
+// @Param after query string false "Only list items after this type/name, as returned in 'next'"
+// @Success 200 {object} ResourceList "Objects"
+// @Failure 400 {string} Reply "Bad Request - invalid parameters"
+// @Router /resources [get]
+func ResourcesHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	status := ListHandler(w, r, allResourceTypes()...)
+	logRequest(r, status, "resources", "list", "", 0, nil).Send()
+}
+
 // WellKnownReady godoc
 // @Summary Readiness check
 // @Description Check if the service is ready
 // @Tags Health
 // @Success 200 {string} string "Service is ready"
+// @Failure 503 {string} string "Service is shutting down"
 // @Router /.well-known/ready [get]
 func WellKnownReady(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if isDraining() {
+		http.Error(w, ErrShuttingDown.Error(), http.StatusServiceUnavailable)
+		return
+	}
 	fmt.Fprint(w, "I'm ready!\n")
 }
 
@@ -113,15 +222,27 @@ func _addStructFieldsToLog(evt *zerolog.Event, v interface{}) *zerolog.Event {
 }
 
 var statusDescriptions = map[int]string{
-	http.StatusOK:             "ok",
-	http.StatusNoContent:      "ok",
-	http.StatusNotFound:       "not found",
-	http.StatusConflict:       "conflict",
-	http.StatusRequestTimeout: "timeout",
-	http.StatusBadRequest:     "bad request",
+	http.StatusOK:                 "ok",
+	http.StatusCreated:            "ok",
+	http.StatusNoContent:          "ok",
+	http.StatusNotFound:           "not found",
+	http.StatusUnauthorized:       "unauthorized",
+	http.StatusForbidden:          "forbidden",
+	http.StatusConflict:           "conflict",
+	http.StatusRequestTimeout:     "timeout",
+	http.StatusBadRequest:         "bad request",
+	http.StatusServiceUnavailable: "unavailable",
+	http.StatusTooManyRequests:    "limited",
+	StatusClientClosedRequest:     "canceled",
+}
+
+func logRequest(r *http.Request, status int, resourceType string, call string, name string, wait time.Duration, req interface{}) *zerolog.Event {
+	return logCall(clientName(r), status, resourceType, call, name, wait, req)
 }
 
-func logRequest(status int, resourceType string, call string, name string, wait time.Duration, req interface{}) *zerolog.Event {
+// logCall starts the log event of a call, by whoever made it, with the
+// HTTP status it got.
+func logCall(client string, status int, resourceType string, call string, name string, wait time.Duration, req interface{}) *zerolog.Event {
 	st := statusDescriptions[status]
 	if st == "" {
 		st = "unknown"
@@ -135,6 +256,10 @@ func logRequest(status int, resourceType string, call string, name string, wait
 		Str("name", name).
 		Int64("wait", wait.Milliseconds())
 
+	if client != "" {
+		evt.Str("client", client)
+	}
+
 	if req != nil {
 		_addStructFieldsToLog(evt, req)
 	}
@@ -0,0 +1,208 @@
+package bouncermain
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+// idleTracker records when an object was last used. It's embedded by every
+// primitive and touched whenever the object is looked up.
+type idleTracker struct {
+	lastUsed int64 // atomic unix nano
+}
+
+func (t *idleTracker) touch() {
+	atomic.StoreInt64(&t.lastUsed, time.Now().UnixNano())
+}
+
+func (t *idleTracker) idleFor() time.Duration {
+	return time.Since(time.Unix(0, atomic.LoadInt64(&t.lastUsed)))
+}
+
+type evictable interface {
+	idleFor() time.Duration
+	busy() bool // has holders or waiters
+}
+
+type JanitorStats struct {
+	Runs    uint64            `json:"runs"`
+	Evicted map[string]uint64 `json:"evicted"`
+	LastRun string            `json:"last_run"`
+}
+
+var janitorStats = &JanitorStats{Evicted: map[string]uint64{}}
+var janitorStatsMutex = &sync.Mutex{}
+
+var evictionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "bouncer_evictions_total",
+	Help: "Idle objects evicted by the janitor.",
+}, []string{"type"})
+
+func init() {
+	metricsRegistry.MustRegister(evictionsTotal)
+}
+
+// evictIdle removes objects idle for at least ttl. Lookups touch objects
+// while holding the map lock, so an object can't be handed out and evicted
+// at the same time.
+func evictIdle[T evictable](m map[string]T, mu *sync.RWMutex, ttl time.Duration, remove func(string) error) (evicted []string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for name, obj := range m {
+		if obj.idleFor() >= ttl && !obj.busy() {
+			if remove(name) == nil {
+				evicted = append(evicted, name)
+			}
+		}
+	}
+	return evicted
+}
+
+var evictors = map[string]func(ttl time.Duration) []string{
+	"barrier": func(ttl time.Duration) []string {
+		return evictIdle(barriers, barriersMutex, ttl, removeBarrier)
+	},
+	"counter": func(ttl time.Duration) []string {
+		return evictIdle(counters, countersMutex, ttl, removeCounter)
+	},
+	"event": func(ttl time.Duration) []string {
+		return evictIdle(events, eventsMutex, ttl, removeEvent)
+	},
+	"ratelimit": func(ttl time.Duration) []string {
+		return evictIdle(rateLimiters, rateLimitersMutex, ttl, removeRateLimiter)
+	},
+	"rwlock": func(ttl time.Duration) []string {
+		return evictIdle(rwlocks, rwlocksMutex, ttl, removeRWLock)
+	},
+	"semaphore": func(ttl time.Duration) []string {
+		return evictIdle(semaphores, semaphoresMutex, ttl, removeSemaphore)
+	},
+	"tokenbucket": func(ttl time.Duration) []string {
+		return evictIdle(buckets, bucketsMutex, ttl, removeTokenBucket)
+	},
+	"watchdog": func(ttl time.Duration) []string {
+		return evictIdle(watchdogs, watchdogsMutex, ttl, removeWatchdog)
+	},
+}
+
+// collectIdle evicts idle objects of every type with a positive ttl.
+func collectIdle(ttls map[string]time.Duration) map[string]int {
+	counts := map[string]int{}
+
+	for typeName, ttl := range ttls {
+		if ttl <= 0 {
+			continue
+		}
+
+		evicted := evictors[typeName](ttl)
+		for _, name := range evicted {
+			log.Info().Str("type", typeName).Str("name", name).Dur("ttl", ttl).Msg("evicted idle object")
+		}
+		counts[typeName] = len(evicted)
+		evictionsTotal.WithLabelValues(typeName).Add(float64(len(evicted)))
+	}
+
+	janitorStatsMutex.Lock()
+	janitorStats.Runs++
+	janitorStats.LastRun = time.Now().Format(time.RFC3339)
+	for typeName, n := range counts {
+		janitorStats.Evicted[typeName] += uint64(n)
+	}
+	janitorStatsMutex.Unlock()
+
+	return counts
+}
+
+// idleTTLs reads the global idle ttl and the per type overrides, in seconds.
+func idleTTLs() map[string]time.Duration {
+	ttls := map[string]time.Duration{}
+	global := viper.GetInt("idleTTL")
+
+	for typeName := range evictors {
+		ttl := global
+		if key := typeName + "IdleTTL"; viper.IsSet(key) {
+			ttl = viper.GetInt(key)
+		}
+		ttls[typeName] = time.Duration(ttl) * time.Second
+	}
+	return ttls
+}
+
+// setupJanitor starts evicting idle objects if any idle ttl is set. It fails
+// if the interval between sweeps isn't positive.
+func setupJanitor() error {
+	ttls := idleTTLs()
+
+	enabled := false
+	for _, ttl := range ttls {
+		enabled = enabled || ttl > 0
+	}
+	if !enabled {
+		return nil
+	}
+
+	// evicted objects couldn't be created again on use
+	if strictMode {
+		log.Warn().Msg("janitor: idle objects aren't evicted in strict mode")
+		return nil
+	}
+
+	seconds := viper.GetInt("janitorInterval")
+	if seconds <= 0 {
+		return fmt.Errorf("janitor: BOUNCER_JANITOR_INTERVAL must be positive, got %d", seconds)
+	}
+
+	interval := time.Duration(seconds) * time.Second
+	log.Info().Dur("interval", interval).Msg("janitor: evicting idle objects")
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			// followers get deletes from the leader
+			if cluster != nil && !cluster.IsLeader() {
+				continue
+			}
+			collectIdle(ttls)
+		}
+	}()
+	return nil
+}
+
+func getJanitorStats() *JanitorStats {
+	janitorStatsMutex.Lock()
+	defer janitorStatsMutex.Unlock()
+
+	stats := &JanitorStats{Runs: janitorStats.Runs, LastRun: janitorStats.LastRun, Evicted: map[string]uint64{}}
+	for typeName, n := range janitorStats.Evicted {
+		stats.Evicted[typeName] = n
+	}
+	return stats
+}
+
+// JanitorStatsHandler godoc
+// @Summary Get janitor statistics
+// @Description Get the number of idle objects evicted, by type
+// @Tags Health
+// @Produce json
+// @Success 200 {object} JanitorStats "Janitor statistics"
+// @Router /janitor/stats [get]
+func JanitorStatsHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	rep := newReply()
+
+	buf, _ := json.Marshal(getJanitorStats())
+	rep.Body = string(buf)
+
+	rep.WriteResponse(w, r, nil)
+}
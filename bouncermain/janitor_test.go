@@ -0,0 +1,120 @@
+package bouncermain
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func backdate(t *idleTracker, d time.Duration) {
+	atomic.StoreInt64(&t.lastUsed, time.Now().Add(-d).UnixNano())
+}
+
+func TestJanitorEvictsIdleObjects(t *testing.T) {
+	counter, _ := getCounter("janitor-idle")
+	counter.Count(1)
+	backdate(&counter.idleTracker, 2*time.Hour)
+
+	held, _ := getSemaphore("janitor-held", 1)
+	_, _, err := held.Acquire(context.Background(), 0, 0, "holder")
+	require.Nil(t, err)
+	backdate(&held.idleTracker, 2*time.Hour)
+
+	recent, _ := getSemaphore("janitor-recent", 1)
+	backdate(&recent.idleTracker, 2*time.Hour)
+	getSemaphore("janitor-recent", 1)
+
+	defer func() {
+		deleteSemaphore("janitor-held")
+		deleteSemaphore("janitor-recent")
+	}()
+
+	before := getJanitorStats()
+	counts := collectIdle(map[string]time.Duration{"counter": time.Hour, "semaphore": time.Hour})
+	require.Equal(t, 1, counts["counter"])
+	require.Equal(t, 0, counts["semaphore"])
+
+	_, err = getCounterStats("janitor-idle")
+	require.Equal(t, ErrNotFound, err)
+	_, err = getSemaphoreStats("janitor-held")
+	require.Nil(t, err)
+	_, err = getSemaphoreStats("janitor-recent")
+	require.Nil(t, err)
+
+	after := getJanitorStats()
+	require.Equal(t, before.Runs+1, after.Runs)
+	require.Equal(t, before.Evicted["counter"]+1, after.Evicted["counter"])
+}
+
+func TestJanitorEvictionKeepsFencingStateBounded(t *testing.T) {
+	acquire := func(name string) uint64 {
+		semaphore, _ := getSemaphore(name, 1)
+		key, token, err := semaphore.Acquire(context.Background(), 0, time.Minute, "")
+		require.Nil(t, err)
+		require.Nil(t, semaphore.Release(key))
+		backdate(&semaphore.idleTracker, 2*time.Hour)
+		return token
+	}
+
+	last := acquire("janitor-fenced-0")
+	collectIdle(map[string]time.Duration{"semaphore": time.Hour})
+	before := len(captureState())
+
+	for i := 1; i <= 100; i++ {
+		acquire(fmt.Sprintf("janitor-fenced-%d", i))
+	}
+	require.Equal(t, 100, collectIdle(map[string]time.Duration{"semaphore": time.Hour})["semaphore"])
+	require.Equal(t, before, len(captureState()))
+
+	// an evicted name still gets higher tokens
+	require.Greater(t, acquire("janitor-fenced-0"), last)
+	deleteSemaphore("janitor-fenced-0")
+}
+
+func TestJanitorKeepsObjectsWithWaiters(t *testing.T) {
+	event, _ := getEvent("janitor-waited")
+	defer deleteEvent("janitor-waited")
+
+	done := make(chan error)
+	go func() {
+		_, err := event.Wait(context.Background(), time.Second)
+		done <- err
+	}()
+
+	require.Eventually(t, event.busy, time.Second, time.Millisecond)
+	backdate(&event.idleTracker, 2*time.Hour)
+
+	counts := collectIdle(map[string]time.Duration{"event": time.Hour})
+	require.Equal(t, 0, counts["event"])
+
+	require.Nil(t, event.Send("done"))
+	require.Nil(t, <-done)
+
+	counts = collectIdle(map[string]time.Duration{"event": time.Hour})
+	require.Equal(t, 1, counts["event"])
+}
+
+func TestJanitorKeepsEmptiedTokenBucket(t *testing.T) {
+	bucket, _ := getTokenBucket("janitor-bucket", 1, time.Hour)
+	defer deleteTokenBucket("janitor-bucket")
+
+	require.Nil(t, bucket.Acquire(context.Background(), 1, 0, time.Now()))
+	backdate(&bucket.idleTracker, 2*time.Hour)
+
+	counts := collectIdle(map[string]time.Duration{"tokenbucket": time.Hour})
+	require.Equal(t, 0, counts["tokenbucket"])
+}
+
+func TestJanitorInvalidInterval(t *testing.T) {
+	viper.Set("idleTTL", 60)
+	viper.Set("janitorInterval", 0)
+	defer viper.Set("idleTTL", nil)
+	defer viper.Set("janitorInterval", nil)
+
+	require.NotNil(t, setupJanitor())
+}
@@ -0,0 +1,483 @@
+package bouncermain
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+// Journal operations. Every record carries absolute state for a single
+// object, so replaying a record more than once is harmless.
+const (
+	opCounterSet      = "counter_set"
+	opSemaphoreSetKey = "semaphore_set_key"
+	opSemaphoreDelKey = "semaphore_del_key"
+	opRWLockSetKey    = "rwlock_set_key"
+	opRWLockDelKey    = "rwlock_del_key"
+	opFence           = "fence"
+	opEventSend       = "event_send"
+	opWatchdogKick    = "watchdog_kick"
+	opDelete          = "delete"
+)
+
+const (
+	snapshotFile  = "snapshot.json"
+	segmentPrefix = "wal-"
+	segmentSuffix = ".log"
+)
+
+type stateRecord struct {
+	Op      string `json:"op"`
+	Type    string `json:"type,omitempty"`
+	Name    string `json:"name"`
+	Key     string `json:"key,omitempty"`
+	Value   int64  `json:"value,omitempty"`
+	Size    uint64 `json:"size,omitempty"`
+	Message string `json:"message,omitempty"`
+	Expires int64  `json:"expires,omitempty"` // unix nano
+	Write   bool   `json:"write,omitempty"`   // rwlock key held for writing
+	Token   uint64 `json:"token,omitempty"`   // fencing token
+	Origin  string `json:"origin,omitempty"`  // replicating node
+}
+
+type stateSnapshot struct {
+	Segment   uint64        `json:"segment"`
+	CreatedAt string        `json:"created_at"`
+	Records   []stateRecord `json:"records"`
+}
+
+func applyRecord(rec stateRecord) {
+	switch rec.Op {
+	case opCounterSet:
+		restoreCounter(rec)
+	case opSemaphoreSetKey, opSemaphoreDelKey:
+		restoreSemaphore(rec)
+	case opRWLockSetKey, opRWLockDelKey:
+		restoreRWLock(rec)
+	case opFence:
+		restoreFence(rec)
+	case opEventSend:
+		restoreEvent(rec)
+	case opWatchdogKick:
+		restoreWatchdog(rec)
+	case opDelete:
+		restoreDelete(rec)
+	default:
+		log.Warn().Str("op", rec.Op).Msg("journal: unknown operation")
+	}
+}
+
+func restoreDelete(rec stateRecord) {
+	deleters := map[string]deleteFunc{
+		"counter":   deleteCounter,
+		"semaphore": deleteSemaphore,
+		"rwlock":    deleteRWLock,
+		"event":     deleteEvent,
+		"watchdog":  deleteWatchdog,
+	}
+
+	if df, ok := deleters[rec.Type]; ok {
+		df(rec.Name)
+	}
+}
+
+// resetState removes every object that captureState would record. Fencing
+// tokens are never lowered.
+func resetState() {
+	for _, rec := range captureState() {
+		if rec.Op != opFence {
+			restoreDelete(stateRecord{Op: opDelete, Type: rec.Type, Name: rec.Name})
+		}
+	}
+}
+
+// captureState returns records that rebuild the current state when applied
+// in order.
+func captureState() []stateRecord {
+	records := []stateRecord{}
+	records = append(records, captureCounters()...)
+	records = append(records, captureSemaphores()...)
+	records = append(records, captureRWLocks()...)
+	records = append(records, captureEvents()...)
+	records = append(records, captureWatchdogs()...)
+	records = append(records, captureFences()...)
+	return records
+}
+
+// Journal is a write-ahead log of state changes, periodically compacted
+// into a snapshot file.
+type Journal struct {
+	dir     string
+	sync    bool
+	mu      sync.Mutex // protects segment and file
+	snapMu  sync.Mutex // serializes snapshots
+	segment uint64
+	file    *os.File
+	stopC   chan struct{}
+	doneC   chan struct{}
+}
+
+var journal *Journal
+
+// recordChange persists a state change and replicates it to the cluster.
+func recordChange(rec stateRecord) {
+	if journal != nil {
+		journal.Append(rec)
+	}
+	if cluster != nil {
+		cluster.replicate(rec)
+	}
+}
+
+func segmentPath(dir string, segment uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("%s%016d%s", segmentPrefix, segment, segmentSuffix))
+}
+
+func listSegments(dir string) ([]uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	segments := []uint64{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, segmentPrefix) || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		var segment uint64
+		_, err := fmt.Sscanf(strings.TrimSuffix(strings.TrimPrefix(name, segmentPrefix), segmentSuffix), "%d", &segment)
+		if err == nil {
+			segments = append(segments, segment)
+		}
+	}
+
+	sort.Slice(segments, func(i, j int) bool { return segments[i] < segments[j] })
+	return segments, nil
+}
+
+func encodeRecord(rec stateRecord) ([]byte, error) {
+	buf, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("%08x %s\n", crc32.ChecksumIEEE(buf), buf)), nil
+}
+
+func decodeRecord(line []byte) (rec stateRecord, err error) {
+	if len(line) < 10 || line[8] != ' ' {
+		return rec, fmt.Errorf("journal: malformed record")
+	}
+
+	var sum uint32
+	if _, err = fmt.Sscanf(string(line[:8]), "%08x", &sum); err != nil {
+		return rec, err
+	}
+
+	payload := line[9:]
+	if crc32.ChecksumIEEE(payload) != sum {
+		return rec, fmt.Errorf("journal: checksum mismatch")
+	}
+
+	err = json.Unmarshal(payload, &rec)
+	return rec, err
+}
+
+// replaySegment applies every valid record in a segment. A torn or corrupt
+// record ends the replay of that segment, since nothing after it can be
+// trusted.
+func replaySegment(path string) (applied int, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) == 0 || (err != nil && len(bytes.TrimSpace(line)) == 0) {
+			return applied, nil
+		}
+
+		if err != nil {
+			log.Warn().Str("segment", path).Msg("journal: ignoring incomplete record at end of segment")
+			return applied, nil
+		}
+
+		rec, derr := decodeRecord(bytes.TrimSuffix(line, []byte("\n")))
+		if derr != nil {
+			log.Warn().Err(derr).Str("segment", path).Msg("journal: ignoring corrupt record and the rest of the segment")
+			return applied, nil
+		}
+
+		applyRecord(rec)
+		applied++
+	}
+}
+
+func loadSnapshot(dir string) (snapshot *stateSnapshot, err error) {
+	buf, err := os.ReadFile(filepath.Join(dir, snapshotFile))
+	if os.IsNotExist(err) {
+		return &stateSnapshot{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot = &stateSnapshot{}
+	err = json.Unmarshal(buf, snapshot)
+	return snapshot, err
+}
+
+// openJournal restores state from the snapshot and log segments found in dir,
+// starts a new segment for subsequent changes and takes a snapshot every
+// interval.
+func openJournal(dir string, sync bool, interval time.Duration) (j *Journal, err error) {
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	snapshot, err := loadSnapshot(dir)
+	if err != nil {
+		return nil, fmt.Errorf("journal: reading snapshot: %w", err)
+	}
+
+	for _, rec := range snapshot.Records {
+		applyRecord(rec)
+	}
+
+	segments, err := listSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	next := snapshot.Segment
+	replayed := 0
+	for _, segment := range segments {
+		if segment < snapshot.Segment {
+			continue
+		}
+		n, err := replaySegment(segmentPath(dir, segment))
+		if err != nil {
+			return nil, err
+		}
+		replayed += n
+		next = segment + 1
+	}
+
+	log.Info().
+		Str("dir", dir).
+		Int("snapshot_records", len(snapshot.Records)).
+		Int("journal_records", replayed).
+		Msg("journal: state restored")
+
+	j = &Journal{
+		dir:   dir,
+		sync:  sync,
+		stopC: make(chan struct{}),
+		doneC: make(chan struct{}),
+	}
+
+	if err = j.openSegment(next); err != nil {
+		return nil, err
+	}
+
+	go j.run(interval)
+
+	return j, nil
+}
+
+func (j *Journal) openSegment(segment uint64) error {
+	f, err := os.OpenFile(segmentPath(j.dir, segment), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	j.segment = segment
+	j.file = f
+	return nil
+}
+
+// Append writes a record to the current segment. Each record is written
+// with a single write call, so a crash leaves at most one torn record at
+// the end of the segment.
+func (j *Journal) Append(rec stateRecord) {
+	buf, err := encodeRecord(rec)
+	if err != nil {
+		log.Error().Err(err).Msg("journal: encoding record")
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file == nil {
+		return
+	}
+
+	if _, err = j.file.Write(buf); err != nil {
+		log.Error().Err(err).Msg("journal: writing record")
+		return
+	}
+
+	if j.sync {
+		if err = j.file.Sync(); err != nil {
+			log.Error().Err(err).Msg("journal: syncing segment")
+		}
+	}
+}
+
+// Snapshot compacts the journal. A new segment is started before state is
+// captured, so every record in older segments is already reflected in the
+// snapshot and those segments can be removed.
+func (j *Journal) Snapshot() error {
+	j.snapMu.Lock()
+	defer j.snapMu.Unlock()
+
+	j.mu.Lock()
+	if j.file == nil {
+		j.mu.Unlock()
+		return nil
+	}
+	previous := j.segment
+	j.file.Close()
+	j.file = nil
+	err := j.openSegment(previous + 1)
+	segment := j.segment
+	j.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	snapshot := stateSnapshot{
+		Segment:   segment,
+		CreatedAt: time.Now().Format(time.RFC3339),
+		Records:   captureState(),
+	}
+
+	buf, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	if err = writeFileAtomic(filepath.Join(j.dir, snapshotFile), buf); err != nil {
+		return err
+	}
+
+	segments, err := listSegments(j.dir)
+	if err != nil {
+		return err
+	}
+	for _, s := range segments {
+		if s < segment {
+			os.Remove(segmentPath(j.dir, s))
+		}
+	}
+
+	log.Debug().Int("records", len(snapshot.Records)).Uint64("segment", segment).Msg("journal: snapshot written")
+	return nil
+}
+
+func writeFileAtomic(path string, buf []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if _, err = f.Write(buf); err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if err = os.Rename(tmp, path); err != nil {
+		return err
+	}
+
+	// sync the directory so the rename itself is durable
+	if d, err := os.Open(filepath.Dir(path)); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// run takes snapshots every interval until Close is called.
+func (j *Journal) run(interval time.Duration) {
+	defer close(j.doneC)
+
+	if interval <= 0 {
+		<-j.stopC
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := j.Snapshot(); err != nil {
+				log.Error().Err(err).Msg("journal: writing snapshot")
+			}
+		case <-j.stopC:
+			return
+		}
+	}
+}
+
+// Close writes a final snapshot and closes the current segment.
+func (j *Journal) Close() error {
+	close(j.stopC)
+	<-j.doneC
+
+	err := j.Snapshot()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file != nil {
+		j.file.Close()
+		j.file = nil
+	}
+	return err
+}
+
+func setupJournal() error {
+	dir := viper.GetString("dataDir")
+	if dir == "" {
+		return nil
+	}
+
+	interval := time.Duration(viper.GetInt("snapshotInterval")) * time.Second
+	j, err := openJournal(dir, viper.GetBool("walSync"), interval)
+	if err != nil {
+		return err
+	}
+
+	// compact whatever was replayed before accepting new changes
+	if err = j.Snapshot(); err != nil {
+		j.Close()
+		return err
+	}
+
+	journal = j
+	return nil
+}
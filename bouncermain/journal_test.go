@@ -0,0 +1,215 @@
+package bouncermain
+
+import (
+	"context"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func useJournal(t *testing.T, dir string) *Journal {
+	j, err := openJournal(dir, false, 0)
+	require.Nil(t, err)
+	journal = j
+	return j
+}
+
+func forgetState(names ...string) {
+	journal = nil
+	for _, name := range names {
+		deleteCounter(name)
+		deleteSemaphore(name)
+		deleteRWLock(name)
+		deleteEvent(name)
+		deleteWatchdog(name)
+	}
+}
+
+// forgetFences resets the fencing counters, as in a new process.
+func forgetFences() {
+	for _, counter := range fenceCounters {
+		atomic.StoreUint64(&counter.last, 0)
+	}
+}
+
+func TestJournalRestoresState(t *testing.T) {
+	dir := t.TempDir()
+	j := useJournal(t, dir)
+
+	counter, _ := getCounter("journal-counter")
+	counter.Count(5)
+	counter.Count(2)
+
+	semaphore, _ := getSemaphore("journal-semaphore", 2)
+	_, _, err := semaphore.Acquire(context.Background(), 0, time.Minute, "held")
+	require.Nil(t, err)
+	_, _, err = semaphore.Acquire(context.Background(), 0, time.Minute, "released")
+	require.Nil(t, err)
+	require.Nil(t, semaphore.Release("released"))
+	require.Nil(t, semaphore.Renew("held", time.Hour))
+
+	rwlock, _ := getRWLock("journal-rwlock")
+	_, _, err = rwlock.Acquire(context.Background(), true, 0, time.Minute, "writer")
+	require.Nil(t, err)
+
+	event, _ := getEvent("journal-event")
+	require.Nil(t, event.Send("hello"))
+
+	watchdog, _ := getWatchdog("journal-watchdog", time.Minute)
+	watchdog.Kick(time.Hour)
+	deadline := watchdog.expires
+
+	require.Nil(t, j.Close())
+	forgetState("journal-counter", "journal-semaphore", "journal-rwlock", "journal-event", "journal-watchdog")
+
+	j = useJournal(t, dir)
+	defer func() {
+		j.Close()
+		forgetState("journal-counter", "journal-semaphore", "journal-rwlock", "journal-event", "journal-watchdog")
+	}()
+
+	counter, _ = getCounter("journal-counter")
+	require.Equal(t, int64(7), counter.Value())
+
+	semaphore, _ = getSemaphore("journal-semaphore", 2)
+	_, ok := semaphore.getKey("held")
+	require.True(t, ok)
+	_, ok = semaphore.getKey("released")
+	require.False(t, ok)
+	require.WithinDuration(t, time.Now().Add(time.Hour), semaphore.deadlines["held"], 5*time.Second)
+
+	rwlock, _ = getRWLock("journal-rwlock")
+	require.True(t, rwlock.Keys["writer"])
+	require.True(t, rwlock.writer)
+
+	event, _ = getEvent("journal-event")
+	message, err := event.Wait(context.Background(), 0)
+	require.Nil(t, err)
+	require.Equal(t, "hello", message)
+
+	watchdog, _ = getWatchdog("journal-watchdog", time.Minute)
+	require.Equal(t, deadline, watchdog.expires)
+}
+
+func TestJournalIgnoresTornRecord(t *testing.T) {
+	dir := t.TempDir()
+	j := useJournal(t, dir)
+
+	counter, _ := getCounter("journal-torn")
+	counter.Count(3)
+
+	// simulate a crash in the middle of a write
+	close(j.stopC)
+	f, err := os.OpenFile(segmentPath(dir, j.segment), os.O_WRONLY|os.O_APPEND, 0o644)
+	require.Nil(t, err)
+	_, err = f.Write([]byte(`0badc0de {"op":"counter_set","name":"journal-torn","val`))
+	require.Nil(t, err)
+	f.Close()
+	forgetState("journal-torn")
+
+	j = useJournal(t, dir)
+	defer func() {
+		j.Close()
+		forgetState("journal-torn")
+	}()
+
+	counter, _ = getCounter("journal-torn")
+	require.Equal(t, int64(3), counter.Value())
+
+	// new records still replay after the torn one
+	counter.Count(1)
+	require.Nil(t, j.Close())
+	forgetState("journal-torn")
+
+	j = useJournal(t, dir)
+	counter, _ = getCounter("journal-torn")
+	require.Equal(t, int64(4), counter.Value())
+}
+
+func TestJournalSkipsExpiredKeys(t *testing.T) {
+	dir := t.TempDir()
+	j := useJournal(t, dir)
+
+	semaphore, _ := getSemaphore("journal-expired", 1)
+	_, _, err := semaphore.Acquire(context.Background(), 0, 50*time.Millisecond, "short")
+	require.Nil(t, err)
+
+	close(j.stopC)
+	forgetState("journal-expired")
+	time.Sleep(100 * time.Millisecond)
+
+	j = useJournal(t, dir)
+	defer func() {
+		j.Close()
+		forgetState("journal-expired")
+	}()
+
+	semaphore, _ = getSemaphore("journal-expired", 1)
+	_, ok := semaphore.getKey("short")
+	require.False(t, ok)
+}
+
+func TestJournalKeepsFencingTokensIncreasing(t *testing.T) {
+	dir := t.TempDir()
+	j := useJournal(t, dir)
+
+	semaphore, _ := getSemaphore("journal-fencing", 1)
+	var last uint64
+	for i := 0; i < 2; i++ {
+		key, token, err := semaphore.Acquire(context.Background(), 0, time.Minute, "")
+		require.Nil(t, err)
+		require.Nil(t, semaphore.Release(key))
+		last = token
+	}
+
+	// closing compacts the log into a snapshot with no holders left
+	require.Nil(t, j.Close())
+	forgetState("journal-fencing")
+	forgetFences()
+
+	j = useJournal(t, dir)
+	defer func() {
+		j.Close()
+		forgetState("journal-fencing")
+	}()
+
+	semaphore, _ = getSemaphore("journal-fencing", 1)
+	_, token, err := semaphore.Acquire(context.Background(), 0, time.Minute, "")
+	require.Nil(t, err)
+	require.Greater(t, token, last)
+}
+
+func TestJournalKeepsFencingTokensAfterDelete(t *testing.T) {
+	dir := t.TempDir()
+	j := useJournal(t, dir)
+
+	rwlock, _ := getRWLock("journal-fencing-delete")
+	key, last, err := rwlock.Acquire(context.Background(), true, 0, time.Minute, "")
+	require.Nil(t, err)
+	require.Nil(t, rwlock.Release(key))
+	require.Nil(t, deleteRWLock("journal-fencing-delete"))
+
+	// the deleted lock isn't restored, but the last token issued is
+	require.Nil(t, j.Close())
+	forgetState("journal-fencing-delete")
+	forgetFences()
+
+	j = useJournal(t, dir)
+	defer func() {
+		j.Close()
+		forgetState("journal-fencing-delete")
+	}()
+
+	rwlocksMutex.RLock()
+	_, ok := rwlocks["journal-fencing-delete"]
+	rwlocksMutex.RUnlock()
+	require.False(t, ok)
+
+	rwlock, _ = getRWLock("journal-fencing-delete")
+	_, token, err := rwlock.Acquire(context.Background(), true, 0, time.Minute, "")
+	require.Nil(t, err)
+	require.Greater(t, token, last)
+}
@@ -0,0 +1,145 @@
+package bouncermain
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const maxListLimit = 1000
+
+type ListRequest struct {
+	Prefix string `schema:"prefix"`
+	Limit  int    `schema:"limit"`
+	After  string `schema:"after"`
+}
+
+func newListRequest() *ListRequest {
+	return &ListRequest{
+		Prefix: "",
+		Limit:  100,
+		After:  "",
+	}
+}
+
+func (r *ListRequest) Decode(values url.Values) error {
+	err := decoder.Decode(r, values)
+	if err == nil && (r.Limit < 1 || r.Limit > maxListLimit) {
+		err = ErrInvalidLimit
+	}
+	return err
+}
+
+type ResourceInfo struct {
+	Type   string      `json:"type"`
+	Name   string      `json:"name"`
+	Config interface{} `json:"config,omitempty"`
+	Stats  interface{} `json:"stats"`
+}
+
+type ResourceList struct {
+	Items []ResourceInfo `json:"items"`
+	Next  string         `json:"next,omitempty"` // pass as 'after' to get the next page
+}
+
+// ConfigGetter is a function type for getting the configuration of any
+// synchronization primitive
+type ConfigGetter = func(name string) (interface{}, error)
+
+type resourceType struct {
+	names  func() []string
+	config ConfigGetter
+	stats  StatsGetter
+	delete deleteFunc
+}
+
+// resourceTypes maps each type name, as used in routes, to the functions
+// that describe its objects.
+var resourceTypes = map[string]resourceType{
+	"barrier":     {func() []string { return mapNames(barriers, barriersMutex) }, getBarrierConfig, getBarrierStats, deleteBarrier},
+	"counter":     {func() []string { return mapNames(counters, countersMutex) }, nil, getCounterStats, deleteCounter},
+	"event":       {func() []string { return mapNames(events, eventsMutex) }, nil, getEventStats, deleteEvent},
+	"ratelimit":   {func() []string { return mapNames(rateLimiters, rateLimitersMutex) }, getRateLimiterConfig, getRateLimiterStats, deleteRateLimiter},
+	"rwlock":      {func() []string { return mapNames(rwlocks, rwlocksMutex) }, getRWLockConfig, getRWLockStats, deleteRWLock},
+	"semaphore":   {func() []string { return mapNames(semaphores, semaphoresMutex) }, getSemaphoreConfig, getSemaphoreStats, deleteSemaphore},
+	"tokenbucket": {func() []string { return mapNames(buckets, bucketsMutex) }, getTokenBucketConfig, getTokenBucketStats, deleteTokenBucket},
+	"watchdog":    {func() []string { return mapNames(watchdogs, watchdogsMutex) }, getWatchdogConfig, getWatchdogStats, deleteWatchdog},
+}
+
+func mapNames[T any](m map[string]T, mu *sync.RWMutex) []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	return names
+}
+
+func describeResource(typeName string, name string) (info ResourceInfo, err error) {
+	rt := resourceTypes[typeName]
+	info = ResourceInfo{Type: typeName, Name: name}
+
+	info.Stats, err = rt.stats(name)
+	if err == nil && rt.config != nil {
+		info.Config, err = rt.config(name)
+	}
+	return info, err
+}
+
+// listResources lists objects of the given types in (type, name) order,
+// within a namespace if not empty. Objects deleted while the page is built
+// are skipped.
+func listResources(typeNames []string, namespace string, req *ListRequest) *ResourceList {
+	sort.Strings(typeNames)
+	list := &ResourceList{Items: []ResourceInfo{}}
+	multi := len(typeNames) > 1
+
+	for _, typeName := range typeNames {
+		names := namespaceNames(typeName, namespace)
+		sort.Strings(names)
+
+		for _, name := range names {
+			cursor := name
+			if multi {
+				cursor = typeName + "/" + name
+			}
+
+			if !strings.HasPrefix(name, req.Prefix) || (req.After != "" && cursor <= req.After) {
+				continue
+			}
+
+			if len(list.Items) == req.Limit {
+				list.Next = list.cursor(multi)
+				return list
+			}
+
+			info, err := describeResource(typeName, qualifiedName(namespace, name))
+			if err != nil {
+				continue
+			}
+			info.Name = name
+			list.Items = append(list.Items, info)
+		}
+	}
+
+	return list
+}
+
+func (list *ResourceList) cursor(multi bool) string {
+	last := list.Items[len(list.Items)-1]
+	if multi {
+		return last.Type + "/" + last.Name
+	}
+	return last.Name
+}
+
+func allResourceTypes() []string {
+	typeNames := make([]string, 0, len(resourceTypes))
+	for typeName := range resourceTypes {
+		typeNames = append(typeNames, typeName)
+	}
+	return typeNames
+}
@@ -0,0 +1,97 @@
+package bouncermain_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type resourceList struct {
+	Items []struct {
+		Type   string                 `json:"type"`
+		Name   string                 `json:"name"`
+		Config map[string]interface{} `json:"config"`
+		Stats  map[string]interface{} `json:"stats"`
+	} `json:"items"`
+	Next string `json:"next"`
+}
+
+func getList(t *testing.T, url string) resourceList {
+	status, body, err := GetRequest(url)
+	require.Nil(t, err)
+	require.Equal(t, 200, status)
+
+	var list resourceList
+	require.Nil(t, json.Unmarshal([]byte(body), &list))
+	return list
+}
+
+func TestListSemaphores(t *testing.T) {
+	for _, name := range []string{"list-a1", "list-a2", "list-b1"} {
+		status, _, err := GetRequest(fmt.Sprintf("%s/semaphore/%s/acquire?size=3", server.URL, name))
+		require.Nil(t, err)
+		require.Equal(t, 200, status)
+	}
+
+	list := getList(t, fmt.Sprintf("%s/semaphore/?prefix=list-a", server.URL))
+	require.Len(t, list.Items, 2)
+	require.Equal(t, "list-a1", list.Items[0].Name)
+	require.Equal(t, "list-a2", list.Items[1].Name)
+	require.Equal(t, float64(3), list.Items[0].Config["size"])
+	require.Equal(t, float64(1), list.Items[0].Stats["acquired"])
+	require.Empty(t, list.Next)
+}
+
+func TestListPagination(t *testing.T) {
+	for _, name := range []string{"page-1", "page-2", "page-3"} {
+		status, _, err := GetRequest(fmt.Sprintf("%s/counter/%s/count", server.URL, name))
+		require.Nil(t, err)
+		require.Equal(t, 200, status)
+	}
+
+	names := []string{}
+	after := ""
+	for {
+		list := getList(t, fmt.Sprintf("%s/counter/?prefix=page-&limit=2&after=%s", server.URL, after))
+		for _, item := range list.Items {
+			names = append(names, item.Name)
+		}
+		if list.Next == "" {
+			break
+		}
+		after = list.Next
+	}
+
+	require.Equal(t, []string{"page-1", "page-2", "page-3"}, names)
+}
+
+func TestListResources(t *testing.T) {
+	status, _, err := GetRequest(fmt.Sprintf("%s/counter/resources-test/count", server.URL))
+	require.Nil(t, err)
+	require.Equal(t, 200, status)
+
+	status, _, err = GetRequest(fmt.Sprintf("%s/tokenbucket/resources-test/acquire?size=5&interval=2000", server.URL))
+	require.Nil(t, err)
+	require.Equal(t, 204, status)
+
+	list := getList(t, fmt.Sprintf("%s/resources?prefix=resources-test", server.URL))
+	require.Len(t, list.Items, 2)
+	require.Equal(t, "counter", list.Items[0].Type)
+	require.Equal(t, "tokenbucket", list.Items[1].Type)
+	require.Equal(t, float64(2000), list.Items[1].Config["interval"])
+
+	list = getList(t, fmt.Sprintf("%s/resources?prefix=resources-test&limit=1", server.URL))
+	require.Equal(t, "counter/resources-test", list.Next)
+
+	list = getList(t, fmt.Sprintf("%s/resources?prefix=resources-test&after=%s", server.URL, list.Next))
+	require.Len(t, list.Items, 1)
+	require.Equal(t, "tokenbucket", list.Items[0].Type)
+}
+
+func TestListInvalidLimit(t *testing.T) {
+	status, _, err := GetRequest(fmt.Sprintf("%s/resources?limit=0", server.URL))
+	require.Nil(t, err)
+	require.Equal(t, 400, status)
+}
@@ -0,0 +1,311 @@
+package bouncermain
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Wait times range from immediate acquires to long-polling requests, so the
+// buckets go from 1ms to about 4 minutes.
+var waitBuckets = prometheus.ExponentialBuckets(0.001, 4, 10)
+
+var (
+	metricsRegistry = prometheus.NewRegistry()
+	promHandler     = promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{})
+
+	waitSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "bouncer_wait_seconds",
+		Help:    "Time clients waited for successful operations.",
+		Buckets: waitBuckets,
+	}, []string{"type", "name"})
+
+	requestSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "bouncer_http_request_duration_seconds",
+		Help:    "HTTP request latency by route.",
+		Buckets: waitBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+func init() {
+	metricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		waitSeconds,
+		requestSeconds,
+		&primitiveCollector{},
+	)
+}
+
+func observeWait(resourceType string, name string, wait time.Duration) {
+	waitSeconds.WithLabelValues(resourceType, name).Observe(wait.Seconds())
+}
+
+func forgetMetrics(resourceType string, name string) {
+	waitSeconds.DeleteLabelValues(resourceType, name)
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets WebSocket connections take over, logged as switching
+// protocols.
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	r.status = http.StatusSwitchingProtocols
+	return http.NewResponseController(r.ResponseWriter).Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// instrument records the latency of a route, labeled by its pattern rather
+// than the request path to keep the number of series bounded.
+func instrument(method string, route string, handle httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		handle(rec, r, ps)
+
+		requestSeconds.WithLabelValues(method, route, strconv.Itoa(rec.status)).Observe(time.Since(start).Seconds())
+	}
+}
+
+// instrumentedRouter registers every route through instrument, and the
+// routes for objects also under /ns/:namespace.
+type instrumentedRouter struct {
+	*httprouter.Router
+}
+
+func (r instrumentedRouter) handle(method string, path string, handle httprouter.Handle) {
+	r.Router.Handle(method, path, instrument(method, path, handle))
+	if namespacedRoute(path) {
+		nsPath := "/ns/:namespace" + path
+		r.Router.Handle(method, nsPath, instrument(method, nsPath, namespaced(method, path, handle)))
+	}
+}
+
+func (r instrumentedRouter) GET(path string, handle httprouter.Handle) {
+	r.handle(http.MethodGet, path, handle)
+}
+
+func (r instrumentedRouter) DELETE(path string, handle httprouter.Handle) {
+	r.handle(http.MethodDelete, path, handle)
+}
+
+func (r instrumentedRouter) PUT(path string, handle httprouter.Handle) {
+	r.handle(http.MethodPut, path, handle)
+}
+
+var primitiveDescs = []*prometheus.Desc{}
+
+func newDesc(name string, help string) *prometheus.Desc {
+	desc := prometheus.NewDesc(name, help, []string{"name"}, nil)
+	primitiveDescs = append(primitiveDescs, desc)
+	return desc
+}
+
+var (
+	semaphoreAcquiredDesc   = newDesc("bouncer_semaphore_acquired_total", "Semaphore keys acquired.")
+	semaphoreReacquiredDesc = newDesc("bouncer_semaphore_reacquired_total", "Semaphore keys reacquired.")
+	semaphoreReleasedDesc   = newDesc("bouncer_semaphore_released_total", "Semaphore keys released.")
+	semaphoreExpiredDesc    = newDesc("bouncer_semaphore_expired_total", "Semaphore keys expired.")
+	semaphoreRenewedDesc    = newDesc("bouncer_semaphore_renewed_total", "Semaphore keys renewed.")
+	semaphoreTimedOutDesc   = newDesc("bouncer_semaphore_timed_out_total", "Semaphore acquires that timed out.")
+	semaphoreCanceledDesc   = newDesc("bouncer_semaphore_canceled_total", "Semaphore acquires abandoned by the client.")
+	semaphoreHeldDesc       = newDesc("bouncer_semaphore_held", "Semaphore keys currently held.")
+	semaphoreSizeDesc       = newDesc("bouncer_semaphore_size", "Semaphore size.")
+	semaphoreMaxHeldDesc    = newDesc("bouncer_semaphore_max_ever_held", "Maximum number of semaphore keys ever held at once.")
+
+	rwlockReadAcquiredDesc  = newDesc("bouncer_rwlock_read_acquired_total", "Read locks acquired.")
+	rwlockWriteAcquiredDesc = newDesc("bouncer_rwlock_write_acquired_total", "Write locks acquired.")
+	rwlockReleasedDesc      = newDesc("bouncer_rwlock_released_total", "Lock keys released.")
+	rwlockExpiredDesc       = newDesc("bouncer_rwlock_expired_total", "Lock keys expired.")
+	rwlockTimedOutDesc      = newDesc("bouncer_rwlock_timed_out_total", "Lock acquires that timed out.")
+	rwlockCanceledDesc      = newDesc("bouncer_rwlock_canceled_total", "Lock acquires abandoned by the client.")
+	rwlockReadersDesc       = newDesc("bouncer_rwlock_readers", "Read locks currently held.")
+	rwlockWriterDesc        = newDesc("bouncer_rwlock_writer", "Whether the write lock is held.")
+
+	bucketAcquiredDesc  = newDesc("bouncer_tokenbucket_acquired_total", "Successful token acquires.")
+	bucketTokensDesc    = newDesc("bouncer_tokenbucket_tokens_total", "Tokens taken by successful acquires.")
+	bucketTimedOutDesc  = newDesc("bouncer_tokenbucket_timed_out_total", "Token acquires that timed out.")
+	bucketDeniedDesc    = newDesc("bouncer_tokenbucket_denied_total", "Token checks denied.")
+	bucketCanceledDesc  = newDesc("bouncer_tokenbucket_canceled_total", "Token acquires abandoned by the client.")
+	bucketAvailableDesc = newDesc("bouncer_tokenbucket_available", "Tokens currently available.")
+	bucketSizeDesc      = newDesc("bouncer_tokenbucket_size", "Token bucket size.")
+
+	rateLimitAcquiredDesc = newDesc("bouncer_ratelimit_acquired_total", "Rate limited requests allowed.")
+	rateLimitTimedOutDesc = newDesc("bouncer_ratelimit_timed_out_total", "Rate limited requests that timed out.")
+	rateLimitCanceledDesc = newDesc("bouncer_ratelimit_canceled_total", "Rate limited requests abandoned by the client.")
+
+	eventWaitedDesc    = newDesc("bouncer_event_waited_total", "Event waits completed.")
+	eventTimedOutDesc  = newDesc("bouncer_event_timed_out_total", "Event waits that timed out.")
+	eventCanceledDesc  = newDesc("bouncer_event_canceled_total", "Event waits abandoned by the client.")
+	eventTriggeredDesc = newDesc("bouncer_event_triggered_total", "Events sent.")
+
+	watchdogWaitedDesc   = newDesc("bouncer_watchdog_waited_total", "Watchdog waits completed.")
+	watchdogTimedOutDesc = newDesc("bouncer_watchdog_timed_out_total", "Watchdog waits that timed out.")
+	watchdogCanceledDesc = newDesc("bouncer_watchdog_canceled_total", "Watchdog waits abandoned by the client.")
+	watchdogKicksDesc    = newDesc("bouncer_watchdog_kicks_total", "Watchdog kicks.")
+	watchdogExpiresDesc  = newDesc("bouncer_watchdog_expires_timestamp_seconds", "Time the watchdog expires, in seconds since the epoch.")
+
+	counterValueDesc      = newDesc("bouncer_counter_value", "Counter value.")
+	counterIncrementsDesc = newDesc("bouncer_counter_increments_total", "Counter increments.")
+	counterResetsDesc     = newDesc("bouncer_counter_resets_total", "Counter resets.")
+
+	barrierWaitingDesc   = newDesc("bouncer_barrier_waiting", "Clients currently waiting at the barrier.")
+	barrierSizeDesc      = newDesc("bouncer_barrier_size", "Barrier size.")
+	barrierWaitedDesc    = newDesc("bouncer_barrier_waited_total", "Barrier waits completed.")
+	barrierTimedOutDesc  = newDesc("bouncer_barrier_timed_out_total", "Barrier waits that timed out.")
+	barrierCanceledDesc  = newDesc("bouncer_barrier_canceled_total", "Barrier waits abandoned by the client.")
+	barrierTriggeredDesc = newDesc("bouncer_barrier_triggered_total", "Barriers triggered.")
+)
+
+// primitiveCollector reports the stats of every live object at scrape time.
+type primitiveCollector struct{}
+
+func (c *primitiveCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range primitiveDescs {
+		ch <- desc
+	}
+}
+
+func (c *primitiveCollector) Collect(ch chan<- prometheus.Metric) {
+	counter := func(desc *prometheus.Desc, value uint64, name string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(value), name)
+	}
+	gauge := func(desc *prometheus.Desc, value float64, name string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, name)
+	}
+
+	semaphoresMutex.RLock()
+	for name, s := range semaphores {
+		counter(semaphoreAcquiredDesc, atomic.LoadUint64(&s.Stats.Acquired), name)
+		counter(semaphoreReacquiredDesc, atomic.LoadUint64(&s.Stats.Reacquired), name)
+		counter(semaphoreReleasedDesc, atomic.LoadUint64(&s.Stats.Released), name)
+		counter(semaphoreExpiredDesc, atomic.LoadUint64(&s.Stats.Expired), name)
+		counter(semaphoreRenewedDesc, atomic.LoadUint64(&s.Stats.Renewed), name)
+		counter(semaphoreTimedOutDesc, atomic.LoadUint64(&s.Stats.TimedOut), name)
+		counter(semaphoreCanceledDesc, atomic.LoadUint64(&s.Stats.Canceled), name)
+		gauge(semaphoreMaxHeldDesc, float64(atomic.LoadUint64(&s.Stats.MaxEverHeld)), name)
+		s.mu.RLock()
+		gauge(semaphoreHeldDesc, float64(len(s.Keys)), name)
+		gauge(semaphoreSizeDesc, float64(s.Size), name)
+		s.mu.RUnlock()
+	}
+	semaphoresMutex.RUnlock()
+
+	rwlocksMutex.RLock()
+	for name, l := range rwlocks {
+		counter(rwlockReadAcquiredDesc, atomic.LoadUint64(&l.Stats.ReadAcquired), name)
+		counter(rwlockWriteAcquiredDesc, atomic.LoadUint64(&l.Stats.WriteAcquired), name)
+		counter(rwlockReleasedDesc, atomic.LoadUint64(&l.Stats.Released), name)
+		counter(rwlockExpiredDesc, atomic.LoadUint64(&l.Stats.Expired), name)
+		counter(rwlockTimedOutDesc, atomic.LoadUint64(&l.Stats.TimedOut), name)
+		counter(rwlockCanceledDesc, atomic.LoadUint64(&l.Stats.Canceled), name)
+		l.mu.RLock()
+		readers, writer := float64(len(l.Keys)), 0.0
+		if l.writer {
+			readers, writer = 0, 1
+		}
+		l.mu.RUnlock()
+		gauge(rwlockReadersDesc, readers, name)
+		gauge(rwlockWriterDesc, writer, name)
+	}
+	rwlocksMutex.RUnlock()
+
+	bucketsMutex.RLock()
+	for name, b := range buckets {
+		available, size := b.level()
+		counter(bucketAcquiredDesc, atomic.LoadUint64(&b.Stats.Acquired), name)
+		counter(bucketTokensDesc, atomic.LoadUint64(&b.Stats.Tokens), name)
+		counter(bucketTimedOutDesc, atomic.LoadUint64(&b.Stats.TimedOut), name)
+		counter(bucketDeniedDesc, atomic.LoadUint64(&b.Stats.Denied), name)
+		counter(bucketCanceledDesc, atomic.LoadUint64(&b.Stats.Canceled), name)
+		gauge(bucketAvailableDesc, float64(available), name)
+		gauge(bucketSizeDesc, float64(size), name)
+	}
+	bucketsMutex.RUnlock()
+
+	rateLimitersMutex.RLock()
+	for name, l := range rateLimiters {
+		counter(rateLimitAcquiredDesc, atomic.LoadUint64(&l.Stats.Acquired), name)
+		counter(rateLimitTimedOutDesc, atomic.LoadUint64(&l.Stats.TimedOut), name)
+		counter(rateLimitCanceledDesc, atomic.LoadUint64(&l.Stats.Canceled), name)
+	}
+	rateLimitersMutex.RUnlock()
+
+	eventsMutex.RLock()
+	for name, e := range events {
+		counter(eventWaitedDesc, atomic.LoadUint64(&e.Stats.Waited), name)
+		counter(eventTimedOutDesc, atomic.LoadUint64(&e.Stats.TimedOut), name)
+		counter(eventCanceledDesc, atomic.LoadUint64(&e.Stats.Canceled), name)
+		counter(eventTriggeredDesc, atomic.LoadUint64(&e.Stats.Triggered), name)
+	}
+	eventsMutex.RUnlock()
+
+	watchdogsMutex.RLock()
+	for name, w := range watchdogs {
+		counter(watchdogWaitedDesc, atomic.LoadUint64(&w.Stats.Waited), name)
+		counter(watchdogTimedOutDesc, atomic.LoadUint64(&w.Stats.TimedOut), name)
+		counter(watchdogCanceledDesc, atomic.LoadUint64(&w.Stats.Canceled), name)
+		counter(watchdogKicksDesc, atomic.LoadUint64(&w.Stats.Kicks), name)
+		gauge(watchdogExpiresDesc, float64(atomic.LoadInt64(&w.expires))/1e9, name)
+	}
+	watchdogsMutex.RUnlock()
+
+	countersMutex.RLock()
+	for name, c := range counters {
+		gauge(counterValueDesc, float64(c.Value()), name)
+		counter(counterIncrementsDesc, atomic.LoadUint64(&c.Stats.Increments), name)
+		counter(counterResetsDesc, atomic.LoadUint64(&c.Stats.Resets), name)
+	}
+	countersMutex.RUnlock()
+
+	barriersMutex.RLock()
+	for name, b := range barriers {
+		gauge(barrierWaitingDesc, float64(atomic.LoadUint64(&b.Stats.Waiting)), name)
+		b.mu.RLock()
+		gauge(barrierSizeDesc, float64(b.Size), name)
+		b.mu.RUnlock()
+		counter(barrierWaitedDesc, atomic.LoadUint64(&b.Stats.TotalWaited), name)
+		counter(barrierTimedOutDesc, atomic.LoadUint64(&b.Stats.TimedOut), name)
+		counter(barrierCanceledDesc, atomic.LoadUint64(&b.Stats.Canceled), name)
+		counter(barrierTriggeredDesc, atomic.LoadUint64(&b.Stats.Triggered), name)
+	}
+	barriersMutex.RUnlock()
+}
+
+// MetricsHandler godoc
+// @Summary Prometheus metrics
+// @Description Statistics of every live object and HTTP request latency in Prometheus text format
+// @Tags Health
+// @Produce plain
+// @Success 200 {string} string "Metrics in Prometheus text exposition format"
+// @Router /metrics [get]
+func MetricsHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	promHandler.ServeHTTP(w, r)
+}
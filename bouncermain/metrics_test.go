@@ -0,0 +1,56 @@
+package bouncermain_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	status, _, err := GetRequest(fmt.Sprintf("%s/semaphore/metrics-test/acquire?size=2", server.URL))
+	require.Nil(t, err)
+	require.Equal(t, 200, status)
+
+	status, _, err = GetRequest(fmt.Sprintf("%s/tokenbucket/metrics-test/acquire?size=5", server.URL))
+	require.Nil(t, err)
+	require.Equal(t, 204, status)
+
+	status, body, err := GetRequest(fmt.Sprintf("%s/metrics", server.URL))
+	require.Nil(t, err)
+	require.Equal(t, 200, status)
+
+	require.Contains(t, body, `bouncer_semaphore_acquired_total{name="metrics-test"} 1`)
+	require.Contains(t, body, `bouncer_semaphore_held{name="metrics-test"} 1`)
+	require.Contains(t, body, `bouncer_semaphore_size{name="metrics-test"} 2`)
+	require.Contains(t, body, `bouncer_tokenbucket_available{name="metrics-test"} 4`)
+	require.Contains(t, body, `bouncer_wait_seconds_count{name="metrics-test",type="semaphore"} 1`)
+	require.Contains(t, body, `bouncer_http_request_duration_seconds_count{method="GET",route="/semaphore/:name/acquire",status="200"}`)
+}
+
+func TestMetricsTokenBucketRefillDue(t *testing.T) {
+	status, _, err := GetRequest(fmt.Sprintf("%s/tokenbucket/metrics-refill/acquire?size=5&interval=50", server.URL))
+	require.Nil(t, err)
+	require.Equal(t, 204, status)
+
+	// a refill that is due counts, though no one took it yet
+	time.Sleep(100 * time.Millisecond)
+	_, body, err := GetRequest(fmt.Sprintf("%s/metrics", server.URL))
+	require.Nil(t, err)
+	require.Contains(t, body, `bouncer_tokenbucket_available{name="metrics-refill"} 5`)
+}
+
+func TestMetricsForgetDeletedObjects(t *testing.T) {
+	status, _, err := GetRequest(fmt.Sprintf("%s/event/metrics-delete/send", server.URL))
+	require.Nil(t, err)
+	require.Equal(t, 204, status)
+
+	status, _, err = DeleteRequest(fmt.Sprintf("%s/event/metrics-delete", server.URL))
+	require.Nil(t, err)
+	require.Equal(t, 204, status)
+
+	_, body, err := GetRequest(fmt.Sprintf("%s/metrics", server.URL))
+	require.Nil(t, err)
+	require.NotContains(t, body, `name="metrics-delete"`)
+}
@@ -0,0 +1,253 @@
+package bouncermain
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/spf13/viper"
+)
+
+// namespaceSeparator joins a namespace and a name into the key of an object
+// in the map of its type. Names in routes can't contain it, so objects in a
+// namespace never collide with those outside it, or in other namespaces.
+const namespaceSeparator = "/"
+
+// NamespaceConfig holds the limits of a namespace.
+type NamespaceConfig struct {
+	MaxObjects int `json:"max_objects"` // objects of all types, 0 for no limit
+}
+
+type NamespaceEntry struct {
+	objectMatch
+	NamespaceConfig
+}
+
+type NamespaceStats struct {
+	Name       string         `json:"name"`
+	Objects    int            `json:"objects"`
+	MaxObjects int            `json:"max_objects"`
+	Types      map[string]int `json:"types"`
+}
+
+type NamespaceList struct {
+	Items []NamespaceStats `json:"items"`
+	Next  string           `json:"next,omitempty"` // pass as 'after' to get the next page
+}
+
+type namespaceKey struct{}
+
+// requestNamespace returns the namespace of a request under /ns/, if any.
+func requestNamespace(r *http.Request) string {
+	namespace, _ := r.Context().Value(namespaceKey{}).(string)
+	return namespace
+}
+
+// qualifiedName returns the key of an object in a namespace.
+func qualifiedName(namespace string, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + namespaceSeparator + name
+}
+
+// namespaceNames returns the names of the objects of a type within a
+// namespace, without it, or every name if namespace is empty.
+func namespaceNames(typeName string, namespace string) []string {
+	names := resourceTypes[typeName].names()
+	if namespace == "" {
+		return names
+	}
+
+	local := names[:0]
+	for _, name := range names {
+		if name, ok := strings.CutPrefix(name, namespace+namespaceSeparator); ok {
+			local = append(local, name)
+		}
+	}
+	return local
+}
+
+// namespaceLimit returns the maximum number of objects in a namespace, from
+// the config file or else BOUNCER_NAMESPACE_MAX_OBJECTS.
+func namespaceLimit(namespace string) int {
+	if e, ok := findEntry(currentFileConfig().Namespaces, namespace); ok {
+		return e.MaxObjects
+	}
+	return viper.GetInt("namespaceMaxObjects")
+}
+
+func getNamespaceStats(namespace string) *NamespaceStats {
+	stats := &NamespaceStats{
+		Name:       namespace,
+		MaxObjects: namespaceLimit(namespace),
+		Types:      map[string]int{},
+	}
+	for typeName := range resourceTypes {
+		n := len(namespaceNames(typeName, namespace))
+		stats.Types[typeName] = n
+		stats.Objects += n
+	}
+	return stats
+}
+
+// checkNamespaceLimit fails calls that would create an object in a full
+// namespace. Objects are counted before the call, so concurrent calls may
+// briefly go over the limit.
+func checkNamespaceLimit(typeName string, namespace string, name string) error {
+	limit := namespaceLimit(namespace)
+	if limit <= 0 {
+		return nil
+	}
+	if _, err := resourceTypes[typeName].stats(name); err == nil {
+		return nil
+	}
+	if getNamespaceStats(namespace).Objects >= limit {
+		return ErrTooManyObjects
+	}
+	return nil
+}
+
+// listNamespaces lists every namespace with an object in it, by name.
+func listNamespaces(req *ListRequest) *NamespaceList {
+	seen := map[string]bool{}
+	for typeName := range resourceTypes {
+		for _, name := range resourceTypes[typeName].names() {
+			if namespace, _, ok := strings.Cut(name, namespaceSeparator); ok {
+				seen[namespace] = true
+			}
+		}
+	}
+
+	namespaces := make([]string, 0, len(seen))
+	for namespace := range seen {
+		if strings.HasPrefix(namespace, req.Prefix) && namespace > req.After {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	sort.Strings(namespaces)
+
+	list := &NamespaceList{Items: []NamespaceStats{}}
+	for _, namespace := range namespaces {
+		if len(list.Items) == req.Limit {
+			list.Next = list.Items[len(list.Items)-1].Name
+			break
+		}
+		list.Items = append(list.Items, *getNamespaceStats(namespace))
+	}
+	return list
+}
+
+// deleteNamespace deletes every object in a namespace.
+func deleteNamespace(namespace string) error {
+	deleted := false
+	for typeName, rt := range resourceTypes {
+		for _, name := range namespaceNames(typeName, namespace) {
+			if rt.delete(qualifiedName(namespace, name)) == nil {
+				deleted = true
+			}
+		}
+	}
+
+	if !deleted {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// namespacedRoute reports whether a route is also served under
+// /ns/:namespace, for the objects in a namespace.
+func namespacedRoute(path string) bool {
+	typeName, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	_, ok := resourceTypes[typeName]
+	return ok || path == "/resources" || streamPath(path)
+}
+
+// namespaced serves a route under /ns/:namespace, passing the handler the
+// name qualified by the namespace, and checking the namespace limit for
+// calls that may create an object.
+func namespaced(method string, path string, handle httprouter.Handle) httprouter.Handle {
+	typeName, rest, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	_, call, _ := strings.Cut(rest, "/")
+	creates := strings.HasPrefix(rest, ":name") && method != http.MethodDelete &&
+		requiredAccess(method, call) != accessRead
+
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		namespace := ps[0].Value
+		params := slices.Clone(ps[1:])
+		if len(params) > 0 {
+			params[0].Value = qualifiedName(namespace, params[0].Value)
+		}
+
+		if creates {
+			if err := checkNamespaceLimit(typeName, namespace, params[0].Value); err != nil {
+				rep := newReply()
+				rep.WriteResponse(w, r, err)
+				logRequest(r, rep.Status, typeName, call, params[0].Value, 0, nil).Send()
+				return
+			}
+		}
+
+		handle(w, r.WithContext(context.WithValue(r.Context(), namespaceKey{}, namespace)), params)
+	}
+}
+
+// NamespaceListHandler godoc
+// @Summary List namespaces
+// @Description List namespaces with objects in them, with their object counts, ordered by name
+// @Tags Namespace
+// @Produce json
+// @Param prefix query string false "Only list namespaces starting with prefix"
+// @Param limit query int false "Maximum number of items" default(100)
+// @Param after query string false "Only list namespaces after this one, as returned in 'next'"
+// @Success 200 {object} NamespaceList "Namespaces"
+// @Failure 400 {string} Reply "Bad Request - invalid parameters"
+// @Router /ns/ [get]
+func NamespaceListHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	req := newListRequest()
+	rep := newReply()
+
+	err := req.Decode(r.URL.Query())
+	if err == nil {
+		buf, _ := json.Marshal(listNamespaces(req))
+		rep.Body = string(buf)
+	}
+
+	rep.WriteResponse(w, r, err)
+	logRequest(r, rep.Status, "namespace", "list", "", 0, nil).Send()
+}
+
+// NamespaceStatsHandler godoc
+// @Summary Get namespace statistics
+// @Description Get the number of objects in a namespace, in total and by type, and its limit
+// @Tags Namespace
+// @Produce json
+// @Param namespace path string true "Namespace name"
+// @Success 200 {object} NamespaceStats "Namespace statistics"
+// @Router /ns/{namespace}/stats [get]
+func NamespaceStatsHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	rep := newReply()
+	buf, _ := json.Marshal(getNamespaceStats(ps[0].Value))
+	rep.Body = string(buf)
+
+	rep.WriteResponse(w, r, nil)
+	logRequest(r, rep.Status, "namespace", "stats", ps[0].Value, 0, nil).Send()
+}
+
+// NamespaceDeleteHandler godoc
+// @Summary Delete a namespace
+// @Description Delete every object in a namespace
+// @Tags Namespace
+// @Produce plain
+// @Param namespace path string true "Namespace name"
+// @Success 204 "Namespace deleted successfully"
+// @Failure 404 {string} Reply "Not Found - no objects in namespace"
+// @Router /ns/{namespace} [delete]
+func NamespaceDeleteHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	status := DeleteHandler(w, r, ps, deleteNamespace)
+	logRequest(r, status, "namespace", "delete", ps[0].Value, 0, nil).Send()
+}
@@ -0,0 +1,120 @@
+package bouncermain_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+type namespaceStats struct {
+	Name       string         `json:"name"`
+	Objects    int            `json:"objects"`
+	MaxObjects int            `json:"max_objects"`
+	Types      map[string]int `json:"types"`
+}
+
+func getNamespaceStats(t *testing.T, namespace string) namespaceStats {
+	status, body, err := GetRequest(fmt.Sprintf("%s/ns/%s/stats", server.URL, namespace))
+	require.Nil(t, err)
+	require.Equal(t, 200, status)
+
+	var stats namespaceStats
+	require.Nil(t, json.Unmarshal([]byte(body), &stats))
+	return stats
+}
+
+func TestNamespaceIsolation(t *testing.T) {
+	defer DeleteRequest(server.URL + "/ns/ns-a")
+	defer DeleteRequest(server.URL + "/ns/ns-b")
+
+	// the same name in two namespaces, and outside them, are three semaphores
+	for _, prefix := range []string{"/ns/ns-a", "/ns/ns-b", ""} {
+		status, _, err := GetRequest(server.URL + prefix + "/semaphore/ns-db/acquire?maxwait=0")
+		require.Nil(t, err)
+		require.Equal(t, 200, status, prefix)
+	}
+	defer DeleteRequest(server.URL + "/semaphore/ns-db")
+
+	status, _, err := GetRequest(server.URL + "/ns/ns-a/semaphore/ns-db/acquire?maxwait=0")
+	require.Nil(t, err)
+	require.Equal(t, 408, status)
+
+	list := getList(t, server.URL+"/ns/ns-a/semaphore/")
+	require.Len(t, list.Items, 1)
+	require.Equal(t, "ns-db", list.Items[0].Name)
+
+	// objects outside namespaces see them by their qualified names
+	list = getList(t, server.URL+"/semaphore/?prefix=ns-")
+	require.Len(t, list.Items, 3)
+	require.Equal(t, "ns-a/ns-db", list.Items[0].Name)
+	require.Equal(t, "ns-b/ns-db", list.Items[1].Name)
+	require.Equal(t, "ns-db", list.Items[2].Name)
+}
+
+func TestNamespaceStatsAndDelete(t *testing.T) {
+	for _, path := range []string{"/semaphore/db/acquire", "/counter/hits/count", "/counter/misses/count"} {
+		status, _, err := GetRequest(server.URL + "/ns/ns-stats" + path)
+		require.Nil(t, err)
+		require.Equal(t, 200, status)
+	}
+
+	stats := getNamespaceStats(t, "ns-stats")
+	require.Equal(t, 3, stats.Objects)
+	require.Equal(t, 1, stats.Types["semaphore"])
+	require.Equal(t, 2, stats.Types["counter"])
+
+	list := getList(t, server.URL+"/ns/ns-stats/resources")
+	require.Len(t, list.Items, 3)
+	require.Equal(t, "counter", list.Items[0].Type)
+	require.Equal(t, "hits", list.Items[0].Name)
+
+	status, body, err := GetRequest(server.URL + "/ns/?prefix=ns-stats")
+	require.Nil(t, err)
+	require.Equal(t, 200, status)
+	var namespaces struct {
+		Items []namespaceStats `json:"items"`
+	}
+	require.Nil(t, json.Unmarshal([]byte(body), &namespaces))
+	require.Len(t, namespaces.Items, 1)
+	require.Equal(t, "ns-stats", namespaces.Items[0].Name)
+	require.Equal(t, 3, namespaces.Items[0].Objects)
+
+	status, _, err = DeleteRequest(server.URL + "/ns/ns-stats")
+	require.Nil(t, err)
+	require.Equal(t, 204, status)
+	require.Equal(t, 0, getNamespaceStats(t, "ns-stats").Objects)
+
+	status, _, err = DeleteRequest(server.URL + "/ns/ns-stats")
+	require.Nil(t, err)
+	require.Equal(t, 404, status)
+}
+
+func TestNamespaceMaxObjects(t *testing.T) {
+	viper.Set("namespaceMaxObjects", 2)
+	defer viper.Set("namespaceMaxObjects", 0)
+	defer DeleteRequest(server.URL + "/ns/ns-full")
+
+	for _, name := range []string{"a", "b"} {
+		status, _, err := GetRequest(server.URL + "/ns/ns-full/counter/" + name + "/count")
+		require.Nil(t, err)
+		require.Equal(t, 200, status)
+	}
+
+	status, _, err := GetRequest(server.URL + "/ns/ns-full/counter/c/count")
+	require.Nil(t, err)
+	require.Equal(t, 429, status)
+
+	// existing objects are still usable, and reads don't count
+	status, _, err = GetRequest(server.URL + "/ns/ns-full/counter/a/count")
+	require.Nil(t, err)
+	require.Equal(t, 200, status)
+
+	status, _, err = GetRequest(server.URL + "/ns/ns-full/counter/c/stats")
+	require.Nil(t, err)
+	require.Equal(t, 404, status)
+
+	require.Equal(t, 2, getNamespaceStats(t, "ns-full").MaxObjects)
+}
@@ -0,0 +1,433 @@
+package bouncermain
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Rate limiter algorithms
+const (
+	algoGCRA    = "gcra"
+	algoSliding = "sliding"
+)
+
+// maxSlidingRate bounds the memory used by a sliding window, which keeps
+// the time of every request in it.
+const maxSlidingRate = 10000
+
+// maxBurstPeriods bounds how many periods' worth of requests a GCRA burst
+// can let through at once.
+const maxBurstPeriods = 1000
+
+type RateLimiterStats struct {
+	Acquired        uint64  `json:"acquired"`
+	TotalWaitTime   uint64  `json:"total_wait_time"`
+	TimedOut        uint64  `json:"timed_out"`
+	Canceled        uint64  `json:"canceled"`
+	CreatedAt       string  `json:"created_at"`
+	AverageWaitTime float64 `json:"average_wait_time"`
+}
+
+type RateLimiterConfig struct {
+	Algorithm string `json:"algo"`
+	Rate      uint64 `json:"rate"`
+	Per       int64  `json:"per"` // milliseconds
+	Burst     uint64 `json:"burst,omitempty"`
+}
+
+// rateAlgorithm decides when requests can go ahead. Methods are called with
+// the limiter mutex held.
+type rateAlgorithm interface {
+	// take admits a request at now, or returns how long until one could be
+	// admitted.
+	take(now time.Time) (wait time.Duration)
+	// idle reports whether past requests no longer limit new ones.
+	idle(now time.Time) bool
+}
+
+// gcra is the generic cell rate algorithm. Requests are spaced by an
+// emission interval, with up to burst of them allowed at once.
+type gcra struct {
+	interval  time.Duration // between requests at the steady rate
+	tolerance time.Duration // how far ahead of schedule a request can be
+	tat       time.Time     // theoretical arrival time of the next request
+}
+
+func newGCRA(rate uint64, per time.Duration, burst uint64) *gcra {
+	interval := per / time.Duration(rate)
+	return &gcra{interval: interval, tolerance: interval * time.Duration(max(burst, 1)-1)}
+}
+
+func (g *gcra) take(now time.Time) time.Duration {
+	tat := g.tat
+	if tat.Before(now) {
+		tat = now
+	}
+
+	if allowed := tat.Add(-g.tolerance); allowed.After(now) {
+		return allowed.Sub(now)
+	}
+
+	g.tat = tat.Add(g.interval)
+	return 0
+}
+
+func (g *gcra) idle(now time.Time) bool {
+	return !g.tat.After(now)
+}
+
+// slidingWindow admits up to rate requests in any window of length per. It
+// keeps the time of each request in the window in a ring buffer.
+type slidingWindow struct {
+	per   time.Duration
+	times []time.Time
+	head  int // oldest request
+	count int
+}
+
+func newSlidingWindow(rate uint64, per time.Duration) *slidingWindow {
+	return &slidingWindow{per: per, times: make([]time.Time, rate)}
+}
+
+func (s *slidingWindow) expire(now time.Time) {
+	for s.count > 0 && !s.times[s.head].Add(s.per).After(now) {
+		s.head = (s.head + 1) % len(s.times)
+		s.count--
+	}
+}
+
+func (s *slidingWindow) take(now time.Time) time.Duration {
+	s.expire(now)
+
+	if s.count == len(s.times) {
+		return s.times[s.head].Add(s.per).Sub(now)
+	}
+
+	s.times[(s.head+s.count)%len(s.times)] = now
+	s.count++
+	return 0
+}
+
+func (s *slidingWindow) idle(now time.Time) bool {
+	s.expire(now)
+	return s.count == 0
+}
+
+type RateLimiter struct {
+	Name     string
+	config   RateLimiterConfig
+	algo     rateAlgorithm
+	mu       sync.Mutex // protects config, algo and declared
+	declared bool       // configured explicitly, so acquires don't change it
+	Stats    *RateLimiterStats
+	waiters  int64
+	idleTracker
+}
+
+// rateLimiterDefaults are used for the settings not given on creation.
+var rateLimiterDefaults = RateLimiterConfig{Algorithm: algoGCRA, Rate: 1, Per: 1000, Burst: 1}
+
+var rateLimiters = map[string]*RateLimiter{}
+var rateLimitersMutex = &sync.RWMutex{}
+
+// normalize fills in defaults and checks the configuration.
+func (config *RateLimiterConfig) normalize() error {
+	if config.Rate == 0 || config.Per <= 0 || config.Per > math.MaxInt64/int64(time.Millisecond) {
+		return ErrInvalidRate
+	}
+
+	switch config.Algorithm {
+	case algoGCRA:
+		per := time.Duration(config.Per) * time.Millisecond
+		if per < time.Duration(config.Rate) {
+			// less than a nanosecond between requests
+			return ErrInvalidRate
+		}
+		config.Burst = max(config.Burst, 1)
+
+		// burst at most maxBurstPeriods times rate, without overflowing, and
+		// a tolerance of interval times burst-1 that fits a Duration
+		interval := per / time.Duration(config.Rate)
+		extra := config.Burst - 1
+		if extra/maxBurstPeriods >= config.Rate || extra > uint64(math.MaxInt64/interval) {
+			return ErrInvalidBurst
+		}
+	case algoSliding:
+		if config.Rate > maxSlidingRate {
+			return ErrInvalidRate
+		}
+		// the whole window is the burst
+		config.Burst = 0
+	default:
+		return ErrInvalidAlgo
+	}
+	return nil
+}
+
+// merge returns the configuration with the settings given in other, the
+// non-zero ones, replaced.
+func (config RateLimiterConfig) merge(other RateLimiterConfig) RateLimiterConfig {
+	config.Algorithm = cmp.Or(other.Algorithm, config.Algorithm)
+	config.Rate = cmp.Or(other.Rate, config.Rate)
+	config.Per = cmp.Or(other.Per, config.Per)
+	config.Burst = cmp.Or(other.Burst, config.Burst)
+	return config
+}
+
+func newRateAlgorithm(config RateLimiterConfig) rateAlgorithm {
+	per := time.Duration(config.Per) * time.Millisecond
+	if config.Algorithm == algoSliding {
+		return newSlidingWindow(config.Rate, per)
+	}
+	return newGCRA(config.Rate, per, config.Burst)
+}
+
+func newRateLimiter(name string, config RateLimiterConfig) *RateLimiter {
+	limiter := &RateLimiter{
+		Name:   name,
+		config: config,
+		algo:   newRateAlgorithm(config),
+		Stats:  &RateLimiterStats{CreatedAt: time.Now().Format(time.RFC3339)},
+	}
+
+	limiter.touch()
+	rateLimiters[name] = limiter
+	return limiter
+}
+
+// getRateLimiter returns the limiter, created on first use with the
+// configured settings, or those given over the defaults. Zero values in
+// config aren't given.
+func getRateLimiter(name string, config RateLimiterConfig) (*RateLimiter, error) {
+	// check the settings before anything is created with them
+	defaults := rateLimiterDefaults.merge(config)
+	if err := defaults.normalize(); err != nil {
+		return nil, err
+	}
+
+	rateLimitersMutex.RLock()
+	limiter, ok := rateLimiters[name]
+	if ok {
+		limiter.touch()
+	}
+	rateLimitersMutex.RUnlock()
+
+	if ok {
+		return limiter, limiter.reconfigure(config)
+	}
+
+	preset, configured := configuredRateLimiter(name)
+	if strictMode && !configured {
+		return nil, ErrNotFound
+	}
+
+	rateLimitersMutex.Lock()
+	defer rateLimitersMutex.Unlock()
+
+	// Check again in case another goroutine created it
+	limiter, ok = rateLimiters[name]
+	if ok {
+		limiter.touch()
+		return limiter, limiter.reconfigure(config)
+	}
+
+	if !configured {
+		return newRateLimiter(name, defaults), nil
+	}
+
+	limiter = newRateLimiter(name, preset)
+	limiter.declared = true
+	return limiter, limiter.reconfigure(config)
+}
+
+// reconfigure switches to the settings given in an acquire, with the history
+// of past requests cleared, unless the limiter was configured explicitly.
+func (limiter *RateLimiter) reconfigure(given RateLimiterConfig) error {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	config := limiter.config.merge(given)
+	if err := config.normalize(); err != nil {
+		return err
+	}
+
+	if config == limiter.config {
+		return nil
+	}
+
+	if limiter.declared {
+		return paramsConflict("ratelimit", limiter.Name, config, limiter.config)
+	}
+
+	log.Debug().
+		Str("name", limiter.Name).
+		Interface("current", limiter.config).
+		Interface("new", config).
+		Msg("ratelimit reconfigured")
+
+	limiter.config = config
+	limiter.algo = newRateAlgorithm(config)
+	return nil
+}
+
+// configureRateLimiter creates or reconfigures a rate limiter. Acquires no
+// longer change its configuration.
+func configureRateLimiter(name string, body []byte) (created bool, err error) {
+	config := rateLimiterDefaults
+	if err = decodeConfig(body, &config); err != nil {
+		return false, err
+	}
+	if err = config.normalize(); err != nil {
+		return false, err
+	}
+
+	return declareRateLimiter(name, config), nil
+}
+
+func declareRateLimiter(name string, config RateLimiterConfig) (created bool) {
+	rateLimitersMutex.Lock()
+	limiter, ok := rateLimiters[name]
+	if ok {
+		limiter.touch()
+	} else {
+		limiter = newRateLimiter(name, config)
+	}
+	rateLimitersMutex.Unlock()
+
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	if config != limiter.config {
+		limiter.config = config
+		limiter.algo = newRateAlgorithm(config)
+	}
+	limiter.declared = true
+	return !ok
+}
+
+// undeclareRateLimiter lets acquires change the rate limiter again, once
+// it's no longer in the config file.
+func undeclareRateLimiter(name string) {
+	rateLimitersMutex.RLock()
+	limiter, ok := rateLimiters[name]
+	rateLimitersMutex.RUnlock()
+	if !ok {
+		return
+	}
+
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	limiter.declared = false
+}
+
+func (limiter *RateLimiter) Acquire(ctx context.Context, maxwait time.Duration, arrival time.Time) error {
+	deadline := time.Now().Add(maxwait)
+
+	atomic.AddInt64(&limiter.waiters, 1)
+	defer atomic.AddInt64(&limiter.waiters, -1)
+
+	for {
+		// don't take a slot nobody will use
+		if ctx.Err() != nil {
+			atomic.AddUint64(&limiter.Stats.Canceled, 1)
+			return ErrCanceled
+		}
+
+		now := time.Now()
+		limiter.mu.Lock()
+		wait := limiter.algo.take(now)
+		limiter.mu.Unlock()
+
+		if wait <= 0 {
+			atomic.AddUint64(&limiter.Stats.Acquired, 1)
+			atomic.AddUint64(&limiter.Stats.TotalWaitTime, uint64(time.Since(arrival)/time.Millisecond))
+			observeWait("ratelimit", limiter.Name, time.Since(arrival))
+			return nil
+		}
+
+		// the wait only gets longer, so don't hold the client if it's
+		// already past maxwait
+		if maxwait >= 0 && now.Add(wait).After(deadline) {
+			atomic.AddUint64(&limiter.Stats.TimedOut, 1)
+			return ErrTimedOut
+		}
+
+		if err := sleep(ctx, min(wait, maxSleepDuration.Get())); err != nil {
+			countGiveUp(err, &limiter.Stats.TimedOut, &limiter.Stats.Canceled)
+			return err
+		}
+	}
+}
+
+// busy reports whether the limiter has waiters or recent requests.
+// Evicting it then would let requests through early.
+func (limiter *RateLimiter) busy() bool {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	return atomic.LoadInt64(&limiter.waiters) > 0 || !limiter.algo.idle(time.Now())
+}
+
+func deleteRateLimiter(name string) error {
+	rateLimitersMutex.Lock()
+	defer rateLimitersMutex.Unlock()
+
+	return removeRateLimiter(name)
+}
+
+// removeRateLimiter must be called with rateLimitersMutex held.
+func removeRateLimiter(name string) error {
+	_, ok := rateLimiters[name]
+	if !ok {
+		return ErrNotFound
+	}
+
+	delete(rateLimiters, name)
+	forgetMetrics("ratelimit", name)
+	return nil
+}
+
+func getRateLimiterConfig(name string) (interface{}, error) {
+	rateLimitersMutex.RLock()
+	limiter, ok := rateLimiters[name]
+	rateLimitersMutex.RUnlock()
+
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	config := limiter.config
+	return &config, nil
+}
+
+func getRateLimiterStats(name string) (interface{}, error) {
+	rateLimitersMutex.RLock()
+	limiter, ok := rateLimiters[name]
+	rateLimitersMutex.RUnlock()
+
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	stats := &RateLimiterStats{
+		Acquired:      atomic.LoadUint64(&limiter.Stats.Acquired),
+		TotalWaitTime: atomic.LoadUint64(&limiter.Stats.TotalWaitTime),
+		TimedOut:      atomic.LoadUint64(&limiter.Stats.TimedOut),
+		Canceled:      atomic.LoadUint64(&limiter.Stats.Canceled),
+		CreatedAt:     limiter.Stats.CreatedAt,
+	}
+	if stats.Acquired > 0 {
+		stats.AverageWaitTime = float64(stats.TotalWaitTime) / float64(stats.Acquired)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,170 @@
+package bouncermain
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type RateLimitAcquireRequest struct {
+	Algorithm string        `schema:"algo"`
+	Rate      uint64        `schema:"rate"`
+	Per       time.Duration `schema:"per"`
+	Burst     uint64        `schema:"burst"`
+	MaxWait   time.Duration `schema:"maxwait"`
+	Arrival   time.Time     `schema:"-"`
+	ID        string        `schema:"id"`
+}
+
+func newRateLimitAcquireRequest() *RateLimitAcquireRequest {
+	// zero settings are taken from the limiter configuration
+	return &RateLimitAcquireRequest{
+		MaxWait: -1,
+		Arrival: time.Now(),
+		ID:      "",
+	}
+}
+
+func (r *RateLimitAcquireRequest) Decode(values url.Values) error {
+	if err := decoder.Decode(r, values); err != nil {
+		return err
+	}
+	if r.Rate == 0 && values.Has("rate") || r.Per <= 0 && values.Has("per") {
+		return ErrInvalidRate
+	}
+	return nil
+}
+
+func (r *RateLimitAcquireRequest) config() RateLimiterConfig {
+	return RateLimiterConfig{
+		Algorithm: r.Algorithm,
+		Rate:      r.Rate,
+		Per:       r.Per.Milliseconds(),
+		Burst:     r.Burst,
+	}
+}
+
+// RateLimitAcquireHandler godoc
+// @Summary Acquire from a GCRA or sliding-window rate limiter
+// @description.markdown ratelimit_acquire.md
+// @Tags RateLimit
+// @Produce plain
+// @Param name path string true "Rate limiter name"
+// @Param algo query string false "Algorithm, gcra or sliding" default(gcra)
+// @Param rate query int false "Requests allowed per period" default(1)
+// @Param per query int false "Period" default(1000)
+// @Param burst query int false "Requests allowed at once, for gcra, at most 1000 times rate" default(1)
+// @Param maxwait query int false "Maximum wait time" default(-1)
+// @Param id query string false "Optional request identifier for logging"
+// @Success 204 {string} Reply "Request allowed"
+// @Failure 400 {string} Reply "Bad Request - invalid parameters"
+// @Failure 408 {string} Reply "Request Timeout - `maxwait` exceeded"
+// @Failure 409 {string} Reply "Conflict - parameters differ from the configured ones"
+// @Failure 503 {string} Reply "Service Unavailable - server is shutting down"
+// @Router /ratelimit/{name}/acquire [get]
+func RateLimitAcquireHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var err error
+	var limiter *RateLimiter
+	var wait time.Duration = 0
+
+	req := newRateLimitAcquireRequest()
+	rep := newReply()
+
+	err = req.Decode(r.URL.Query())
+	if err == nil {
+		limiter, err = getRateLimiter(ps[0].Value, req.config())
+	}
+
+	if err == nil {
+		start := time.Now()
+		err = limiter.Acquire(r.Context(), req.MaxWait, req.Arrival)
+		wait = time.Since(start)
+
+		if err == nil {
+			rep.Status = http.StatusNoContent
+		} else if errors.Is(err, ErrTimedOut) {
+			rep.Status = http.StatusRequestTimeout
+		}
+	}
+
+	rep.WriteResponse(w, r, err)
+	logRequest(r, rep.Status, "ratelimit", "acquire", ps[0].Value, wait, req).Send()
+}
+
+// RateLimitConfigureHandler godoc
+// @Summary Create or reconfigure a rate limiter
+// @description.markdown ratelimit_configure.md
+// @Tags RateLimit
+// @Accept json
+// @Produce json
+// @Param name path string true "Rate limiter name"
+// @Param config body RateLimiterConfig false "Rate limiter configuration"
+// @Success 200 {object} RateLimiterConfig "Rate limiter reconfigured"
+// @Success 201 {object} RateLimiterConfig "Rate limiter created"
+// @Failure 400 {string} Reply "Bad Request - invalid configuration"
+// @Router /ratelimit/{name} [put]
+func RateLimitConfigureHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	status := ConfigureHandler(w, r, ps, configureRateLimiter, getRateLimiterConfig)
+	logRequest(r, status, "ratelimit", "configure", ps[0].Value, 0, nil).Send()
+}
+
+// RateLimitConfigHandler godoc
+// @Summary Get rate limiter configuration
+// @Description Get the current configuration of a rate limiter
+// @Tags RateLimit
+// @Produce json
+// @Param name path string true "Rate limiter name"
+// @Success 200 {object} RateLimiterConfig "Rate limiter configuration"
+// @Failure 404 {string} Reply "Not Found - rate limiter not found"
+// @Router /ratelimit/{name} [get]
+func RateLimitConfigHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	status := ConfigHandler(w, r, ps, getRateLimiterConfig)
+	logRequest(r, status, "ratelimit", "config", ps[0].Value, 0, nil).Send()
+}
+
+// RateLimitDeleteHandler godoc
+// @Summary Delete a rate limiter
+// @Description Remove a rate limiter
+// @Tags RateLimit
+// @Produce plain
+// @Param name path string true "Rate limiter name"
+// @Success 204 "Rate limiter deleted successfully"
+// @Failure 404 {string} Reply "Not Found - rate limiter not found"
+// @Router /ratelimit/{name} [delete]
+func RateLimitDeleteHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	status := DeleteHandler(w, r, ps, deleteRateLimiter)
+	logRequest(r, status, "ratelimit", "delete", ps[0].Value, 0, nil).Send()
+}
+
+// RateLimitStatsHandler godoc
+// @Summary View rate limiter stats
+// @Description Get rate limiter statistics
+// @Tags RateLimit
+// @Produce json
+// @Param name path string true "Rate limiter name"
+// @Success 200 {object} RateLimiterStats "Rate limiter statistics"
+// @Failure 404 {string} Reply "Not Found - rate limiter not found"
+// @Router /ratelimit/{name}/stats [get]
+func RateLimitStatsHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	status := StatsHandler(w, r, ps, getRateLimiterStats)
+	logRequest(r, status, "ratelimit", "stats", ps[0].Value, 0, nil).Send()
+}
+
+// RateLimitListHandler godoc
+// @Summary List rate limiters
+// @Description List existing rate limiters with their configuration and stats, ordered by name
+// @Tags RateLimit
+// @Produce json
+// @Param prefix query string false "Only list names starting with prefix"
+// @Param limit query int false "Maximum number of items" default(100)
+// @Param after query string false "Only list names after this one, as returned in 'next'"
+// @Success 200 {object} ResourceList "Rate limiters"
+// @Failure 400 {string} Reply "Bad Request - invalid parameters"
+// @Router /ratelimit/ [get]
+func RateLimitListHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	status := ListHandler(w, r, "ratelimit")
+	logRequest(r, status, "ratelimit", "list", "", 0, nil).Send()
+}
@@ -0,0 +1,100 @@
+package bouncermain_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pjwerneck/bouncer/bouncermain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitGCRABurstThenSpacing(t *testing.T) {
+	url := fmt.Sprintf("%s/ratelimit/gcra1/acquire?algo=gcra&rate=10&per=1000&burst=5&maxwait=0", server.URL)
+
+	// the burst goes through at once
+	for i := 0; i < 5; i++ {
+		status, _, err := GetRequest(url)
+		require.Nil(t, err)
+		require.Equal(t, 204, status)
+	}
+
+	status, _, err := GetRequest(url)
+	require.Nil(t, err)
+	require.Equal(t, 408, status)
+
+	// then one request every 100ms
+	time.Sleep(120 * time.Millisecond)
+
+	status, _, err = GetRequest(url)
+	require.Nil(t, err)
+	require.Equal(t, 204, status)
+
+	status, _, err = GetRequest(url)
+	require.Nil(t, err)
+	require.Equal(t, 408, status)
+}
+
+func TestRateLimitGCRAWaitsForSlot(t *testing.T) {
+	url := fmt.Sprintf("%s/ratelimit/gcra2/acquire?rate=10&per=1000&maxwait=500", server.URL)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		status, _, err := GetRequest(url)
+		require.Nil(t, err)
+		require.Equal(t, 204, status)
+	}
+
+	// requests are spaced by 100ms with no burst
+	require.GreaterOrEqual(t, time.Since(start), 190*time.Millisecond)
+}
+
+func TestRateLimitSlidingWindow(t *testing.T) {
+	url := fmt.Sprintf("%s/ratelimit/sliding1/acquire?algo=sliding&rate=3&per=300&maxwait=0", server.URL)
+
+	for i := 0; i < 3; i++ {
+		status, _, err := GetRequest(url)
+		require.Nil(t, err)
+		require.Equal(t, 204, status)
+	}
+
+	status, _, err := GetRequest(url)
+	require.Nil(t, err)
+	require.Equal(t, 408, status)
+
+	// the window slides past the first requests
+	time.Sleep(350 * time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		status, _, err := GetRequest(url)
+		require.Nil(t, err)
+		require.Equal(t, 204, status)
+	}
+
+	status, body, err := GetRequest(fmt.Sprintf("%s/ratelimit/sliding1/stats", server.URL))
+	require.Nil(t, err)
+	require.Equal(t, 200, status)
+	require.Contains(t, body, `"acquired":6`)
+	require.Contains(t, body, `"timed_out":1`)
+}
+
+func TestRateLimitInvalidParameters(t *testing.T) {
+	for _, query := range []string{"algo=leaky", "rate=0", "per=0", "algo=sliding&rate=20000"} {
+		status, _, err := GetRequest(fmt.Sprintf("%s/ratelimit/invalid/acquire?%s", server.URL, query))
+		require.Nil(t, err)
+		require.Equal(t, 400, status, query)
+	}
+}
+
+func TestRateLimitBurstBounded(t *testing.T) {
+	for _, query := range []string{"rate=1&per=1000&burst=10000000000", "rate=1&per=1000&burst=1001", "rate=1000000&per=86400000&burst=18446744073709551615"} {
+		status, body, err := GetRequest(fmt.Sprintf("%s/ratelimit/burst/acquire?%s", server.URL, query))
+		require.Nil(t, err)
+		require.Equal(t, 400, status, query)
+		require.Equal(t, bouncermain.ErrInvalidBurst.Error(), body, query)
+	}
+
+	status, _, err := GetRequest(fmt.Sprintf("%s/ratelimit/burst/acquire?rate=1&per=1000&burst=1000", server.URL))
+	require.Nil(t, err)
+	require.Equal(t, 204, status)
+}
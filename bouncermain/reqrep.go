@@ -5,6 +5,10 @@ import (
 	"net/http"
 )
 
+// StatusClientClosedRequest is logged for requests abandoned by the client.
+// Nobody reads the reply, but it's not a server error either.
+const StatusClientClosedRequest = 499
+
 type Reply struct {
 	Body   string
 	Status int
@@ -16,22 +20,41 @@ func newReply() Reply {
 	}
 }
 
+// errorStatus returns the HTTP status for an error.
+func errorStatus(err error) int {
+	// Simple switch on error type, no additional branching needed
+	switch {
+	case errors.Is(err, ErrTimedOut):
+		return http.StatusRequestTimeout
+	case errors.Is(err, ErrKeyError),
+		errors.Is(err, ErrBarrierClosed),
+		errors.Is(err, ErrEventClosed),
+		errors.Is(err, ErrStaleToken),
+		errors.Is(err, ErrConfigConflict):
+		return http.StatusConflict
+	case errors.Is(err, ErrCanceled):
+		return StatusClientClosedRequest
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, ErrTooManyObjects):
+		return http.StatusTooManyRequests
+	case errors.Is(err, ErrNoLeader),
+		errors.Is(err, ErrNotLeader),
+		errors.Is(err, ErrShuttingDown):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusBadRequest
+	}
+}
+
 func (rep *Reply) WriteResponse(w http.ResponseWriter, r *http.Request, err error) {
 	if err != nil {
 		rep.Body = err.Error()
-		// Simple switch on error type, no additional branching needed
-		switch {
-		case errors.Is(err, ErrTimedOut):
-			rep.Status = http.StatusRequestTimeout
-		case errors.Is(err, ErrKeyError),
-			errors.Is(err, ErrBarrierClosed),
-			errors.Is(err, ErrEventClosed):
-			rep.Status = http.StatusConflict
-		case errors.Is(err, ErrNotFound):
-			rep.Status = http.StatusNotFound
-		default:
-			rep.Status = http.StatusBadRequest
-		}
+		rep.Status = errorStatus(err)
 	}
 	// These can move outside the if block since they always happen
 	w.WriteHeader(rep.Status)
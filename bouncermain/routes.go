@@ -6,39 +6,83 @@ import (
 )
 
 func Router() *httprouter.Router {
-	r := httprouter.New()
+	router := httprouter.New()
+	r := instrumentedRouter{router}
 
 	// Swagger documentation endpoint
-	r.Handler("GET", "/docs/*any", httpSwagger.Handler(
+	router.Handler("GET", "/docs/*any", httpSwagger.Handler(
 		httpSwagger.URL("/docs/doc.json"), // The url pointing to API definition
 	))
 
 	r.DELETE("/barrier/:name", BarrierDeleteHandler)
 	r.DELETE("/counter/:name", CounterDeleteHandler)
 	r.DELETE("/event/:name", EventDeleteHandler)
+	r.DELETE("/ns/:namespace", NamespaceDeleteHandler)
+	r.DELETE("/ratelimit/:name", RateLimitDeleteHandler)
+	r.DELETE("/rwlock/:name", RWLockDeleteHandler)
 	r.DELETE("/semaphore/:name", SemaphoreDeleteHandler)
 	r.DELETE("/tokenbucket/:name", TokenBucketDeleteHandler)
 	r.DELETE("/watchdog/:name", WatchdogDeleteHandler)
 	r.GET("/.well-known/ready", WellKnownReady)
+	r.GET("/barrier/", BarrierListHandler)
+	r.GET("/barrier/:name", BarrierConfigHandler)
 	r.GET("/barrier/:name/stats", BarrierStatsHandler)
 	r.GET("/barrier/:name/wait", BarrierWaitHandler)
+	r.GET("/cluster/status", ClusterStatusHandler)
+	r.GET("/counter/", CounterListHandler)
+	r.GET("/counter/:name", CounterConfigHandler)
 	r.GET("/counter/:name/count", CounterCountHandler)
 	r.GET("/counter/:name/reset", CounterResetHandler)
 	r.GET("/counter/:name/stats", CounterStatsHandler)
 	r.GET("/counter/:name/value", CounterValueHandler)
+	r.GET("/event/", EventListHandler)
+	r.GET("/event/:name", EventConfigHandler)
 	r.GET("/event/:name/send", EventSendHandler)
 	r.GET("/event/:name/stats", EventStatsHandler)
 	r.GET("/event/:name/wait", EventWaitHandler)
+	r.GET("/janitor/stats", JanitorStatsHandler)
+	r.GET("/metrics", MetricsHandler)
+	r.GET("/ns/", NamespaceListHandler)
+	r.GET("/ns/:namespace/stats", NamespaceStatsHandler)
+	r.GET("/ratelimit/", RateLimitListHandler)
+	r.GET("/ratelimit/:name", RateLimitConfigHandler)
+	r.GET("/ratelimit/:name/acquire", RateLimitAcquireHandler)
+	r.GET("/ratelimit/:name/stats", RateLimitStatsHandler)
+	r.GET("/resources", ResourcesHandler)
+	r.GET("/rwlock/", RWLockListHandler)
+	r.GET("/rwlock/:name", RWLockConfigHandler)
+	r.GET("/rwlock/:name/lock", RWLockLockHandler)
+	r.GET("/rwlock/:name/rlock", RWLockRLockHandler)
+	r.GET("/rwlock/:name/stats", RWLockStatsHandler)
+	r.GET("/rwlock/:name/unlock", RWLockUnlockHandler)
+	r.GET("/rwlock/:name/validate", RWLockValidateHandler)
+	r.GET("/semaphore/", SemaphoreListHandler)
+	r.GET("/semaphore/:name", SemaphoreConfigHandler)
 	r.GET("/semaphore/:name/acquire", SemaphoreAcquireHandler)
 	r.GET("/semaphore/:name/release", SemaphoreReleaseHandler)
+	r.GET("/semaphore/:name/renew", SemaphoreRenewHandler)
 	r.GET("/semaphore/:name/stats", SemaphoreStatsHandler)
+	r.GET("/semaphore/:name/validate", SemaphoreValidateHandler)
+	r.GET("/stream", StreamHandler)
+	r.GET("/stream/sse", StreamSSEHandler)
+	r.GET("/tokenbucket/", TokenBucketListHandler)
+	r.GET("/tokenbucket/:name", TokenBucketConfigHandler)
 	r.GET("/tokenbucket/:name/acquire", TokenBucketAcquireHandler)
+	r.GET("/tokenbucket/:name/check", TokenBucketCheckHandler)
 	r.GET("/tokenbucket/:name/stats", TokenBucketStatsHandler)
+	r.GET("/watchdog/", WatchdogListHandler)
+	r.GET("/watchdog/:name", WatchdogConfigHandler)
 	r.GET("/watchdog/:name/kick", WatchdogKickHandler)
 	r.GET("/watchdog/:name/stats", WatchdogStatsHandler)
 	r.GET("/watchdog/:name/wait", WatchdogWaitHandler)
+	r.PUT("/barrier/:name", BarrierConfigureHandler)
+	r.PUT("/counter/:name", CounterConfigureHandler)
+	r.PUT("/event/:name", EventConfigureHandler)
+	r.PUT("/ratelimit/:name", RateLimitConfigureHandler)
+	r.PUT("/rwlock/:name", RWLockConfigureHandler)
+	r.PUT("/semaphore/:name", SemaphoreConfigureHandler)
+	r.PUT("/tokenbucket/:name", TokenBucketConfigureHandler)
+	r.PUT("/watchdog/:name", WatchdogConfigureHandler)
 
-	// Add DELETE endpoints
-
-	return r
+	return router
 }
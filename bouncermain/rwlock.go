@@ -0,0 +1,505 @@
+package bouncermain
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+type RWLockStats struct {
+	ReadAcquired    uint64  `json:"read_acquired"`
+	WriteAcquired   uint64  `json:"write_acquired"`
+	Reacquired      uint64  `json:"reacquired"`
+	Released        uint64  `json:"released"`
+	Expired         uint64  `json:"expired"`
+	TotalWaitTime   uint64  `json:"total_wait_time"`
+	AverageWaitTime float64 `json:"average_wait_time"`
+	TimedOut        uint64  `json:"timed_out"`
+	Canceled        uint64  `json:"canceled"`
+	MaxEverReaders  uint64  `json:"max_ever_readers"`
+	CreatedAt       string  `json:"created_at"`
+}
+
+type RWLockConfig struct {
+	Readers int  `json:"readers"`
+	Writer  bool `json:"writer"`
+}
+
+// RWLock is held by any number of readers or by a single writer. Waiters
+// get the lock in arrival order, so readers queue behind a waiting writer
+// instead of starving it.
+type RWLock struct {
+	Name      string
+	Keys      map[string]bool // key -> held for writing
+	queue     *list.List      // of *rwlockWaiter, in arrival order
+	timers    map[string]*time.Timer
+	deadlines map[string]time.Time
+	writer    bool
+	mu        *sync.RWMutex
+	Stats     *RWLockStats
+	fencing   // protected by mu
+	idleTracker
+}
+
+type rwlockWaiter struct {
+	key     string
+	write   bool
+	expires time.Duration
+	readyC  chan struct{}
+	granted bool   // protected by the lock mutex
+	token   uint64 // fencing token, set when granted
+}
+
+var rwlocks = map[string]*RWLock{}
+var rwlocksMutex = &sync.RWMutex{}
+
+func newRWLock(name string) *RWLock {
+	rwlock := &RWLock{
+		Name:      name,
+		Keys:      make(map[string]bool),
+		queue:     list.New(),
+		timers:    make(map[string]*time.Timer),
+		deadlines: make(map[string]time.Time),
+		mu:        &sync.RWMutex{},
+		Stats:     &RWLockStats{CreatedAt: time.Now().Format(time.RFC3339)},
+	}
+
+	rwlock.counter = rwlockFence
+	rwlock.touch()
+	rwlocks[name] = rwlock
+
+	return rwlock
+}
+
+func getRWLock(name string) (*RWLock, error) {
+	rwlocksMutex.RLock()
+	rwlock, ok := rwlocks[name]
+	if ok {
+		rwlock.touch()
+	}
+	rwlocksMutex.RUnlock()
+
+	if ok {
+		return rwlock, nil
+	}
+
+	if strictMode {
+		return nil, ErrNotFound
+	}
+
+	rwlocksMutex.Lock()
+	defer rwlocksMutex.Unlock()
+
+	// Check again in case another goroutine created it
+	rwlock, ok = rwlocks[name]
+	if ok {
+		rwlock.touch()
+		return rwlock, nil
+	}
+
+	return newRWLock(name), nil
+}
+
+// canTake reports whether the lock is free for a reader or a writer,
+// ignoring the queue. Must be called with the lock mutex held.
+func (rwlock *RWLock) canTake(write bool) bool {
+	if write {
+		return len(rwlock.Keys) == 0
+	}
+	return !rwlock.writer
+}
+
+// take adds a key holding the lock. Must be called with the lock mutex held.
+func (rwlock *RWLock) take(key string, write bool, expires time.Duration) {
+	rwlock.addKey(key, write, expires, rwlock.next())
+
+	if write {
+		return
+	}
+
+	current := uint64(len(rwlock.Keys))
+	for {
+		max := atomic.LoadUint64(&rwlock.Stats.MaxEverReaders)
+		if current <= max || atomic.CompareAndSwapUint64(&rwlock.Stats.MaxEverReaders, max, current) {
+			break
+		}
+	}
+}
+
+// wakeWaiters hands the lock to queued waiters in arrival order: either the
+// writer at the front, or every reader up to the next writer. Must be called
+// with the lock mutex held.
+func (rwlock *RWLock) wakeWaiters() {
+	for rwlock.queue.Len() > 0 {
+		front := rwlock.queue.Front()
+		waiter := front.Value.(*rwlockWaiter)
+
+		if !rwlock.canTake(waiter.write) {
+			return
+		}
+
+		rwlock.take(waiter.key, waiter.write, waiter.expires)
+		rwlock.queue.Remove(front)
+		waiter.granted = true
+		waiter.token = rwlock.tokens[waiter.key]
+		close(waiter.readyC)
+	}
+}
+
+// addKey registers a key and its expiration timer. Must be called with the
+// lock mutex held.
+func (rwlock *RWLock) addKey(key string, write bool, expires time.Duration, token uint64) {
+	rec := stateRecord{Op: opRWLockSetKey, Type: "rwlock", Name: rwlock.Name, Key: key, Write: write, Token: token}
+
+	rwlock.Keys[key] = write
+	rwlock.hold(key, token)
+	if write {
+		rwlock.writer = true
+	}
+
+	if expires > 0 {
+		deadline := time.Now().Add(expires)
+		rwlock.deadlines[key] = deadline
+		rwlock.timers[key] = time.AfterFunc(expires,
+			func() {
+				log.Debug().Msgf("rwlock expired: name=%v, key=%v", rwlock.Name, key)
+				if rwlock.delKey(key) == nil {
+					atomic.AddUint64(&rwlock.Stats.Expired, 1)
+				}
+			})
+		rec.Expires = deadline.UnixNano()
+	}
+
+	recordChange(rec)
+}
+
+func (rwlock *RWLock) delKey(key string) error {
+	rwlock.mu.Lock()
+	defer rwlock.mu.Unlock()
+
+	if _, ok := rwlock.Keys[key]; !ok {
+		return ErrKeyError
+	}
+
+	if t, ok := rwlock.timers[key]; ok {
+		t.Stop()
+		delete(rwlock.timers, key)
+	}
+	delete(rwlock.deadlines, key)
+
+	if rwlock.Keys[key] {
+		rwlock.writer = false
+	}
+	delete(rwlock.Keys, key)
+	rwlock.drop(key)
+
+	recordChange(stateRecord{Op: opRWLockDelKey, Type: "rwlock", Name: rwlock.Name, Key: key})
+	rwlock.wakeWaiters()
+	return nil
+}
+
+// Acquire waits up to maxwait for the lock, for writing if write is set,
+// and returns the key to release it with and its fencing token.
+func (rwlock *RWLock) Acquire(ctx context.Context, write bool, maxwait time.Duration, expires time.Duration, key string) (string, uint64, error) {
+	// generate a random uuid as key if not provided
+	if key == "" {
+		key = uuid.Must(uuid.NewV4()).String()
+	}
+
+	started := time.Now()
+	rwlock.mu.Lock()
+
+	// a key already holding the lock in the same mode reacquires it
+	if held, ok := rwlock.Keys[key]; ok {
+		token := rwlock.tokens[key]
+		rwlock.mu.Unlock()
+		if held != write {
+			return "", 0, ErrKeyError
+		}
+		atomic.AddUint64(&rwlock.Stats.Reacquired, 1)
+		return key, token, nil
+	}
+
+	// readers don't overtake queued writers
+	if rwlock.queue.Len() == 0 && rwlock.canTake(write) {
+		rwlock.take(key, write, expires)
+		token := rwlock.tokens[key]
+		rwlock.mu.Unlock()
+		rwlock.acquired(write, started)
+		return key, token, nil
+	}
+
+	if maxwait == 0 {
+		rwlock.mu.Unlock()
+		return "", 0, rwlock.timedOut(maxwait)
+	}
+
+	waiter := &rwlockWaiter{key: key, write: write, expires: expires, readyC: make(chan struct{})}
+	elem := rwlock.queue.PushBack(waiter)
+	rwlock.mu.Unlock()
+
+	var timeoutC <-chan time.Time
+	if maxwait > 0 {
+		timer := time.NewTimer(maxwait)
+		defer timer.Stop()
+		timeoutC = timer.C
+	}
+
+	select {
+	case <-waiter.readyC:
+	case <-timeoutC:
+		// the lock may have been handed over as the timer fired
+		if !rwlock.dequeue(waiter, elem) {
+			return "", 0, rwlock.timedOut(maxwait)
+		}
+	case <-ctx.Done():
+		// nobody will get the key, so give back a lock handed over meanwhile
+		if rwlock.dequeue(waiter, elem) {
+			rwlock.delKey(key)
+		}
+		atomic.AddUint64(&rwlock.Stats.Canceled, 1)
+		return "", 0, ErrCanceled
+	case <-shuttingDown:
+		if !rwlock.dequeue(waiter, elem) {
+			return "", 0, ErrShuttingDown
+		}
+	}
+
+	rwlock.acquired(write, started)
+	return key, waiter.token, nil
+}
+
+// dequeue removes a waiter that gave up, and reports whether it was granted
+// the lock in the meantime. Readers queued behind a writer that gave up may
+// be able to go ahead.
+func (rwlock *RWLock) dequeue(waiter *rwlockWaiter, elem *list.Element) (granted bool) {
+	rwlock.mu.Lock()
+	defer rwlock.mu.Unlock()
+
+	if !waiter.granted {
+		rwlock.queue.Remove(elem)
+		rwlock.wakeWaiters()
+	}
+	return waiter.granted
+}
+
+func (rwlock *RWLock) acquired(write bool, started time.Time) {
+	if write {
+		atomic.AddUint64(&rwlock.Stats.WriteAcquired, 1)
+	} else {
+		atomic.AddUint64(&rwlock.Stats.ReadAcquired, 1)
+	}
+	wait := uint64(time.Since(started) / time.Millisecond)
+	atomic.AddUint64(&rwlock.Stats.TotalWaitTime, wait)
+	observeWait("rwlock", rwlock.Name, time.Since(started))
+}
+
+func (rwlock *RWLock) timedOut(maxwait time.Duration) error {
+	atomic.AddUint64(&rwlock.Stats.TimedOut, 1)
+	log.Debug().Msgf("rwlock acquire timed out: name=%v, maxwait=%v", rwlock.Name, maxwait)
+	return ErrTimedOut
+}
+
+func (rwlock *RWLock) Release(key string) error {
+	err := rwlock.delKey(key)
+	if err == nil {
+		atomic.AddUint64(&rwlock.Stats.Released, 1)
+	}
+	return err
+}
+
+func getRWLockStats(name string) (interface{}, error) {
+	rwlocksMutex.RLock()
+	defer rwlocksMutex.RUnlock()
+
+	rwlock, ok := rwlocks[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	stats := &RWLockStats{
+		ReadAcquired:   atomic.LoadUint64(&rwlock.Stats.ReadAcquired),
+		WriteAcquired:  atomic.LoadUint64(&rwlock.Stats.WriteAcquired),
+		Reacquired:     atomic.LoadUint64(&rwlock.Stats.Reacquired),
+		Released:       atomic.LoadUint64(&rwlock.Stats.Released),
+		Expired:        atomic.LoadUint64(&rwlock.Stats.Expired),
+		TotalWaitTime:  atomic.LoadUint64(&rwlock.Stats.TotalWaitTime),
+		TimedOut:       atomic.LoadUint64(&rwlock.Stats.TimedOut),
+		Canceled:       atomic.LoadUint64(&rwlock.Stats.Canceled),
+		MaxEverReaders: atomic.LoadUint64(&rwlock.Stats.MaxEverReaders),
+		CreatedAt:      rwlock.Stats.CreatedAt,
+	}
+	acquired := stats.ReadAcquired + stats.WriteAcquired
+	if acquired > 0 {
+		stats.AverageWaitTime = float64(stats.TotalWaitTime) / float64(acquired)
+	}
+	return stats, nil
+}
+
+func getRWLockConfig(name string) (interface{}, error) {
+	rwlocksMutex.RLock()
+	rwlock, ok := rwlocks[name]
+	rwlocksMutex.RUnlock()
+
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	rwlock.mu.RLock()
+	defer rwlock.mu.RUnlock()
+
+	config := &RWLockConfig{Writer: rwlock.writer}
+	if !rwlock.writer {
+		config.Readers = len(rwlock.Keys)
+	}
+	return config, nil
+}
+
+func (rwlock *RWLock) busy() bool {
+	rwlock.mu.RLock()
+	defer rwlock.mu.RUnlock()
+
+	return len(rwlock.Keys) > 0 || rwlock.queue.Len() > 0
+}
+
+func deleteRWLock(name string) error {
+	rwlocksMutex.Lock()
+	defer rwlocksMutex.Unlock()
+
+	return removeRWLock(name)
+}
+
+// removeRWLock must be called with rwlocksMutex held.
+func removeRWLock(name string) error {
+	rwlock, ok := rwlocks[name]
+	if !ok {
+		return ErrNotFound
+	}
+
+	rwlock.mu.Lock()
+	defer rwlock.mu.Unlock()
+
+	for _, timer := range rwlock.timers {
+		timer.Stop()
+	}
+
+	delete(rwlocks, name)
+	forgetMetrics("rwlock", name)
+	recordChange(stateRecord{Op: opDelete, Type: "rwlock", Name: name})
+	return nil
+}
+
+// configureRWLock creates a read-write lock. Locks have no settings, and
+// their config only reports the current holders.
+func configureRWLock(name string, body []byte) (created bool, err error) {
+	if err = decodeConfig(body, &struct{}{}); err != nil {
+		return false, err
+	}
+
+	rwlocksMutex.Lock()
+	defer rwlocksMutex.Unlock()
+
+	rwlock, ok := rwlocks[name]
+	if ok {
+		rwlock.touch()
+	} else {
+		newRWLock(name)
+	}
+	return !ok, nil
+}
+
+func restoreRWLock(rec stateRecord) {
+	rwlocksMutex.Lock()
+	rwlock, ok := rwlocks[rec.Name]
+	if !ok && rec.Op == opRWLockSetKey {
+		rwlock = newRWLock(rec.Name)
+	}
+	rwlocksMutex.Unlock()
+
+	if rwlock == nil {
+		return
+	}
+
+	if rec.Op == opRWLockDelKey {
+		rwlock.delKey(rec.Key)
+		return
+	}
+
+	rwlock.mu.Lock()
+	defer rwlock.mu.Unlock()
+
+	rwlockFence.raise(rec.Token)
+	if _, ok := rwlock.Keys[rec.Key]; ok {
+		return
+	}
+
+	var expires time.Duration
+	if rec.Expires > 0 {
+		expires = time.Until(time.Unix(0, rec.Expires))
+		if expires <= 0 {
+			// expired while we were down
+			return
+		}
+	}
+
+	token := rec.Token
+	if token == 0 {
+		// recorded before fencing tokens
+		token = rwlock.next()
+	}
+	rwlock.addKey(rec.Key, rec.Write, expires, token)
+}
+
+func captureRWLocks() []stateRecord {
+	rwlocksMutex.RLock()
+	defer rwlocksMutex.RUnlock()
+
+	records := []stateRecord{}
+	for name, rwlock := range rwlocks {
+		rwlock.mu.RLock()
+		for key, write := range rwlock.Keys {
+			rec := stateRecord{Op: opRWLockSetKey, Type: "rwlock", Name: name, Key: key, Write: write, Token: rwlock.tokens[key]}
+			if deadline, ok := rwlock.deadlines[key]; ok {
+				rec.Expires = deadline.UnixNano()
+			}
+			records = append(records, rec)
+		}
+		rwlock.mu.RUnlock()
+	}
+	return records
+}
+
+// Validate checks that a fencing token belongs to a current holder, and to
+// key if given.
+func (rwlock *RWLock) Validate(key string, token uint64) error {
+	rwlock.mu.RLock()
+	defer rwlock.mu.RUnlock()
+
+	return rwlock.validate(key, token)
+}
+
+func validateRWLockToken(name string, key string, token uint64) error {
+	rwlock, err := findRWLock(name)
+	if err != nil {
+		return err
+	}
+	return rwlock.Validate(key, token)
+}
+
+// findRWLock returns an existing read-write lock, without creating it.
+func findRWLock(name string) (*RWLock, error) {
+	rwlocksMutex.RLock()
+	defer rwlocksMutex.RUnlock()
+
+	rwlock, ok := rwlocks[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	rwlock.touch()
+	return rwlock, nil
+}
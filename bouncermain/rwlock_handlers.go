@@ -0,0 +1,252 @@
+package bouncermain
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type RWLockAcquireRequest struct {
+	MaxWait time.Duration `schema:"maxWait"`
+	Expires time.Duration `schema:"expires"`
+	ID      string        `schema:"id"`
+}
+
+func newRWLockAcquireRequest() *RWLockAcquireRequest {
+	return &RWLockAcquireRequest{
+		MaxWait: -1,
+		Expires: time.Minute,
+		ID:      "",
+	}
+}
+
+func (r *RWLockAcquireRequest) Decode(values url.Values) error {
+	return decoder.Decode(r, values)
+}
+
+type RWLockUnlockRequest struct {
+	Key string `schema:"key"`
+	ID  string `schema:"id"`
+}
+
+func newRWLockUnlockRequest() *RWLockUnlockRequest {
+	return &RWLockUnlockRequest{
+		ID: "",
+	}
+}
+
+func (r *RWLockUnlockRequest) Decode(values url.Values) error {
+	return decoder.Decode(r, values)
+}
+
+// RWLockRLockHandler godoc
+// @Summary Acquire a read lock
+// @description.markdown rwlock_rlock.md
+// @Tags RWLock
+// @Produce plain
+// @Param name path string true "Lock name"
+// @Param maxwait query int false "Maximum wait time" default(-1)
+// @Param expires query int false "Expiration time" default(60000)
+// @Param id query string false "Optional request identifier for logging"
+// @Success 200 {string} Reply "The lock release key"
+// @Header 200 {integer} X-Fencing-Token "Fencing token of the key"
+// @Failure 400 {string} Reply "Bad Request - invalid parameters"
+// @Failure 408 {string} Reply "Request Timeout - `maxWait` exceeded"
+// @Failure 503 {string} Reply "Service Unavailable - server is shutting down"
+// @Router /rwlock/{name}/rlock [get]
+func RWLockRLockHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	rwlockAcquire(w, r, ps, false)
+}
+
+// RWLockLockHandler godoc
+// @Summary Acquire a write lock
+// @description.markdown rwlock_lock.md
+// @Tags RWLock
+// @Produce plain
+// @Param name path string true "Lock name"
+// @Param maxwait query int false "Maximum wait time" default(-1)
+// @Param expires query int false "Expiration time" default(60000)
+// @Param id query string false "Optional request identifier for logging"
+// @Success 200 {string} Reply "The lock release key"
+// @Header 200 {integer} X-Fencing-Token "Fencing token of the key"
+// @Failure 400 {string} Reply "Bad Request - invalid parameters"
+// @Failure 408 {string} Reply "Request Timeout - `maxWait` exceeded"
+// @Failure 503 {string} Reply "Service Unavailable - server is shutting down"
+// @Router /rwlock/{name}/lock [get]
+func RWLockLockHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	rwlockAcquire(w, r, ps, true)
+}
+
+func rwlockAcquire(w http.ResponseWriter, r *http.Request, ps httprouter.Params, write bool) {
+	var err error
+	var rwlock *RWLock
+	var wait time.Duration = 0
+
+	req := newRWLockAcquireRequest()
+	rep := newReply()
+
+	err = req.Decode(r.URL.Query())
+	if err == nil {
+		rwlock, err = getRWLock(ps[0].Value)
+	}
+
+	if err == nil {
+		start := time.Now()
+		var token uint64
+		rep.Body, token, err = rwlock.Acquire(r.Context(), write, req.MaxWait, req.Expires, "")
+		wait = time.Since(start)
+
+		if errors.Is(err, ErrTimedOut) {
+			rep.Status = http.StatusRequestTimeout
+		} else if err == nil {
+			w.Header().Set(FencingTokenHeader, strconv.FormatUint(token, 10))
+			rep.Status = http.StatusOK
+		}
+
+	}
+
+	action := "rlock"
+	if write {
+		action = "lock"
+	}
+
+	rep.WriteResponse(w, r, err)
+	logRequest(r, rep.Status, "rwlock", action, ps[0].Value, wait, req).Send()
+}
+
+// RWLockUnlockHandler godoc
+// @Summary Release a read or write lock
+// @description.markdown rwlock_unlock.md
+// @Tags RWLock
+// @Produce plain
+// @Param name path string true "Lock name"
+// @Param key query string true "Release key"
+// @Param id query string false "Optional request identifier for logging"
+// @Success 204 "Lock released successfully"
+// @Failure 400 {string} Reply "Bad Request - invalid parameters"
+// @Failure 409 {string} Reply "Conflict - key is invalid or already released"
+// @Router /rwlock/{name}/unlock [get]
+func RWLockUnlockHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var err error
+	var rwlock *RWLock
+
+	req := newRWLockUnlockRequest()
+	rep := newReply()
+
+	err = req.Decode(r.URL.Query())
+	if err == nil {
+		rwlock, err = getRWLock(ps[0].Value)
+	}
+
+	if err == nil {
+		err = rwlock.Release(req.Key)
+
+		if errors.Is(err, ErrKeyError) {
+			rep.Status = http.StatusConflict
+		} else if err == nil {
+			rep.Status = http.StatusNoContent
+		}
+
+	}
+
+	rep.WriteResponse(w, r, err)
+	logRequest(r, rep.Status, "rwlock", "unlock", ps[0].Value, 0, req).Send()
+}
+
+// RWLockValidateHandler godoc
+// @Summary Validate a fencing token
+// @description.markdown rwlock_validate.md
+// @Tags RWLock
+// @Produce plain
+// @Param name path string true "Lock name"
+// @Param token query int true "Fencing token"
+// @Param key query string false "Release key the token was issued with"
+// @Param id query string false "Optional request identifier for logging"
+// @Success 204 "Token is held"
+// @Failure 400 {string} Reply "Bad Request - invalid parameters"
+// @Failure 404 {string} Reply "Not Found - lock not found"
+// @Failure 409 {string} Reply "Conflict - token is stale"
+// @Router /rwlock/{name}/validate [get]
+func RWLockValidateHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	status, req := ValidateHandler(w, r, ps, validateRWLockToken)
+	logRequest(r, status, "rwlock", "validate", ps[0].Value, 0, req).Send()
+}
+
+// RWLockConfigureHandler godoc
+// @Summary Create or reconfigure a read-write lock
+// @Description Create a read-write lock explicitly, as required in strict mode. Locks have no settings, so the body is empty or `{}`
+// @Tags RWLock
+// @Accept json
+// @Produce json
+// @Param name path string true "Lock name"
+// @Param config body RWLockConfig false "Lock configuration"
+// @Success 200 {object} RWLockConfig "Lock reconfigured"
+// @Success 201 {object} RWLockConfig "Lock created"
+// @Failure 400 {string} Reply "Bad Request - invalid configuration"
+// @Router /rwlock/{name} [put]
+func RWLockConfigureHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	status := ConfigureHandler(w, r, ps, configureRWLock, getRWLockConfig)
+	logRequest(r, status, "rwlock", "configure", ps[0].Value, 0, nil).Send()
+}
+
+// RWLockConfigHandler godoc
+// @Summary Get read-write lock configuration
+// @Description Get the current configuration of a read-write lock
+// @Tags RWLock
+// @Produce json
+// @Param name path string true "Lock name"
+// @Success 200 {object} RWLockConfig "Lock configuration"
+// @Failure 404 {string} Reply "Not Found - lock not found"
+// @Router /rwlock/{name} [get]
+func RWLockConfigHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	status := ConfigHandler(w, r, ps, getRWLockConfig)
+	logRequest(r, status, "rwlock", "config", ps[0].Value, 0, nil).Send()
+}
+
+// RWLockDeleteHandler godoc
+// @Summary Delete a read-write lock
+// @Description Remove a read-write lock
+// @Tags RWLock
+// @Produce plain
+// @Param name path string true "Lock name"
+// @Success 204 "Lock deleted successfully"
+// @Failure 404 {string} Reply "Not Found - lock not found"
+// @Router /rwlock/{name} [delete]
+func RWLockDeleteHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	status := DeleteHandler(w, r, ps, deleteRWLock)
+	logRequest(r, status, "rwlock", "delete", ps[0].Value, 0, nil).Send()
+}
+
+// RWLockStatsHandler godoc
+// @Summary Get read-write lock statistics
+// @Description Get current statistics for the read-write lock
+// @Tags RWLock
+// @Produce json
+// @Param name path string true "Lock name"
+// @Success 200 {object} RWLockStats "Lock statistics"
+// @Failure 404 {string} Reply "Not Found - lock not found"
+// @Router /rwlock/{name}/stats [get]
+func RWLockStatsHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	status := StatsHandler(w, r, ps, getRWLockStats)
+	logRequest(r, status, "rwlock", "stats", ps[0].Value, 0, nil).Send()
+}
+
+// RWLockListHandler godoc
+// @Summary List read-write locks
+// @Description List existing read-write locks with their holders and stats, ordered by name
+// @Tags RWLock
+// @Produce json
+// @Param prefix query string false "Only list names starting with prefix"
+// @Param limit query int false "Maximum number of items" default(100)
+// @Param after query string false "Only list names after this one, as returned in 'next'"
+// @Success 200 {object} ResourceList "Read-write locks"
+// @Failure 400 {string} Reply "Bad Request - invalid parameters"
+// @Router /rwlock/ [get]
+func RWLockListHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	status := ListHandler(w, r, "rwlock")
+	logRequest(r, status, "rwlock", "list", "", 0, nil).Send()
+}
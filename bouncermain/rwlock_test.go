@@ -0,0 +1,96 @@
+package bouncermain_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRWLockReadersShareWriterExcludes(t *testing.T) {
+	baseURL := fmt.Sprintf("%s/rwlock/share-test", server.URL)
+
+	status, r1, err := GetRequest(baseURL + "/rlock?maxwait=0")
+	require.Nil(t, err)
+	require.Equal(t, 200, status)
+
+	status, r2, err := GetRequest(baseURL + "/rlock?maxwait=0")
+	require.Nil(t, err)
+	require.Equal(t, 200, status)
+
+	// the writer waits for both readers
+	status, _, err = GetRequest(baseURL + "/lock?maxwait=100")
+	require.Nil(t, err)
+	require.Equal(t, 408, status)
+
+	for _, key := range []string{r1, r2} {
+		status, _, err = GetRequest(fmt.Sprintf("%s/unlock?key=%s", baseURL, key))
+		require.Nil(t, err)
+		require.Equal(t, 204, status)
+	}
+
+	status, w, err := GetRequest(baseURL + "/lock?maxwait=0")
+	require.Nil(t, err)
+	require.Equal(t, 200, status)
+
+	// and readers wait for the writer
+	status, _, err = GetRequest(baseURL + "/rlock?maxwait=0")
+	require.Nil(t, err)
+	require.Equal(t, 408, status)
+
+	status, _, err = GetRequest(fmt.Sprintf("%s/unlock?key=%s", baseURL, w))
+	require.Nil(t, err)
+	require.Equal(t, 204, status)
+
+	status, _, err = GetRequest(fmt.Sprintf("%s/unlock?key=%s", baseURL, w))
+	require.Nil(t, err)
+	require.Equal(t, 409, status)
+}
+
+func TestRWLockReadersDontStarveWriter(t *testing.T) {
+	baseURL := fmt.Sprintf("%s/rwlock/starve-test", server.URL)
+
+	status, reader, err := GetRequest(baseURL + "/rlock")
+	require.Nil(t, err)
+	require.Equal(t, 200, status)
+
+	writerC := make(chan string, 1)
+	go func() {
+		_, key, _ := GetRequest(baseURL + "/lock?maxwait=5000")
+		writerC <- key
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// a reader arriving after the writer queues behind it
+	status, _, err = GetRequest(baseURL + "/rlock?maxwait=100")
+	require.Nil(t, err)
+	require.Equal(t, 408, status)
+
+	status, _, err = GetRequest(fmt.Sprintf("%s/unlock?key=%s", baseURL, reader))
+	require.Nil(t, err)
+	require.Equal(t, 204, status)
+
+	select {
+	case key := <-writerC:
+		require.NotEmpty(t, key)
+	case <-time.After(time.Second):
+		t.Fatal("writer not woken by the last reader")
+	}
+}
+
+func TestRWLockDelete(t *testing.T) {
+	baseURL := fmt.Sprintf("%s/rwlock/delete-test", server.URL)
+
+	status, _, err := GetRequest(baseURL + "/rlock")
+	require.Nil(t, err)
+	require.Equal(t, 200, status)
+
+	status, _, err = DeleteRequest(baseURL)
+	require.Nil(t, err)
+	require.Equal(t, 204, status)
+
+	status, _, err = DeleteRequest(baseURL)
+	require.Nil(t, err)
+	require.Equal(t, 404, status)
+}
@@ -1,6 +1,9 @@
 package bouncermain
 
 import (
+	"cmp"
+	"container/list"
+	"context"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -14,21 +17,42 @@ type SemaphoreStats struct {
 	Reacquired      uint64  `json:"reacquired"`
 	Released        uint64  `json:"released"`
 	Expired         uint64  `json:"expired"`
+	Renewed         uint64  `json:"renewed"`
 	TotalWaitTime   uint64  `json:"total_wait_time"`
 	AverageWaitTime float64 `json:"average_wait_time"`
 	TimedOut        uint64  `json:"timed_out"`
+	Canceled        uint64  `json:"canceled"`
 	MaxEverHeld     uint64  `json:"max_ever_held"`
 	CreatedAt       string  `json:"created_at"`
 }
 
+type SemaphoreConfig struct {
+	Size uint64 `json:"size"`
+}
+
 type Semaphore struct {
-	Name     string
-	Size     uint64
-	Keys     map[string]time.Duration
-	acquireC chan bool
-	timers   map[string]*time.Timer
-	mu       *sync.RWMutex
-	Stats    *SemaphoreStats
+	Name      string
+	Size      uint64
+	Keys      map[string]time.Duration
+	queue     *list.List // of *semaphoreWaiter, in arrival order
+	timers    map[string]*time.Timer
+	deadlines map[string]time.Time
+	mu        *sync.RWMutex
+	Stats     *SemaphoreStats
+	declared  bool // configured explicitly, so acquires don't resize it
+	fencing        // protected by mu
+	idleTracker
+}
+
+// semaphoreWaiter is a queued acquire. Slots are handed to waiters by
+// wakeWaiters, so a late arrival can't take a slot released for them.
+type semaphoreWaiter struct {
+	key     string
+	expires time.Duration
+	readyC  chan struct{}
+	granted bool   // protected by the semaphore mutex
+	shared  bool   // granted a slot already held under its key
+	token   uint64 // fencing token, set when granted
 }
 
 var semaphores = map[string]*Semaphore{}
@@ -36,44 +60,40 @@ var semaphoresMutex = &sync.RWMutex{}
 
 func newSemaphore(name string, size uint64) (semaphore *Semaphore) {
 	semaphore = &Semaphore{
-		Name:     name,
-		Size:     uint64(size),
-		Keys:     make(map[string]time.Duration),
-		acquireC: make(chan bool, size),
-		timers:   make(map[string]*time.Timer),
-		mu:       &sync.RWMutex{},
-		Stats:    &SemaphoreStats{CreatedAt: time.Now().Format(time.RFC3339)},
+		Name:      name,
+		Size:      uint64(size),
+		Keys:      make(map[string]time.Duration),
+		queue:     list.New(),
+		timers:    make(map[string]*time.Timer),
+		deadlines: make(map[string]time.Time),
+		mu:        &sync.RWMutex{},
+		Stats:     &SemaphoreStats{CreatedAt: time.Now().Format(time.RFC3339)},
 	}
 
+	semaphore.counter = semaphoreFence
+	semaphore.touch()
 	semaphores[name] = semaphore
 
 	return semaphore
 }
 
+// getSemaphore returns the semaphore, created on first use with the
+// configured size, or the size given, or 1. A size of 0 isn't given.
 func getSemaphore(name string, size uint64) (semaphore *Semaphore, err error) {
 	semaphoresMutex.RLock()
 	semaphore, ok := semaphores[name]
+	if ok {
+		semaphore.touch()
+	}
 	semaphoresMutex.RUnlock()
 
 	if ok {
-		// Check if we need to update settings
-		semaphore.mu.RLock()
-		currentSize := semaphore.Size
-		semaphore.mu.RUnlock()
-
-		if size != currentSize {
-			log.Warn().
-				Str("name", name).
-				Uint64("current_size", currentSize).
-				Uint64("new_size", size).
-				Msg("semaphore size modification through acquire is deprecated and will be removed in a future version")
-
-			semaphore.mu.Lock()
-			semaphore.Size = size
-			semaphore.mu.Unlock()
-		}
+		return semaphore, semaphore.resize(size)
+	}
 
-		return semaphore, nil
+	config, configured := configuredSemaphore(name)
+	if strictMode && !configured {
+		return nil, ErrNotFound
 	}
 
 	// Semaphore doesn't exist, need to create it
@@ -83,11 +103,46 @@ func getSemaphore(name string, size uint64) (semaphore *Semaphore, err error) {
 	// Check again in case another goroutine created it
 	semaphore, ok = semaphores[name]
 	if ok {
+		semaphore.touch()
 		return semaphore, nil
 	}
 
-	semaphore = newSemaphore(name, size)
-	return semaphore, nil
+	if !configured {
+		return newSemaphore(name, cmp.Or(size, 1)), nil
+	}
+
+	semaphore = newSemaphore(name, config.Size)
+	semaphore.declared = true
+	return semaphore, semaphore.resize(size)
+}
+
+// resize changes the size as given in an acquire, unless the semaphore was
+// configured explicitly.
+func (semaphore *Semaphore) resize(size uint64) error {
+	if size == 0 {
+		return nil
+	}
+
+	semaphore.mu.Lock()
+	defer semaphore.mu.Unlock()
+
+	if size == semaphore.Size {
+		return nil
+	}
+
+	if semaphore.declared {
+		return paramsConflict("semaphore", semaphore.Name, SemaphoreConfig{Size: size}, SemaphoreConfig{Size: semaphore.Size})
+	}
+
+	log.Warn().
+		Str("name", semaphore.Name).
+		Uint64("current_size", semaphore.Size).
+		Uint64("new_size", size).
+		Msg("semaphore size modification through acquire is deprecated and will be removed in a future version")
+
+	semaphore.Size = size
+	semaphore.wakeWaiters()
+	return nil
 }
 
 func (semaphore *Semaphore) getKey(key string) (expires time.Duration, ok bool) {
@@ -97,23 +152,14 @@ func (semaphore *Semaphore) getKey(key string) (expires time.Duration, ok bool)
 	return
 }
 
-func (semaphore *Semaphore) setKey(key string, expires time.Duration) bool {
-	semaphore.mu.Lock()
-	defer semaphore.mu.Unlock()
-
+// takeSlot adds a key if there's a free slot. Must be called with the
+// semaphore mutex held.
+func (semaphore *Semaphore) takeSlot(key string, expires time.Duration) bool {
 	if int(semaphore.Size)-len(semaphore.Keys) <= 0 {
 		return false
 	}
 
-	semaphore.Keys[key] = expires
-	if expires > 0 {
-		semaphore.timers[key] = time.AfterFunc(expires,
-			func() {
-				log.Debug().Msgf("semaphore expired: name=%v, key=%v", semaphore.Name, key)
-				semaphore.delKey(key)
-				atomic.AddUint64(&semaphore.Stats.Expired, 1)
-			})
-	}
+	semaphore.addKey(key, expires, semaphore.next())
 
 	// Update max ever held while holding the mutex
 	current := uint64(len(semaphore.Keys))
@@ -127,67 +173,209 @@ func (semaphore *Semaphore) setKey(key string, expires time.Duration) bool {
 	return true
 }
 
-func (semaphore *Semaphore) delKey(key string) error {
-	semaphore.mu.Lock()
-	defer semaphore.mu.Unlock()
+// wakeWaiters hands free slots to queued waiters in arrival order. Must be
+// called with the semaphore mutex held.
+func (semaphore *Semaphore) wakeWaiters() {
+	for semaphore.queue.Len() > 0 {
+		front := semaphore.queue.Front()
+		waiter := front.Value.(*semaphoreWaiter)
+
+		// a waiter for a key that's already held shares its slot
+		_, held := semaphore.Keys[waiter.key]
+		if !held && !semaphore.takeSlot(waiter.key, waiter.expires) {
+			return
+		}
+
+		semaphore.queue.Remove(front)
+		waiter.granted = true
+		waiter.shared = held
+		waiter.token = semaphore.tokens[waiter.key]
+		close(waiter.readyC)
+	}
+}
+
+// addKey registers a key and its expiration timer. Must be called with the
+// semaphore mutex held.
+func (semaphore *Semaphore) addKey(key string, expires time.Duration, token uint64) {
+	rec := stateRecord{Op: opSemaphoreSetKey, Type: "semaphore", Name: semaphore.Name, Key: key, Size: semaphore.Size, Token: token}
+
+	semaphore.Keys[key] = expires
+	semaphore.hold(key, token)
+	rec.Expires = semaphore.setExpiry(key, expires)
+
+	recordChange(rec)
+}
 
+// setExpiry (re)starts the expiration timer of a key, and returns its
+// deadline in unix nanoseconds, or 0 if it doesn't expire. Must be called
+// with the semaphore mutex held.
+func (semaphore *Semaphore) setExpiry(key string, expires time.Duration) int64 {
 	if t, ok := semaphore.timers[key]; ok {
 		t.Stop()
 		delete(semaphore.timers, key)
 	}
+	delete(semaphore.deadlines, key)
+
+	if expires <= 0 {
+		return 0
+	}
+
+	deadline := time.Now().Add(expires)
+	semaphore.deadlines[key] = deadline
+	semaphore.timers[key] = time.AfterFunc(expires, func() { semaphore.expireKey(key) })
+	return deadline.UnixNano()
+}
+
+// expireKey removes a key whose deadline passed. The timer of a renewed key
+// may fire anyway if it was already running, so the deadline is checked
+// again here.
+func (semaphore *Semaphore) expireKey(key string) {
+	semaphore.mu.Lock()
+	defer semaphore.mu.Unlock()
+
+	deadline, ok := semaphore.deadlines[key]
+	if !ok || time.Now().Before(deadline) {
+		return
+	}
+
+	log.Debug().Msgf("semaphore expired: name=%v, key=%v", semaphore.Name, key)
+	if semaphore.removeKey(key) == nil {
+		atomic.AddUint64(&semaphore.Stats.Expired, 1)
+	}
+}
+
+// Renew resets the expiration of a held key to expires from now. Returns
+// ErrKeyError if the key was already released or expired.
+func (semaphore *Semaphore) Renew(key string, expires time.Duration) error {
+	semaphore.mu.Lock()
+	defer semaphore.mu.Unlock()
+
+	if _, ok := semaphore.Keys[key]; !ok {
+		return ErrKeyError
+	}
+
+	semaphore.Keys[key] = expires
+	rec := stateRecord{Op: opSemaphoreSetKey, Type: "semaphore", Name: semaphore.Name, Key: key, Size: semaphore.Size, Token: semaphore.tokens[key]}
+	rec.Expires = semaphore.setExpiry(key, expires)
+	recordChange(rec)
+
+	atomic.AddUint64(&semaphore.Stats.Renewed, 1)
+	return nil
+}
+
+func (semaphore *Semaphore) delKey(key string) error {
+	semaphore.mu.Lock()
+	defer semaphore.mu.Unlock()
+
+	return semaphore.removeKey(key)
+}
+
+// removeKey must be called with the semaphore mutex held.
+func (semaphore *Semaphore) removeKey(key string) error {
+	semaphore.setExpiry(key, 0)
 
 	if _, ok := semaphore.Keys[key]; ok {
 		delete(semaphore.Keys, key)
+		semaphore.drop(key)
 	} else {
 		return ErrKeyError
 	}
+
+	recordChange(stateRecord{Op: opSemaphoreDelKey, Type: "semaphore", Name: semaphore.Name, Key: key})
+	semaphore.wakeWaiters()
 	return nil
 }
 
-func (semaphore *Semaphore) Acquire(maxwait time.Duration, expires time.Duration, key string) (token string, err error) {
+// Acquire waits up to maxwait for a slot, and returns the key to release it
+// with and its fencing token.
+func (semaphore *Semaphore) Acquire(ctx context.Context, maxwait time.Duration, expires time.Duration, key string) (string, uint64, error) {
 	// generate a random uuid as key if not provided
 	if key == "" {
 		key = uuid.Must(uuid.NewV4()).String()
 	}
 
-	// if there's an active token with this key, reacquire and return immediately
-	_, ok := semaphore.getKey(key)
+	started := time.Now()
+	semaphore.mu.Lock()
 
-	if ok {
-		token = key
+	// if there's an active token with this key, reacquire and return immediately
+	if _, ok := semaphore.Keys[key]; ok {
+		token := semaphore.tokens[key]
+		semaphore.mu.Unlock()
 		atomic.AddUint64(&semaphore.Stats.Reacquired, 1)
-		return token, nil
+		return key, token, nil
 	}
 
-	// otherwise, check for available slots
-	started := time.Now()
-	for {
+	// otherwise, take a free slot unless someone arrived first
+	if semaphore.queue.Len() == 0 && semaphore.takeSlot(key, expires) {
+		token := semaphore.tokens[key]
+		semaphore.mu.Unlock()
+		semaphore.acquired(started)
+		return key, token, nil
+	}
 
-		if semaphore.setKey(key, expires) {
-			token = key
-			atomic.AddUint64(&semaphore.Stats.Acquired, 1)
-			wait := uint64(time.Since(started) / time.Millisecond)
-			atomic.AddUint64(&semaphore.Stats.TotalWaitTime, wait)
+	if maxwait == 0 {
+		semaphore.mu.Unlock()
+		return "", 0, semaphore.timedOut(maxwait)
+	}
 
-			break
-		}
+	waiter := &semaphoreWaiter{key: key, expires: expires, readyC: make(chan struct{})}
+	elem := semaphore.queue.PushBack(waiter)
+	semaphore.mu.Unlock()
 
-		if maxwait == 0 {
-			atomic.AddUint64(&semaphore.Stats.TimedOut, 1)
-			log.Debug().Msgf("semaphore acquire timed out: name=%v, maxwait=%v", semaphore.Name, maxwait)
-			return "", ErrTimedOut
-		}
+	var timeoutC <-chan time.Time
+	if maxwait > 0 {
+		timer := time.NewTimer(maxwait)
+		defer timer.Stop()
+		timeoutC = timer.C
+	}
 
-		if maxwait > 0 && time.Since(started) >= maxwait {
-			atomic.AddUint64(&semaphore.Stats.TimedOut, 1)
-			log.Debug().Msgf("semaphore acquire timed out: name=%v, maxwait=%v", semaphore.Name, maxwait)
-			return "", ErrTimedOut
+	select {
+	case <-waiter.readyC:
+	case <-timeoutC:
+		// the slot may have been handed over as the timer fired
+		if !semaphore.dequeue(waiter, elem) {
+			return "", 0, semaphore.timedOut(maxwait)
+		}
+	case <-ctx.Done():
+		// nobody will get the key, so give back a slot handed over meanwhile
+		if semaphore.dequeue(waiter, elem) && !waiter.shared {
+			semaphore.delKey(key)
 		}
+		atomic.AddUint64(&semaphore.Stats.Canceled, 1)
+		return "", 0, ErrCanceled
+	case <-shuttingDown:
+		if !semaphore.dequeue(waiter, elem) {
+			return "", 0, ErrShuttingDown
+		}
+	}
+
+	semaphore.acquired(started)
+	return key, waiter.token, nil
+}
+
+// dequeue removes a waiter that gave up, and reports whether it was granted
+// a slot in the meantime.
+func (semaphore *Semaphore) dequeue(waiter *semaphoreWaiter, elem *list.Element) (granted bool) {
+	semaphore.mu.Lock()
+	defer semaphore.mu.Unlock()
 
-		time.Sleep(time.Duration(10) * time.Millisecond)
+	if !waiter.granted {
+		semaphore.queue.Remove(elem)
 	}
+	return waiter.granted
+}
+
+func (semaphore *Semaphore) acquired(started time.Time) {
+	atomic.AddUint64(&semaphore.Stats.Acquired, 1)
+	wait := uint64(time.Since(started) / time.Millisecond)
+	atomic.AddUint64(&semaphore.Stats.TotalWaitTime, wait)
+	observeWait("semaphore", semaphore.Name, time.Since(started))
+}
 
-	return token, nil
+func (semaphore *Semaphore) timedOut(maxwait time.Duration) error {
+	atomic.AddUint64(&semaphore.Stats.TimedOut, 1)
+	log.Debug().Msgf("semaphore acquire timed out: name=%v, maxwait=%v", semaphore.Name, maxwait)
+	return ErrTimedOut
 }
 
 func (semaphore *Semaphore) Release(key string) error {
@@ -209,20 +397,103 @@ func getSemaphoreStats(name string) (interface{}, error) {
 		return nil, ErrNotFound
 	}
 
-	stats := &SemaphoreStats{}
-	*stats = *semaphore.Stats
-	totalWait := atomic.LoadUint64(&semaphore.Stats.TotalWaitTime)
-	acquired := atomic.LoadUint64(&semaphore.Stats.Acquired)
-	if acquired > 0 {
-		stats.AverageWaitTime = float64(float64(totalWait) / float64(acquired))
+	stats := &SemaphoreStats{
+		Acquired:      atomic.LoadUint64(&semaphore.Stats.Acquired),
+		Reacquired:    atomic.LoadUint64(&semaphore.Stats.Reacquired),
+		Released:      atomic.LoadUint64(&semaphore.Stats.Released),
+		Expired:       atomic.LoadUint64(&semaphore.Stats.Expired),
+		Renewed:       atomic.LoadUint64(&semaphore.Stats.Renewed),
+		TotalWaitTime: atomic.LoadUint64(&semaphore.Stats.TotalWaitTime),
+		TimedOut:      atomic.LoadUint64(&semaphore.Stats.TimedOut),
+		Canceled:      atomic.LoadUint64(&semaphore.Stats.Canceled),
+		MaxEverHeld:   atomic.LoadUint64(&semaphore.Stats.MaxEverHeld),
+		CreatedAt:     semaphore.Stats.CreatedAt,
+	}
+	if stats.Acquired > 0 {
+		stats.AverageWaitTime = float64(stats.TotalWaitTime) / float64(stats.Acquired)
 	}
 	return stats, nil
 }
 
+func getSemaphoreConfig(name string) (interface{}, error) {
+	semaphoresMutex.RLock()
+	semaphore, ok := semaphores[name]
+	semaphoresMutex.RUnlock()
+
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	semaphore.mu.RLock()
+	defer semaphore.mu.RUnlock()
+
+	return &SemaphoreConfig{Size: semaphore.Size}, nil
+}
+
+// configureSemaphore creates or resizes a semaphore. Acquires no longer
+// change its size.
+func configureSemaphore(name string, body []byte) (created bool, err error) {
+	config := &SemaphoreConfig{Size: 1}
+	if err = decodeConfig(body, config); err != nil {
+		return false, err
+	}
+	if config.Size == 0 {
+		return false, ErrInvalidSize
+	}
+
+	return declareSemaphore(name, *config), nil
+}
+
+func declareSemaphore(name string, config SemaphoreConfig) (created bool) {
+	semaphoresMutex.Lock()
+	semaphore, ok := semaphores[name]
+	if ok {
+		semaphore.touch()
+	} else {
+		semaphore = newSemaphore(name, config.Size)
+	}
+	semaphoresMutex.Unlock()
+
+	semaphore.mu.Lock()
+	defer semaphore.mu.Unlock()
+
+	semaphore.Size = config.Size
+	semaphore.declared = true
+	semaphore.wakeWaiters()
+	return !ok
+}
+
+// undeclareSemaphore lets acquires change the semaphore again, once it's no
+// longer in the config file.
+func undeclareSemaphore(name string) {
+	semaphoresMutex.RLock()
+	semaphore, ok := semaphores[name]
+	semaphoresMutex.RUnlock()
+	if !ok {
+		return
+	}
+
+	semaphore.mu.Lock()
+	defer semaphore.mu.Unlock()
+	semaphore.declared = false
+}
+
+func (semaphore *Semaphore) busy() bool {
+	semaphore.mu.RLock()
+	defer semaphore.mu.RUnlock()
+
+	return len(semaphore.Keys) > 0 || semaphore.queue.Len() > 0
+}
+
 func deleteSemaphore(name string) error {
 	semaphoresMutex.Lock()
 	defer semaphoresMutex.Unlock()
 
+	return removeSemaphore(name)
+}
+
+// removeSemaphore must be called with semaphoresMutex held.
+func removeSemaphore(name string) error {
 	semaphore, ok := semaphores[name]
 	if !ok {
 		return ErrNotFound
@@ -237,5 +508,109 @@ func deleteSemaphore(name string) error {
 	}
 
 	delete(semaphores, name)
+	forgetMetrics("semaphore", name)
+	recordChange(stateRecord{Op: opDelete, Type: "semaphore", Name: name})
 	return nil
 }
+
+func restoreSemaphore(rec stateRecord) {
+	size := rec.Size
+	if size == 0 {
+		size = 1
+	}
+
+	semaphoresMutex.Lock()
+	semaphore, ok := semaphores[rec.Name]
+	if !ok && rec.Op == opSemaphoreSetKey {
+		semaphore = newSemaphore(rec.Name, size)
+	}
+	semaphoresMutex.Unlock()
+
+	if semaphore == nil {
+		return
+	}
+
+	if rec.Op == opSemaphoreDelKey {
+		semaphore.delKey(rec.Key)
+		return
+	}
+
+	semaphore.mu.Lock()
+	defer semaphore.mu.Unlock()
+
+	semaphore.Size = size
+	semaphoreFence.raise(rec.Token)
+
+	var expires time.Duration
+	if rec.Expires > 0 {
+		expires = time.Until(time.Unix(0, rec.Expires))
+		if expires <= 0 {
+			// expired while we were down
+			semaphore.removeKey(rec.Key)
+			return
+		}
+	}
+
+	// a renewal of a key we already have
+	if _, ok := semaphore.Keys[rec.Key]; ok {
+		semaphore.Keys[rec.Key] = expires
+		semaphore.setExpiry(rec.Key, expires)
+		return
+	}
+
+	token := rec.Token
+	if token == 0 {
+		// recorded before fencing tokens
+		token = semaphore.next()
+	}
+	semaphore.addKey(rec.Key, expires, token)
+}
+
+func captureSemaphores() []stateRecord {
+	semaphoresMutex.RLock()
+	defer semaphoresMutex.RUnlock()
+
+	records := []stateRecord{}
+	for name, semaphore := range semaphores {
+		semaphore.mu.RLock()
+		for key := range semaphore.Keys {
+			rec := stateRecord{Op: opSemaphoreSetKey, Type: "semaphore", Name: name, Key: key, Size: semaphore.Size, Token: semaphore.tokens[key]}
+			if deadline, ok := semaphore.deadlines[key]; ok {
+				rec.Expires = deadline.UnixNano()
+			}
+			records = append(records, rec)
+		}
+		semaphore.mu.RUnlock()
+	}
+	return records
+}
+
+// Validate checks that a fencing token belongs to a current holder, and to
+// key if given.
+func (semaphore *Semaphore) Validate(key string, token uint64) error {
+	semaphore.mu.RLock()
+	defer semaphore.mu.RUnlock()
+
+	return semaphore.validate(key, token)
+}
+
+func validateSemaphoreToken(name string, key string, token uint64) error {
+	semaphore, err := findSemaphore(name)
+	if err != nil {
+		return err
+	}
+	return semaphore.Validate(key, token)
+}
+
+// findSemaphore returns an existing semaphore, without creating it.
+func findSemaphore(name string) (*Semaphore, error) {
+	semaphoresMutex.RLock()
+	defer semaphoresMutex.RUnlock()
+
+	semaphore, ok := semaphores[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	semaphore.touch()
+	return semaphore, nil
+}
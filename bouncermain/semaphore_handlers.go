@@ -4,6 +4,7 @@ import (
 	"errors"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/julienschmidt/httprouter"
@@ -18,7 +19,7 @@ type SemaphoreAcquireRequest struct {
 
 func newSemaphoreAcquireRequest() *SemaphoreAcquireRequest {
 	return &SemaphoreAcquireRequest{
-		Size:    1,
+		Size:    0, // as configured
 		MaxWait: -1,
 		Expires: time.Minute,
 		ID:      "",
@@ -26,7 +27,13 @@ func newSemaphoreAcquireRequest() *SemaphoreAcquireRequest {
 }
 
 func (r *SemaphoreAcquireRequest) Decode(values url.Values) error {
-	return decoder.Decode(r, values)
+	if err := decoder.Decode(r, values); err != nil {
+		return err
+	}
+	if r.Size == 0 && values.Has("size") {
+		return ErrInvalidSize
+	}
+	return nil
 }
 
 type SemaphoreReleaseRequest struct {
@@ -44,6 +51,23 @@ func (r *SemaphoreReleaseRequest) Decode(values url.Values) error {
 	return decoder.Decode(r, values)
 }
 
+type SemaphoreRenewRequest struct {
+	Key     string        `schema:"key"`
+	Expires time.Duration `schema:"expires"`
+	ID      string        `schema:"id"`
+}
+
+func newSemaphoreRenewRequest() *SemaphoreRenewRequest {
+	return &SemaphoreRenewRequest{
+		Expires: time.Minute,
+		ID:      "",
+	}
+}
+
+func (r *SemaphoreRenewRequest) Decode(values url.Values) error {
+	return decoder.Decode(r, values)
+}
+
 // SemaphoreAcquireHandler godoc
 // @Summary Acquire a semaphore
 // @description.markdown semaphore_acquire.md
@@ -55,9 +79,12 @@ func (r *SemaphoreReleaseRequest) Decode(values url.Values) error {
 // @Param expires query int false "Expiration time" default(60000)
 // @Param id query string false "Optional request identifier for logging"
 // @Success 200 {string} Reply "The semaphore release key"
+// @Header 200 {integer} X-Fencing-Token "Fencing token of the key"
 // @Failure 400 {string} Reply "Bad Request - invalid parameters"
 // @Failure 404 {string} Reply "Not Found - semaphore not found
 // @Failure 408 {string} Reply "Request Timeout - `maxWait` exceeded"
+// @Failure 409 {string} Reply "Conflict - parameters differ from the configured ones"
+// @Failure 503 {string} Reply "Service Unavailable - server is shutting down"
 // @Router /semaphore/{name}/acquire [get]
 func SemaphoreAcquireHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	var err error
@@ -74,19 +101,21 @@ func SemaphoreAcquireHandler(w http.ResponseWriter, r *http.Request, ps httprout
 
 	if err == nil {
 		start := time.Now()
-		rep.Body, err = semaphore.Acquire(req.MaxWait, req.Expires, "")
+		var token uint64
+		rep.Body, token, err = semaphore.Acquire(r.Context(), req.MaxWait, req.Expires, "")
 		wait = time.Since(start)
 
 		if errors.Is(err, ErrTimedOut) {
 			rep.Status = http.StatusRequestTimeout
 		} else if err == nil {
+			w.Header().Set(FencingTokenHeader, strconv.FormatUint(token, 10))
 			rep.Status = http.StatusOK
 		}
 
 	}
 
 	rep.WriteResponse(w, r, err)
-	logRequest(rep.Status, "semaphore", "acquire", ps[0].Value, wait, req).Send()
+	logRequest(r, rep.Status, "semaphore", "acquire", ps[0].Value, wait, req).Send()
 }
 
 // SemaphoreReleaseHandler godoc
@@ -111,7 +140,8 @@ func SemaphoreReleaseHandler(w http.ResponseWriter, r *http.Request, ps httprout
 
 	err = req.Decode(r.URL.Query())
 	if err == nil {
-		semaphore, err = getSemaphore(ps[0].Value, 1) // Size doesn't matter for release
+		// don't go through getSemaphore, which would resize it
+		semaphore, err = findSemaphore(ps[0].Value)
 	}
 
 	if err == nil {
@@ -126,7 +156,96 @@ func SemaphoreReleaseHandler(w http.ResponseWriter, r *http.Request, ps httprout
 	}
 
 	rep.WriteResponse(w, r, err)
-	logRequest(rep.Status, "semaphore", "release", ps[0].Value, 0, req).Send()
+	logRequest(r, rep.Status, "semaphore", "release", ps[0].Value, 0, req).Send()
+}
+
+// SemaphoreRenewHandler godoc
+// @Summary Renew a semaphore key
+// @description.markdown semaphore_renew.md
+// @Tags Semaphore
+// @Produce plain
+// @Param name path string true "Semaphore name"
+// @Param key query string true "Release key"
+// @Param expires query int false "New expiration time, from now" default(60000)
+// @Param id query string false "Optional request identifier for logging"
+// @Success 204 "Key renewed successfully"
+// @Failure 400 {string} Reply "Bad Request - invalid parameters"
+// @Failure 404 {string} Reply "Not Found - semaphore not found"
+// @Failure 409 {string} Reply "Conflict - key already released or expired"
+// @Router /semaphore/{name}/renew [get]
+func SemaphoreRenewHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var err error
+	var semaphore *Semaphore
+
+	req := newSemaphoreRenewRequest()
+	rep := newReply()
+
+	err = req.Decode(r.URL.Query())
+	if err == nil {
+		semaphore, err = findSemaphore(ps[0].Value)
+	}
+
+	if err == nil {
+		err = semaphore.Renew(req.Key, req.Expires)
+
+		if err == nil {
+			rep.Status = http.StatusNoContent
+		}
+
+	}
+
+	rep.WriteResponse(w, r, err)
+	logRequest(r, rep.Status, "semaphore", "renew", ps[0].Value, 0, req).Send()
+}
+
+// SemaphoreValidateHandler godoc
+// @Summary Validate a fencing token
+// @description.markdown semaphore_validate.md
+// @Tags Semaphore
+// @Produce plain
+// @Param name path string true "Semaphore name"
+// @Param token query int true "Fencing token"
+// @Param key query string false "Release key the token was issued with"
+// @Param id query string false "Optional request identifier for logging"
+// @Success 204 "Token is held"
+// @Failure 400 {string} Reply "Bad Request - invalid parameters"
+// @Failure 404 {string} Reply "Not Found - semaphore not found"
+// @Failure 409 {string} Reply "Conflict - token is stale"
+// @Router /semaphore/{name}/validate [get]
+func SemaphoreValidateHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	status, req := ValidateHandler(w, r, ps, validateSemaphoreToken)
+	logRequest(r, status, "semaphore", "validate", ps[0].Value, 0, req).Send()
+}
+
+// SemaphoreConfigureHandler godoc
+// @Summary Create or reconfigure a semaphore
+// @description.markdown semaphore_configure.md
+// @Tags Semaphore
+// @Accept json
+// @Produce json
+// @Param name path string true "Semaphore name"
+// @Param config body SemaphoreConfig false "Semaphore configuration"
+// @Success 200 {object} SemaphoreConfig "Semaphore reconfigured"
+// @Success 201 {object} SemaphoreConfig "Semaphore created"
+// @Failure 400 {string} Reply "Bad Request - invalid configuration"
+// @Router /semaphore/{name} [put]
+func SemaphoreConfigureHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	status := ConfigureHandler(w, r, ps, configureSemaphore, getSemaphoreConfig)
+	logRequest(r, status, "semaphore", "configure", ps[0].Value, 0, nil).Send()
+}
+
+// SemaphoreConfigHandler godoc
+// @Summary Get semaphore configuration
+// @Description Get the current configuration of a semaphore
+// @Tags Semaphore
+// @Produce json
+// @Param name path string true "Semaphore name"
+// @Success 200 {object} SemaphoreConfig "Semaphore configuration"
+// @Failure 404 {string} Reply "Not Found - semaphore not found"
+// @Router /semaphore/{name} [get]
+func SemaphoreConfigHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	status := ConfigHandler(w, r, ps, getSemaphoreConfig)
+	logRequest(r, status, "semaphore", "config", ps[0].Value, 0, nil).Send()
 }
 
 // SemaphoreDeleteHandler godoc
@@ -140,7 +259,7 @@ func SemaphoreReleaseHandler(w http.ResponseWriter, r *http.Request, ps httprout
 // @Router /semaphore/{name} [delete]
 func SemaphoreDeleteHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	status := DeleteHandler(w, r, ps, deleteSemaphore)
-	logRequest(status, "semaphore", "delete", ps[0].Value, 0, nil).Send()
+	logRequest(r, status, "semaphore", "delete", ps[0].Value, 0, nil).Send()
 }
 
 // SemaphoreStatsHandler godoc
@@ -154,5 +273,21 @@ func SemaphoreDeleteHandler(w http.ResponseWriter, r *http.Request, ps httproute
 // @Router /semaphore/{name}/stats [get]
 func SemaphoreStatsHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	status := StatsHandler(w, r, ps, getSemaphoreStats)
-	logRequest(status, "semaphore", "stats", ps[0].Value, 0, nil).Send()
+	logRequest(r, status, "semaphore", "stats", ps[0].Value, 0, nil).Send()
+}
+
+// SemaphoreListHandler godoc
+// @Summary List semaphores
+// @Description List existing semaphores with their configuration and stats, ordered by name
+// @Tags Semaphore
+// @Produce json
+// @Param prefix query string false "Only list names starting with prefix"
+// @Param limit query int false "Maximum number of items" default(100)
+// @Param after query string false "Only list names after this one, as returned in 'next'"
+// @Success 200 {object} ResourceList "Semaphores"
+// @Failure 400 {string} Reply "Bad Request - invalid parameters"
+// @Router /semaphore/ [get]
+func SemaphoreListHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	status := ListHandler(w, r, "semaphore")
+	logRequest(r, status, "semaphore", "list", "", 0, nil).Send()
 }
@@ -2,6 +2,8 @@ package bouncermain_test
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -133,3 +135,223 @@ func TestSemaphoreNegativeSize(t *testing.T) {
 	require.Nil(t, err)
 	require.Equal(t, 400, status)
 }
+
+func TestSemaphoreWaitersAcquireInArrivalOrder(t *testing.T) {
+	baseURL := fmt.Sprintf("%s/semaphore/fifo-test", server.URL)
+
+	status, key, err := GetRequest(fmt.Sprintf("%s/acquire", baseURL))
+	require.Nil(t, err)
+	require.Equal(t, 200, status)
+
+	type acquired struct {
+		waiter int
+		key    string
+	}
+
+	acquiredC := make(chan acquired, 3)
+	for i := 0; i < 3; i++ {
+		go func(waiter int) {
+			status, key, _ := GetRequest(fmt.Sprintf("%s/acquire?maxwait=5000", baseURL))
+			if status == 200 {
+				acquiredC <- acquired{waiter, key}
+			}
+		}(i)
+		// give each waiter time to queue before the next one arrives
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	for i := 0; i < 3; i++ {
+		status, _, err = GetRequest(fmt.Sprintf("%s/release?key=%s", baseURL, key))
+		require.Nil(t, err)
+		require.Equal(t, 204, status)
+
+		select {
+		case next := <-acquiredC:
+			require.Equal(t, i, next.waiter)
+			key = next.key
+		case <-time.After(time.Second):
+			t.Fatalf("waiter %d not woken by release", i)
+		}
+	}
+}
+
+// BenchmarkSemaphoreContention measures the throughput of many clients
+// acquiring and releasing a small semaphore. Every acquire past the first few
+// has to wait for a release, so this is dominated by wakeup latency.
+func BenchmarkSemaphoreContention(b *testing.B) {
+	baseURL := fmt.Sprintf("%s/semaphore/bench-contention", server.URL)
+
+	b.SetParallelism(16)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			status, key, err := GetRequest(fmt.Sprintf("%s/acquire?size=2&maxwait=-1", baseURL))
+			if err != nil || status != 200 {
+				b.Errorf("acquire failed: status=%d err=%v", status, err)
+				return
+			}
+			GetRequest(fmt.Sprintf("%s/release?key=%s", baseURL, key))
+		}
+	})
+}
+
+// BenchmarkSemaphoreHandoff measures the time between a release and the
+// next waiter getting the slot.
+func BenchmarkSemaphoreHandoff(b *testing.B) {
+	baseURL := fmt.Sprintf("%s/semaphore/bench-handoff", server.URL)
+
+	_, key, _ := GetRequest(fmt.Sprintf("%s/acquire?maxwait=-1", baseURL))
+
+	var total time.Duration
+	for i := 0; i < b.N; i++ {
+		acquired := make(chan string)
+		go func() {
+			_, next, _ := GetRequest(fmt.Sprintf("%s/acquire?maxwait=-1", baseURL))
+			acquired <- next
+		}()
+		time.Sleep(time.Millisecond)
+
+		released := time.Now()
+		GetRequest(fmt.Sprintf("%s/release?key=%s", baseURL, key))
+		key = <-acquired
+		total += time.Since(released)
+	}
+	b.StopTimer()
+
+	GetRequest(fmt.Sprintf("%s/release?key=%s", baseURL, key))
+	b.ReportMetric(float64(total.Microseconds())/float64(b.N), "us/handoff")
+}
+
+func TestSemaphoreAbandonedAcquireDoesNotTakeSlot(t *testing.T) {
+	baseURL := fmt.Sprintf("%s/semaphore/abandoned-test", server.URL)
+
+	status, key, err := GetRequest(baseURL + "/acquire")
+	require.Nil(t, err)
+	require.Equal(t, 200, status)
+
+	// the client gives up long before maxwait
+	err = AbandonRequest(baseURL+"/acquire?maxwait=-1", 50*time.Millisecond)
+	require.NotNil(t, err)
+
+	require.Eventually(t, func() bool {
+		_, body, _ := GetRequest(baseURL + "/stats")
+		return strings.Contains(body, `"canceled":1`)
+	}, time.Second, 10*time.Millisecond)
+
+	status, _, err = GetRequest(fmt.Sprintf("%s/release?key=%s", baseURL, key))
+	require.Nil(t, err)
+	require.Equal(t, 204, status)
+
+	// the slot went back to the semaphore, not to the abandoned waiter
+	status, _, err = GetRequest(baseURL + "/acquire?maxwait=0")
+	require.Nil(t, err)
+	require.Equal(t, 200, status)
+
+	_, body, err := GetRequest(baseURL + "/stats")
+	require.Nil(t, err)
+	require.Contains(t, body, `"timed_out":0`)
+}
+
+// tokenValue parses a fencing token header.
+func tokenValue(t *testing.T, token string) uint64 {
+	value, err := strconv.ParseUint(token, 10, 64)
+	require.Nil(t, err)
+	return value
+}
+
+func TestSemaphoreFencingTokens(t *testing.T) {
+	baseURL := fmt.Sprintf("%s/semaphore/fencing-test", server.URL)
+
+	status, first, token1, err := AcquireFenced(baseURL + "/acquire?size=2&expires=100")
+	require.Nil(t, err)
+	require.Equal(t, 200, status)
+	require.NotZero(t, tokenValue(t, token1))
+
+	status, second, token2, err := AcquireFenced(baseURL + "/acquire?size=2")
+	require.Nil(t, err)
+	require.Equal(t, 200, status)
+	require.Greater(t, tokenValue(t, token2), tokenValue(t, token1))
+
+	status, _, err = GetRequest(fmt.Sprintf("%s/validate?token=%s&key=%s", baseURL, token1, first))
+	require.Nil(t, err)
+	require.Equal(t, 204, status)
+
+	// the token must match the key, if given
+	status, _, err = GetRequest(fmt.Sprintf("%s/validate?token=%s&key=%s", baseURL, token1, second))
+	require.Nil(t, err)
+	require.Equal(t, 409, status)
+
+	// a paused client whose key expired can't use its token anymore
+	time.Sleep(200 * time.Millisecond)
+	status, _, err = GetRequest(fmt.Sprintf("%s/validate?token=%s", baseURL, token1))
+	require.Nil(t, err)
+	require.Equal(t, 409, status)
+
+	status, _, err = GetRequest(fmt.Sprintf("%s/validate?token=%s", baseURL, token2))
+	require.Nil(t, err)
+	require.Equal(t, 204, status)
+
+	status, _, err = GetRequest(fmt.Sprintf("%s/release?key=%s", baseURL, second))
+	require.Nil(t, err)
+	require.Equal(t, 204, status)
+
+	status, _, token3, err := AcquireFenced(baseURL + "/acquire?size=2")
+	require.Nil(t, err)
+	require.Equal(t, 200, status)
+	require.Greater(t, tokenValue(t, token3), tokenValue(t, token2))
+
+	status, _, err = GetRequest(fmt.Sprintf("%s/semaphore/no-such-semaphore/validate?token=1", server.URL))
+	require.Nil(t, err)
+	require.Equal(t, 404, status)
+}
+
+func TestSemaphoreFencingTokensSurviveDelete(t *testing.T) {
+	baseURL := fmt.Sprintf("%s/semaphore/fencing-delete", server.URL)
+
+	status, _, first, err := AcquireFenced(baseURL + "/acquire")
+	require.Nil(t, err)
+	require.Equal(t, 200, status)
+
+	status, _, err = DeleteRequest(baseURL)
+	require.Nil(t, err)
+	require.Equal(t, 204, status)
+
+	// a semaphore created again under the same name doesn't reissue tokens
+	status, _, second, err := AcquireFenced(baseURL + "/acquire")
+	require.Nil(t, err)
+	require.Equal(t, 200, status)
+	require.Greater(t, tokenValue(t, second), tokenValue(t, first))
+}
+
+func TestSemaphoreRenew(t *testing.T) {
+	baseURL := fmt.Sprintf("%s/semaphore/renew-test", server.URL)
+
+	status, key, err := GetRequest(baseURL + "/acquire?expires=200")
+	require.Nil(t, err)
+	require.Equal(t, 200, status)
+
+	status, _, err = GetRequest(fmt.Sprintf("%s/renew?key=%s&expires=1000", baseURL, key))
+	require.Nil(t, err)
+	require.Equal(t, 204, status)
+
+	// still held past the original expiration
+	time.Sleep(400 * time.Millisecond)
+	status, _, err = GetRequest(baseURL + "/acquire?maxwait=0")
+	require.Nil(t, err)
+	require.Equal(t, 408, status)
+
+	status, _, err = GetRequest(fmt.Sprintf("%s/renew?key=%s&expires=100", baseURL, key))
+	require.Nil(t, err)
+	require.Equal(t, 204, status)
+
+	// and expires at the new one
+	time.Sleep(200 * time.Millisecond)
+	status, _, err = GetRequest(fmt.Sprintf("%s/renew?key=%s", baseURL, key))
+	require.Nil(t, err)
+	require.Equal(t, 409, status)
+
+	_, body, err := GetRequest(baseURL + "/stats")
+	require.Nil(t, err)
+	require.Contains(t, body, `"renewed":2`)
+	require.Contains(t, body, `"expired":1`)
+}
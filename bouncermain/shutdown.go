@@ -0,0 +1,110 @@
+package bouncermain
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+)
+
+// shuttingDown is closed when the shutdown grace period is over. Blocking
+// calls still waiting then fail with ErrShuttingDown.
+var shuttingDown = make(chan struct{})
+var shutdownOnce = &sync.Once{}
+
+// draining is closed as soon as shutdown starts, so readiness checks fail
+// while waiters are given their grace period.
+var draining = make(chan struct{})
+var drainingOnce = &sync.Once{}
+
+func beginDraining() {
+	drainingOnce.Do(func() { close(draining) })
+}
+
+func beginShutdown() {
+	beginDraining()
+	shutdownOnce.Do(func() { close(shuttingDown) })
+}
+
+func isDraining() bool {
+	select {
+	case <-draining:
+		return true
+	default:
+		return false
+	}
+}
+
+// shutdown stops accepting connections and gives in-flight requests and
+// gRPC calls the grace period to finish, then fails the ones still waiting.
+// State is saved after the last reply is sent. rpcServer may be nil.
+func shutdown(server *http.Server, rpcServer *grpc.Server) {
+	grace := time.Duration(viper.GetInt("shutdownGrace")) * time.Second
+	log.Info().Dur("grace", grace).Msg("shutting down")
+
+	beginDraining()
+	timer := time.AfterFunc(grace, beginShutdown)
+	defer timer.Stop()
+
+	// waiters fail at the end of the grace period, so this only times out
+	// if a handler is stuck on something else
+	ctx, cancel := context.WithTimeout(context.Background(), grace+5*time.Second)
+	defer cancel()
+
+	rpcStopped := make(chan struct{})
+	if rpcServer != nil {
+		go func() {
+			rpcServer.GracefulStop()
+			close(rpcStopped)
+		}()
+	}
+
+	if err := server.Shutdown(ctx); err != nil {
+		log.Error().Err(err).Msg("requests still running, closing connections")
+		server.Close()
+	}
+
+	if rpcServer != nil {
+		select {
+		case <-rpcStopped:
+		case <-ctx.Done():
+			log.Error().Msg("gRPC calls still running, closing connections")
+			rpcServer.Stop()
+			<-rpcStopped
+		}
+	}
+	beginShutdown()
+
+	// the server doesn't track WebSocket connections, which close once
+	// shutting down, releasing the slots they hold
+	streamsClosed := make(chan struct{})
+	go func() {
+		streamConns.wait()
+		close(streamsClosed)
+	}()
+	select {
+	case <-streamsClosed:
+	case <-time.After(5 * time.Second):
+		log.Error().Msg("streams still open")
+	}
+
+	if cluster != nil {
+		if err := cluster.Shutdown(); err != nil {
+			log.Error().Err(err).Msg("could not leave cluster")
+		}
+	}
+
+	if journal != nil {
+		if err := journal.Close(); err != nil {
+			log.Error().Err(err).Msg("could not save final snapshot")
+		}
+	}
+
+	log.Info().Msg("shutdown complete")
+	os.Stderr.Sync()
+}
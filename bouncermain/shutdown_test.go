@@ -0,0 +1,83 @@
+package bouncermain
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func resetShutdown() {
+	shuttingDown = make(chan struct{})
+	shutdownOnce = &sync.Once{}
+	draining = make(chan struct{})
+	drainingOnce = &sync.Once{}
+	streamConns = &streamTracker{}
+}
+
+func TestShutdownDrainsThenFailsWaiters(t *testing.T) {
+	defer resetShutdown()
+	defer deleteEvent("shutdown-sent")
+	defer deleteEvent("shutdown-never")
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	server := &http.Server{Handler: Router()}
+	go server.Serve(ln)
+
+	viper.Set("shutdownGrace", 1)
+	defer viper.Set("shutdownGrace", nil)
+
+	type result struct {
+		status int
+		body   string
+	}
+	wait := func(name string) chan result {
+		resultC := make(chan result, 1)
+		go func() {
+			rep, err := http.Get(fmt.Sprintf("http://%s/event/%s/wait", ln.Addr(), name))
+			if err != nil {
+				resultC <- result{0, err.Error()}
+				return
+			}
+			defer rep.Body.Close()
+			body, _ := io.ReadAll(rep.Body)
+			resultC <- result{rep.StatusCode, string(body)}
+		}()
+		return resultC
+	}
+
+	sentC := wait("shutdown-sent")
+	neverC := wait("shutdown-never")
+	require.Eventually(t, func() bool {
+		sent, _ := getEvent("shutdown-sent")
+		never, _ := getEvent("shutdown-never")
+		return sent.busy() && never.busy()
+	}, time.Second, time.Millisecond)
+
+	started := time.Now()
+	doneC := make(chan struct{})
+	go func() {
+		shutdown(server, nil)
+		close(doneC)
+	}()
+
+	// waiters can still finish during the grace period
+	time.Sleep(100 * time.Millisecond)
+	require.True(t, isDraining())
+	event, _ := getEvent("shutdown-sent")
+	require.Nil(t, event.Send("made it"))
+	require.Equal(t, result{200, "made it"}, <-sentC)
+
+	// the others get a reply once it's over
+	require.Equal(t, result{503, ErrShuttingDown.Error()}, <-neverC)
+	require.GreaterOrEqual(t, time.Since(started), time.Second)
+
+	<-doneC
+}
@@ -0,0 +1,694 @@
+package bouncermain
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	streamPingInterval = 30 * time.Second
+	streamReadTimeout  = 2 * streamPingInterval // without a message or pong
+	streamWriteTimeout = 10 * time.Second
+	maxStreamMessage   = 64 * 1024
+)
+
+// streamCalls maps the types that can be subscribed to the call a
+// subscription is checked as.
+var streamCalls = map[string]string{
+	"barrier":  "wait",
+	"counter":  "value",
+	"event":    "wait",
+	"watchdog": "wait",
+}
+
+// StreamRequest is a message from a WebSocket client.
+type StreamRequest struct {
+	ID      string `json:"id"`      // echoed in the reply
+	Op      string `json:"op"`      // subscribe, unsubscribe, acquire or release
+	Type    string `json:"type"`    // event, watchdog, barrier or counter, or semaphore to acquire and release
+	Name    string `json:"name"`    // within the namespace of the connection
+	Size    uint64 `json:"size"`    // of a barrier or semaphore, as configured if 0
+	Key     string `json:"key"`     // of the semaphore slot to release
+	MaxWait *int64 `json:"maxwait"` // milliseconds to wait for a semaphore, forever if unset
+	Expires int64  `json:"expires"` // milliseconds, renewed while connected, 60000 if 0
+}
+
+// StreamMessage is a reply to a WebSocket client, or a notification of a
+// subscription, sent to WebSocket and SSE clients.
+type StreamMessage struct {
+	ID           string `json:"id,omitempty"`
+	Status       int    `json:"status,omitempty"` // HTTP status of a reply, or of a failed subscription
+	Error        string `json:"error,omitempty"`
+	Type         string `json:"type,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Signal       string `json:"signal,omitempty"`  // sent, expired, released, value or lost
+	Message      string `json:"message,omitempty"` // of a sent event
+	Value        *int64 `json:"value,omitempty"`   // of a counter
+	Time         int64  `json:"time,omitempty"`    // Unix milliseconds of a watchdog expiration
+	Key          string `json:"key,omitempty"`
+	FencingToken uint64 `json:"fencing_token,omitempty"`
+}
+
+// streamWatch follows the object of a subscription, calling notify for each
+// notification, until ctx is done. Events and barriers notify once.
+type streamWatch func(ctx context.Context, notify func(*StreamMessage) error) error
+
+// newStreamWatch gets the object of a subscription, creating it like a wait
+// would, so a missing one fails before the subscription starts.
+func newStreamWatch(typeName string, name string, size uint64) (streamWatch, error) {
+	switch typeName {
+	case "event":
+		event, err := getEvent(name)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context, notify func(*StreamMessage) error) error {
+			message, err := event.Wait(ctx, -1)
+			if err != nil {
+				return err
+			}
+			return notify(&StreamMessage{Signal: "sent", Message: message})
+		}, nil
+
+	case "watchdog":
+		watchdog, err := getWatchdog(name, 0)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context, notify func(*StreamMessage) error) error {
+			var last chan struct{}
+			for {
+				var expiredAt time.Time
+				var err error
+				if last, expiredAt, err = watchdog.waitExpiry(ctx, last); err != nil {
+					return err
+				}
+				if err = notify(&StreamMessage{Signal: "expired", Time: expiredAt.UnixMilli()}); err != nil {
+					return err
+				}
+			}
+		}, nil
+
+	case "barrier":
+		barrier, err := getBarrier(name, size)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context, notify func(*StreamMessage) error) error {
+			if err := barrier.watch(ctx); err != nil {
+				return err
+			}
+			return notify(&StreamMessage{Signal: "released"})
+		}, nil
+
+	case "counter":
+		counter, err := getCounter(name)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context, notify func(*StreamMessage) error) error {
+			return counter.watch(ctx, func(value int64) error {
+				return notify(&StreamMessage{Signal: "value", Value: &value})
+			})
+		}, nil
+	}
+
+	return nil, ErrInvalidType
+}
+
+// errorMessage returns the message for a request or subscription that
+// failed.
+func errorMessage(err error) *StreamMessage {
+	return &StreamMessage{Status: errorStatus(err), Error: err.Error()}
+}
+
+var upgrader = websocket.Upgrader{}
+
+// streamConns tracks the open WebSocket connections, which the HTTP server
+// doesn't wait for on shutdown once hijacked.
+var streamConns = &streamTracker{}
+
+type streamTracker struct {
+	mu     sync.Mutex // orders open with wait
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// open registers a connection, unless wait was already called.
+func (s *streamTracker) open() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return false
+	}
+	s.wg.Add(1)
+	return true
+}
+
+func (s *streamTracker) done() {
+	s.wg.Done()
+}
+
+// wait refuses new connections and waits for the open ones to close.
+func (s *streamTracker) wait() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+// streamSub is a subscription of a connection.
+type streamSub struct {
+	cancel context.CancelFunc
+}
+
+// streamHold is a semaphore slot acquired by a connection.
+type streamHold struct {
+	name   string
+	cancel context.CancelFunc
+}
+
+type streamConn struct {
+	ws        *websocket.Conn
+	r         *http.Request
+	entry     *TokenEntry
+	namespace string
+	ctx       context.Context
+	cancel    context.CancelFunc
+	writeMu   *sync.Mutex
+	mu        *sync.Mutex
+	subs      map[string]*streamSub  // by type and qualified name
+	held      map[string]*streamHold // by key
+	wg        *sync.WaitGroup
+}
+
+func newStreamConn(ws *websocket.Conn, r *http.Request) *streamConn {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &streamConn{
+		ws:        ws,
+		r:         r,
+		entry:     requestTokenEntry(r),
+		namespace: requestNamespace(r),
+		ctx:       ctx,
+		cancel:    cancel,
+		writeMu:   &sync.Mutex{},
+		mu:        &sync.Mutex{},
+		subs:      map[string]*streamSub{},
+		held:      map[string]*streamHold{},
+		wg:        &sync.WaitGroup{},
+	}
+}
+
+// serve handles the requests of the client until it goes away. Then its
+// subscriptions end, and the slots it holds are released.
+func (c *streamConn) serve() {
+	defer c.close()
+
+	// replace the timeouts of the upgraded request, which would end the
+	// connection, with one extended as long as the client answers pings
+	c.ws.SetReadDeadline(time.Now().Add(streamReadTimeout))
+	c.ws.SetPongHandler(func(string) error {
+		return c.ws.SetReadDeadline(time.Now().Add(streamReadTimeout))
+	})
+	c.ws.SetReadLimit(maxStreamMessage)
+	go c.keepAlive()
+
+	for {
+		_, data, err := c.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		c.ws.SetReadDeadline(time.Now().Add(streamReadTimeout))
+
+		req := &StreamRequest{}
+		if err := json.Unmarshal(data, req); err != nil {
+			c.send(errorMessage(fmt.Errorf("%w: %v", ErrInvalidMessage, err)))
+			continue
+		}
+
+		switch req.Op {
+		case "subscribe":
+			c.subscribe(req)
+		case "unsubscribe":
+			c.unsubscribe(req)
+		case "acquire":
+			// acquires wait, so the connection keeps serving meanwhile
+			c.wg.Add(1)
+			go func() {
+				defer c.wg.Done()
+				c.acquire(req)
+			}()
+		case "release":
+			c.release(req)
+		default:
+			c.reply(req, req.Name, nil, 0, ErrInvalidOp)
+		}
+	}
+}
+
+func (c *streamConn) close() {
+	c.cancel()
+	c.wg.Wait()
+	c.ws.Close()
+}
+
+// keepAlive pings the client, so dead connections are noticed, and closes
+// the connection when a ping can't be sent or the server shuts down.
+func (c *streamConn) keepAlive() {
+	ticker := time.NewTicker(streamPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteTimeout)); err != nil {
+				c.ws.Close()
+				return
+			}
+		case <-shuttingDown:
+			msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, ErrShuttingDown.Error())
+			c.ws.WriteControl(websocket.CloseMessage, msg, time.Now().Add(streamWriteTimeout))
+			c.ws.Close()
+			return
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *streamConn) send(msg *StreamMessage) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.ws.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+	if err := c.ws.WriteJSON(msg); err != nil {
+		return ErrCanceled
+	}
+	return nil
+}
+
+// reply answers a request with the status for its error, if any, and logs
+// it by the qualified name.
+func (c *streamConn) reply(req *StreamRequest, name string, rep *StreamMessage, wait time.Duration, err error) {
+	if rep == nil {
+		rep = &StreamMessage{}
+	}
+	rep.ID, rep.Type, rep.Name = req.ID, req.Type, req.Name
+	rep.Status = http.StatusOK
+	if err != nil {
+		rep.Status = errorStatus(err)
+		rep.Error = err.Error()
+	}
+
+	c.send(rep)
+	logRequest(c.r, rep.Status, req.Type, req.Op, name, wait, nil).Str("id", req.ID).Send()
+}
+
+// subscribe starts notifying the client about an object, replacing any
+// subscription to it already made.
+func (c *streamConn) subscribe(req *StreamRequest) {
+	call, ok := streamCalls[req.Type]
+	if !ok {
+		c.reply(req, req.Name, nil, 0, ErrInvalidType)
+		return
+	}
+
+	name, err := checkCall(c.entry, c.namespace, req.Type, call, req.Name)
+	var watch streamWatch
+	if err == nil {
+		watch, err = newStreamWatch(req.Type, name, req.Size)
+	}
+	if err != nil {
+		c.reply(req, name, nil, 0, err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(c.ctx)
+	sub := &streamSub{cancel: cancel}
+	id := req.Type + " " + name
+
+	c.mu.Lock()
+	if old, ok := c.subs[id]; ok {
+		old.cancel()
+	}
+	c.subs[id] = sub
+	c.mu.Unlock()
+
+	// notifications only come after the reply
+	c.reply(req, name, nil, 0, nil)
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer cancel()
+
+		err := watch(ctx, func(msg *StreamMessage) error {
+			msg.Type, msg.Name = req.Type, req.Name
+			return c.send(msg)
+		})
+		if err != nil && ctx.Err() == nil {
+			msg := errorMessage(err)
+			msg.Type, msg.Name = req.Type, req.Name
+			c.send(msg)
+		}
+
+		c.mu.Lock()
+		if c.subs[id] == sub {
+			delete(c.subs, id)
+		}
+		c.mu.Unlock()
+	}()
+}
+
+func (c *streamConn) unsubscribe(req *StreamRequest) {
+	if _, ok := streamCalls[req.Type]; !ok {
+		c.reply(req, req.Name, nil, 0, ErrInvalidType)
+		return
+	}
+
+	name := qualifiedName(c.namespace, req.Name)
+	id := req.Type + " " + name
+
+	c.mu.Lock()
+	sub, ok := c.subs[id]
+	delete(c.subs, id)
+	c.mu.Unlock()
+
+	if !ok {
+		c.reply(req, name, nil, 0, ErrNotFound)
+		return
+	}
+	sub.cancel()
+	c.reply(req, name, nil, 0, nil)
+}
+
+// acquire takes a semaphore slot for as long as the connection is open, or
+// until released.
+func (c *streamConn) acquire(req *StreamRequest) {
+	if req.Type != "semaphore" {
+		c.reply(req, req.Name, nil, 0, ErrInvalidType)
+		return
+	}
+
+	name, err := checkCall(c.entry, c.namespace, req.Type, req.Op, req.Name)
+	var semaphore *Semaphore
+	if err == nil {
+		semaphore, err = getSemaphore(name, req.Size)
+	}
+	if err != nil {
+		c.reply(req, name, nil, 0, err)
+		return
+	}
+
+	maxwait := time.Duration(-1)
+	if req.MaxWait != nil {
+		maxwait = time.Duration(*req.MaxWait) * time.Millisecond
+	}
+	expires := time.Minute
+	if req.Expires > 0 {
+		expires = time.Duration(req.Expires) * time.Millisecond
+	}
+
+	start := time.Now()
+	mark := replicationMark()
+	key, token, err := semaphore.Acquire(c.ctx, maxwait, expires, "")
+	wait := time.Since(start)
+	if err == nil {
+		// the client won't know the key unless the slot was replicated
+		if err = replicationDone(mark); err == nil {
+			c.hold(req, semaphore, key, expires)
+		} else {
+			semaphore.Release(key)
+		}
+	}
+
+	c.reply(req, name, &StreamMessage{Key: key, FencingToken: token}, wait, err)
+}
+
+// hold renews a slot before it expires until it's released, and releases
+// it when the connection closes. The expiration only matters if the server
+// restarts with the slot persisted.
+func (c *streamConn) hold(req *StreamRequest, semaphore *Semaphore, key string, expires time.Duration) {
+	ctx, cancel := context.WithCancel(c.ctx)
+
+	c.mu.Lock()
+	c.held[key] = &streamHold{name: semaphore.Name, cancel: cancel}
+	c.mu.Unlock()
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer cancel()
+
+		ticker := time.NewTicker(expires / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := semaphore.Renew(key, expires); err != nil {
+					// deleted, or released by someone else
+					c.mu.Lock()
+					delete(c.held, key)
+					c.mu.Unlock()
+
+					msg := errorMessage(err)
+					msg.Type, msg.Name, msg.Signal, msg.Key = req.Type, req.Name, "lost", key
+					c.send(msg)
+					return
+				}
+			case <-ctx.Done():
+				// released by the client otherwise
+				if c.ctx.Err() != nil {
+					semaphore.Release(key)
+				}
+				return
+			}
+		}
+	}()
+}
+
+func (c *streamConn) release(req *StreamRequest) {
+	if req.Type != "semaphore" {
+		c.reply(req, req.Name, nil, 0, ErrInvalidType)
+		return
+	}
+
+	name, err := checkCall(c.entry, c.namespace, req.Type, req.Op, req.Name)
+	var semaphore *Semaphore
+	if err == nil {
+		semaphore, err = findSemaphore(name)
+	}
+
+	if err == nil {
+		c.mu.Lock()
+		if hold, ok := c.held[req.Key]; ok && hold.name == name {
+			hold.cancel()
+			delete(c.held, req.Key)
+		}
+		c.mu.Unlock()
+
+		mark := replicationMark()
+		if err = semaphore.Release(req.Key); err == nil {
+			err = replicationDone(mark)
+		}
+	}
+
+	c.reply(req, name, nil, 0, err)
+}
+
+// StreamHandler godoc
+// @Summary Subscribe and hold semaphores over a WebSocket
+// @description.markdown stream.md
+// @Tags Stream
+// @Success 101 "Switching Protocols"
+// @Failure 400 {string} Reply "Bad Request - not a WebSocket handshake"
+// @Failure 503 {string} Reply "Service Unavailable - server is shutting down"
+// @Router /stream [get]
+func StreamHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if isDraining() || !streamConns.open() {
+		rep := newReply()
+		rep.WriteResponse(w, r, ErrShuttingDown)
+		logRequest(r, rep.Status, "stream", "connect", "", 0, nil).Send()
+		return
+	}
+	defer streamConns.done()
+
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader already replied
+		logRequest(r, http.StatusBadRequest, "stream", "connect", "", 0, nil).Send()
+		return
+	}
+
+	start := time.Now()
+	newStreamConn(ws, r).serve()
+	logRequest(r, http.StatusOK, "stream", "connect", "", time.Since(start), nil).Send()
+}
+
+type StreamSSERequest struct {
+	Events    []string `schema:"event"`
+	Watchdogs []string `schema:"watchdog"`
+	Barriers  []string `schema:"barrier"`
+	Counters  []string `schema:"counter"`
+	ID        string   `schema:"id"`
+}
+
+func newStreamSSERequest() *StreamSSERequest {
+	return &StreamSSERequest{
+		ID: "",
+	}
+}
+
+func (r *StreamSSERequest) Decode(values url.Values) error {
+	if err := decoder.Decode(r, values); err != nil {
+		return err
+	}
+	if len(r.Events)+len(r.Watchdogs)+len(r.Barriers)+len(r.Counters) == 0 {
+		return ErrNoSubscriptions
+	}
+	return nil
+}
+
+// sseWatch is a subscription of an SSE request.
+type sseWatch struct {
+	typeName string
+	name     string // as given
+	watch    streamWatch
+}
+
+// sseWatches checks and starts following every object subscribed to.
+func sseWatches(r *http.Request, req *StreamSSERequest) ([]sseWatch, error) {
+	watches := []sseWatch{}
+	for _, sub := range []struct {
+		typeName string
+		names    []string
+	}{
+		{"event", req.Events},
+		{"watchdog", req.Watchdogs},
+		{"barrier", req.Barriers},
+		{"counter", req.Counters},
+	} {
+		for _, name := range sub.names {
+			qualified, err := checkCall(requestTokenEntry(r), requestNamespace(r), sub.typeName, streamCalls[sub.typeName], name)
+			if err != nil {
+				return nil, err
+			}
+			watch, err := newStreamWatch(sub.typeName, qualified, 0)
+			if err != nil {
+				return nil, err
+			}
+			watches = append(watches, sseWatch{sub.typeName, name, watch})
+		}
+	}
+	return watches, nil
+}
+
+// StreamSSEHandler godoc
+// @Summary Stream notifications as Server-Sent Events
+// @description.markdown stream_sse.md
+// @Tags Stream
+// @Produce text/event-stream
+// @Param event query []string false "Event names, notified once sent" collectionFormat(multi)
+// @Param watchdog query []string false "Watchdog names, notified on every expiration" collectionFormat(multi)
+// @Param barrier query []string false "Barrier names, notified once released" collectionFormat(multi)
+// @Param counter query []string false "Counter names, notified of the value and every change" collectionFormat(multi)
+// @Success 200 {object} StreamMessage "Stream of notifications"
+// @Failure 400 {string} Reply "Bad Request - invalid parameters"
+// @Failure 404 {string} Reply "Not Found - object not found, in strict mode"
+// @Router /stream/sse [get]
+func StreamSSEHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	req := newStreamSSERequest()
+	rep := newReply()
+
+	err := req.Decode(r.URL.Query())
+	var watches []sseWatch
+	if err == nil {
+		watches, err = sseWatches(r, req)
+	}
+	if err != nil {
+		rep.WriteResponse(w, r, err)
+		logRequest(r, rep.Status, "stream", "sse", "", 0, req).Send()
+		return
+	}
+
+	// the write timeout of the request would end the stream
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	rc.Flush()
+
+	mu := &sync.Mutex{}
+	closed := false
+	write := func(s string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if closed {
+			return ErrCanceled
+		}
+		if _, err := fmt.Fprint(w, s); err != nil {
+			return ErrCanceled
+		}
+		if err := rc.Flush(); err != nil {
+			return ErrCanceled
+		}
+		return nil
+	}
+	send := func(msg *StreamMessage) error {
+		buf, _ := json.Marshal(msg)
+		return write(fmt.Sprintf("event: %s\ndata: %s\n\n", cmp.Or(msg.Type, "error"), buf))
+	}
+
+	// comments keep proxies from closing an idle stream
+	doneC := make(chan struct{})
+	defer func() {
+		// nothing may be written once the handler returns
+		mu.Lock()
+		closed = true
+		mu.Unlock()
+		close(doneC)
+	}()
+	go func() {
+		ticker := time.NewTicker(streamPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				write(": ping\n\n")
+			case <-doneC:
+				return
+			}
+		}
+	}()
+
+	start := time.Now()
+	err = watchEach(r.Context(), len(watches), func(ctx context.Context, i int) error {
+		return watches[i].watch(ctx, func(msg *StreamMessage) error {
+			msg.Type, msg.Name = watches[i].typeName, watches[i].name
+			return send(msg)
+		})
+	})
+
+	status := http.StatusOK
+	if err != nil {
+		status = errorStatus(err)
+		if r.Context().Err() == nil {
+			send(errorMessage(err))
+		}
+	}
+	logRequest(r, status, "stream", "sse", "", time.Since(start), req).Send()
+}
@@ -0,0 +1,330 @@
+package bouncermain
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+func serveStream(t *testing.T) *httptest.Server {
+	router := Router()
+	server := httptest.NewServer(withAuth(router, router))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func dialStream(t *testing.T, server *httptest.Server, path string, token string) *websocket.Conn {
+	header := http.Header{}
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+path, header)
+	require.Nil(t, err)
+	t.Cleanup(func() { ws.Close() })
+	return ws
+}
+
+// streamCall sends a request and returns the next message, which is its
+// reply unless a notification is pending.
+func streamCall(t *testing.T, ws *websocket.Conn, req *StreamRequest) *StreamMessage {
+	t.Helper()
+	require.Nil(t, ws.WriteJSON(req))
+	return streamRecv(t, ws)
+}
+
+func streamRecv(t *testing.T, ws *websocket.Conn) *StreamMessage {
+	t.Helper()
+	ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+	msg := &StreamMessage{}
+	require.Nil(t, ws.ReadJSON(msg))
+	return msg
+}
+
+func TestStreamSubscribe(t *testing.T) {
+	defer deleteEvent("stream-deploy")
+	defer deleteCounter("stream-hits")
+	defer deleteBarrier("stream-start")
+	ws := dialStream(t, serveStream(t), "/stream", "")
+
+	rep := streamCall(t, ws, &StreamRequest{ID: "1", Op: "subscribe", Type: "event", Name: "stream-deploy"})
+	require.Equal(t, "1", rep.ID)
+	require.Equal(t, 200, rep.Status)
+
+	rep = streamCall(t, ws, &StreamRequest{ID: "2", Op: "subscribe", Type: "counter", Name: "stream-hits"})
+	require.Equal(t, 200, rep.Status)
+	msg := streamRecv(t, ws)
+	require.Equal(t, "value", msg.Signal)
+	require.Equal(t, int64(0), *msg.Value)
+
+	counter, err := getCounter("stream-hits")
+	require.Nil(t, err)
+	counter.Count(3)
+	msg = streamRecv(t, ws)
+	require.Equal(t, "counter", msg.Type)
+	require.Equal(t, "stream-hits", msg.Name)
+	require.Equal(t, int64(3), *msg.Value)
+
+	rep = streamCall(t, ws, &StreamRequest{ID: "3", Op: "subscribe", Type: "barrier", Name: "stream-start", Size: 1})
+	require.Equal(t, 200, rep.Status)
+	barrier, err := getBarrier("stream-start", 0)
+	require.Nil(t, err)
+	require.Nil(t, barrier.Wait(context.Background(), 0))
+	msg = streamRecv(t, ws)
+	require.Equal(t, "barrier", msg.Type)
+	require.Equal(t, "released", msg.Signal)
+
+	event, err := getEvent("stream-deploy")
+	require.Nil(t, err)
+	require.Nil(t, event.Send("v2"))
+	msg = streamRecv(t, ws)
+	require.Equal(t, "event", msg.Type)
+	require.Equal(t, "sent", msg.Signal)
+	require.Equal(t, "v2", msg.Message)
+
+	// no more changes are notified once unsubscribed
+	rep = streamCall(t, ws, &StreamRequest{ID: "4", Op: "unsubscribe", Type: "counter", Name: "stream-hits"})
+	require.Equal(t, 200, rep.Status)
+	counter.Count(1)
+
+	rep = streamCall(t, ws, &StreamRequest{ID: "5", Op: "unsubscribe", Type: "counter", Name: "stream-hits"})
+	require.Equal(t, "5", rep.ID)
+	require.Equal(t, 404, rep.Status)
+
+	rep = streamCall(t, ws, &StreamRequest{ID: "6", Op: "subscribe", Type: "semaphore", Name: "stream-hits"})
+	require.Equal(t, 400, rep.Status)
+
+	rep = streamCall(t, ws, &StreamRequest{ID: "7", Op: "lock", Type: "event", Name: "stream-deploy"})
+	require.Equal(t, 400, rep.Status)
+
+	require.Nil(t, ws.WriteMessage(websocket.TextMessage, []byte("{")))
+	rep = streamRecv(t, ws)
+	require.Equal(t, 400, rep.Status)
+	require.Contains(t, rep.Error, ErrInvalidMessage.Error())
+}
+
+func TestStreamWatchdog(t *testing.T) {
+	defer deleteWatchdog("stream-dog")
+	ws := dialStream(t, serveStream(t), "/stream", "")
+
+	watchdog, err := getWatchdog("stream-dog", 0)
+	require.Nil(t, err)
+	watchdog.Kick(50 * time.Millisecond)
+
+	rep := streamCall(t, ws, &StreamRequest{Op: "subscribe", Type: "watchdog", Name: "stream-dog"})
+	require.Equal(t, 200, rep.Status)
+
+	msg := streamRecv(t, ws)
+	require.Equal(t, "expired", msg.Signal)
+	first := msg.Time
+
+	watchdog.Kick(50 * time.Millisecond)
+	msg = streamRecv(t, ws)
+	require.Equal(t, "expired", msg.Signal)
+	require.Greater(t, msg.Time, first)
+}
+
+func TestStreamSemaphore(t *testing.T) {
+	defer deleteSemaphore("stream-db")
+	server := serveStream(t)
+	ws := dialStream(t, server, "/stream", "")
+
+	rep := streamCall(t, ws, &StreamRequest{ID: "1", Op: "acquire", Type: "semaphore", Name: "stream-db", Size: 1})
+	require.Equal(t, 200, rep.Status)
+	require.NotEmpty(t, rep.Key)
+	require.NotZero(t, rep.FencingToken)
+	key := rep.Key
+
+	rep = streamCall(t, ws, &StreamRequest{ID: "2", Op: "acquire", Type: "semaphore", Name: "stream-db", MaxWait: ms(0)})
+	require.Equal(t, "2", rep.ID)
+	require.Equal(t, 408, rep.Status)
+
+	// a waiting acquire doesn't hold up other requests
+	require.Nil(t, ws.WriteJSON(&StreamRequest{ID: "3", Op: "acquire", Type: "semaphore", Name: "stream-db"}))
+	rep = streamCall(t, ws, &StreamRequest{ID: "4", Op: "release", Type: "semaphore", Name: "stream-db", Key: key})
+	require.Equal(t, "4", rep.ID)
+	require.Equal(t, 200, rep.Status)
+
+	rep = streamRecv(t, ws)
+	require.Equal(t, "3", rep.ID)
+	require.Equal(t, 200, rep.Status)
+
+	// the slot is released when the connection closes
+	semaphore, err := findSemaphore("stream-db")
+	require.Nil(t, err)
+	ws.Close()
+	require.Eventually(t, func() bool {
+		key, _, err := semaphore.Acquire(context.Background(), 0, time.Second, "")
+		if err == nil {
+			semaphore.Release(key)
+		}
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestStreamSemaphoreNotReplicated(t *testing.T) {
+	defer deleteSemaphore("stream-lost")
+	self := clusterPeer{ID: "solo", RaftAddr: freeAddr(t), HTTPAddr: "http://127.0.0.1:1"}
+	node, err := newCluster(clusterConfig{ID: self.ID, Peers: []clusterPeer{self}}, &recordingFSM{})
+	require.Nil(t, err)
+	defer node.Shutdown()
+	waitForLeader(t, map[string]*Cluster{self.ID: node})
+	cluster = node
+	defer func() { cluster = nil }()
+
+	semaphore, err := getSemaphore("stream-lost", 1)
+	require.Nil(t, err)
+	held, _, err := semaphore.Acquire(context.Background(), 0, time.Minute, "")
+	require.Nil(t, err)
+
+	server := serveStream(t)
+	ws := dialStream(t, server, "/stream", "")
+	require.Nil(t, ws.WriteJSON(&StreamRequest{ID: "1", Op: "acquire", Type: "semaphore", Name: "stream-lost"}))
+	require.Eventually(t, func() bool {
+		semaphore.mu.RLock()
+		defer semaphore.mu.RUnlock()
+		return semaphore.queue.Len() == 1
+	}, time.Second, time.Millisecond)
+
+	// a record lost while waiting fails the acquire
+	node.applyMu.Lock()
+	node.issued++
+	node.pending = append(node.pending, lostFuture{})
+	node.appliedC.Broadcast()
+	node.applyMu.Unlock()
+	require.Nil(t, semaphore.Release(held))
+
+	rep := streamRecv(t, ws)
+	require.Equal(t, "1", rep.ID)
+	require.Equal(t, 503, rep.Status)
+
+	// and the slot isn't kept for a key the client never got
+	semaphore.mu.RLock()
+	defer semaphore.mu.RUnlock()
+	require.Empty(t, semaphore.Keys)
+}
+
+func TestStreamNamespaceAndAuth(t *testing.T) {
+	useConfigFile(t, writeConfigFile(t, "bouncer.yaml", `
+tokens:
+  - name: team-a
+    token: token-a
+    grants:
+      - access: acquire
+        prefix: team-a/
+`))
+	defer deleteCounter("team-a/stream-hits")
+	server := serveStream(t)
+
+	_, rep, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/stream", nil)
+	require.NotNil(t, err)
+	require.Equal(t, 401, rep.StatusCode)
+
+	ws := dialStream(t, server, "/stream", "token-a")
+	msg := streamCall(t, ws, &StreamRequest{Op: "subscribe", Type: "counter", Name: "stream-hits"})
+	require.Equal(t, 403, msg.Status)
+
+	ws = dialStream(t, server, "/ns/team-a/stream", "token-a")
+	msg = streamCall(t, ws, &StreamRequest{Op: "subscribe", Type: "counter", Name: "stream-hits"})
+	require.Equal(t, 200, msg.Status)
+	msg = streamRecv(t, ws)
+	require.Equal(t, "stream-hits", msg.Name)
+
+	counter, err := getCounter("team-a/stream-hits")
+	require.Nil(t, err)
+	counter.Count(2)
+	msg = streamRecv(t, ws)
+	require.Equal(t, int64(2), *msg.Value)
+
+	msg = streamCall(t, ws, &StreamRequest{Op: "subscribe", Type: "counter", Name: "team-b/hits"})
+	require.Equal(t, 400, msg.Status)
+}
+
+func TestStreamRefusedWhileDraining(t *testing.T) {
+	defer resetShutdown()
+	server := serveStream(t)
+	ws := dialStream(t, server, "/stream", "")
+
+	beginDraining()
+	_, rep, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/stream", nil)
+	require.NotNil(t, err)
+	require.Equal(t, 503, rep.StatusCode)
+
+	// shutdown waits for the open connection, and no new one is registered
+	waited := make(chan struct{})
+	go func() {
+		streamConns.wait()
+		close(waited)
+	}()
+	require.Eventually(t, func() bool {
+		streamConns.mu.Lock()
+		defer streamConns.mu.Unlock()
+		return streamConns.closed
+	}, time.Second, time.Millisecond)
+	require.False(t, streamConns.open())
+
+	ws.Close()
+	select {
+	case <-waited:
+	case <-time.After(time.Second):
+		t.Fatal("open connection not waited for")
+	}
+}
+
+func TestStreamSSE(t *testing.T) {
+	defer deleteEvent("sse-deploy")
+	defer deleteCounter("sse-hits")
+	server := serveStream(t)
+
+	rep, err := http.Get(server.URL + "/stream/sse")
+	require.Nil(t, err)
+	rep.Body.Close()
+	require.Equal(t, 400, rep.StatusCode)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/stream/sse?event=sse-deploy&counter=sse-hits", nil)
+	rep, err = http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	defer rep.Body.Close()
+	require.Equal(t, 200, rep.StatusCode)
+	require.Equal(t, "text/event-stream", rep.Header.Get("Content-Type"))
+
+	lines := bufio.NewScanner(rep.Body)
+	next := func() (string, *StreamMessage) {
+		var name string
+		msg := &StreamMessage{}
+		for lines.Scan() {
+			line := lines.Text()
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				require.Nil(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), msg))
+			case line == "" && name != "":
+				return name, msg
+			}
+		}
+		require.Nil(t, lines.Err())
+		return "", nil
+	}
+
+	name, msg := next()
+	require.Equal(t, "counter", name)
+	require.Equal(t, "sse-hits", msg.Name)
+	require.Equal(t, int64(0), *msg.Value)
+
+	event, err := getEvent("sse-deploy")
+	require.Nil(t, err)
+	require.Nil(t, event.Send("v3"))
+	name, msg = next()
+	require.Equal(t, "event", name)
+	require.Equal(t, "v3", msg.Message)
+}
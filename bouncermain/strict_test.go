@@ -0,0 +1,55 @@
+package bouncermain
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStrictModeRequiresConfigure(t *testing.T) {
+	strictMode = true
+	defer func() { strictMode = false }()
+	defer deleteSemaphore("strict-declared")
+
+	server := httptest.NewServer(Router())
+	defer server.Close()
+
+	get := func(path string) int {
+		rep, err := http.Get(server.URL + path)
+		require.Nil(t, err)
+		defer rep.Body.Close()
+		io.ReadAll(rep.Body)
+		return rep.StatusCode
+	}
+
+	for _, path := range []string{
+		"/semaphore/strict-undeclared/acquire",
+		"/tokenbucket/strict-undeclared/acquire",
+		"/tokenbucket/strict-undeclared/check",
+		"/ratelimit/strict-undeclared/acquire",
+		"/rwlock/strict-undeclared/lock",
+		"/event/strict-undeclared/wait?maxwait=0",
+		"/watchdog/strict-undeclared/kick",
+		"/counter/strict-undeclared/count",
+		"/barrier/strict-undeclared/wait?maxwait=0",
+	} {
+		require.Equal(t, 404, get(path), path)
+	}
+
+	req, _ := http.NewRequest("PUT", server.URL+"/semaphore/strict-declared", strings.NewReader(`{"size": 1}`))
+	rep, err := http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	rep.Body.Close()
+	require.Equal(t, 201, rep.StatusCode)
+
+	require.Equal(t, 200, get("/semaphore/strict-declared/acquire"))
+
+	// nothing was created by the rejected calls
+	for typeName := range resourceTypes {
+		require.NotContains(t, resourceTypes[typeName].names(), "strict-undeclared", typeName)
+	}
+}
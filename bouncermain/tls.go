@@ -0,0 +1,242 @@
+package bouncermain
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+// tlsFiles holds the certificate and client CA bundle loaded from the files
+// set with BOUNCER_TLS_*, reloaded when they change.
+type tlsFiles struct {
+	certFile   string
+	keyFile    string
+	caFile     string
+	clientAuth tls.ClientAuthType
+	mu         sync.RWMutex // protects cert and clientCAs
+	cert       *tls.Certificate
+	clientCAs  *x509.CertPool
+}
+
+// peerTLS is the config to connect to other cluster nodes with, presenting
+// this node's certificate. Nil if not serving TLS.
+var peerTLS *tls.Config
+
+// setupTLS returns the TLS config to serve with, or nil to serve plain HTTP
+// if no certificate is set.
+func setupTLS() (*tls.Config, error) {
+	files := &tlsFiles{
+		certFile: viper.GetString("tlsCert"),
+		keyFile:  viper.GetString("tlsKey"),
+		caFile:   viper.GetString("tlsClientCA"),
+	}
+
+	peerTLS = nil
+	if files.certFile == "" && files.keyFile == "" && files.caFile == "" {
+		return nil, nil
+	}
+	if files.certFile == "" || files.keyFile == "" {
+		return nil, fmt.Errorf("both BOUNCER_TLS_CERT and BOUNCER_TLS_KEY must be set")
+	}
+
+	switch viper.GetString("tlsClientAuth") {
+	case "require":
+		files.clientAuth = tls.RequireAndVerifyClientCert
+	case "optional":
+		files.clientAuth = tls.VerifyClientCertIfGiven
+	default:
+		return nil, fmt.Errorf("BOUNCER_TLS_CLIENT_AUTH must be 'require' or 'optional'")
+	}
+
+	if err := files.load(); err != nil {
+		return nil, err
+	}
+	if err := files.watch(); err != nil {
+		return nil, err
+	}
+	peerTLS = files.peerConfig()
+	return files.config(), nil
+}
+
+func (f *tlsFiles) load() error {
+	cert, err := tls.LoadX509KeyPair(f.certFile, f.keyFile)
+	if err != nil {
+		return err
+	}
+
+	var clientCAs *x509.CertPool
+	if f.caFile != "" {
+		bundle, err := os.ReadFile(f.caFile)
+		if err != nil {
+			return err
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(bundle) {
+			return fmt.Errorf("no certificates found in %s", f.caFile)
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.cert = &cert
+	f.clientCAs = clientCAs
+	return nil
+}
+
+// watch reloads the files on any change to their directories, which also
+// catches the symlink swaps of mounted secrets. A failed reload, like one
+// between writing the certificate and its key, keeps the current files.
+func (f *tlsFiles) watch() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	dirs := map[string]bool{}
+	for _, file := range []string{f.certFile, f.keyFile, f.caFile} {
+		if file != "" {
+			dirs[filepath.Dir(file)] = true
+		}
+	}
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return err
+		}
+	}
+
+	go func() {
+		for {
+			select {
+			case _, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if err := f.load(); err != nil {
+					log.Warn().Err(err).Msg("could not reload TLS files, keeping the current ones")
+				} else {
+					log.Debug().Str("cert", f.certFile).Msg("reloaded TLS files")
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Error().Err(err).Msg("watching TLS files")
+			}
+		}
+	}()
+	return nil
+}
+
+func (f *tlsFiles) config() *tls.Config {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// the configs for clients are clones of this one, so they must offer
+		// HTTP/2 themselves, which gRPC requires
+		NextProtos: []string{"h2", "http/1.1"},
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			f.mu.RLock()
+			defer f.mu.RUnlock()
+			return f.cert, nil
+		},
+	}
+
+	if f.caFile != "" {
+		config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			f.mu.RLock()
+			defer f.mu.RUnlock()
+
+			client := config.Clone()
+			client.ClientAuth = f.clientAuth
+			client.ClientCAs = f.clientCAs
+			return client, nil
+		}
+	}
+
+	return config
+}
+
+// peerConfig returns the config to connect to other nodes with. Nodes share
+// the certificate setup, so the client CA bundle, if any, is trusted for
+// their server certificates, and our certificate is their client one.
+func (f *tlsFiles) peerConfig() *tls.Config {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			f.mu.RLock()
+			defer f.mu.RUnlock()
+			return f.cert, nil
+		},
+	}
+
+	if f.caFile != "" {
+		// verified below against the current bundle, which may be reloaded
+		config.InsecureSkipVerify = true
+		config.VerifyConnection = func(state tls.ConnectionState) error {
+			f.mu.RLock()
+			roots := f.clientCAs
+			f.mu.RUnlock()
+
+			opts := x509.VerifyOptions{
+				Roots:         roots,
+				DNSName:       state.ServerName,
+				Intermediates: x509.NewCertPool(),
+			}
+			for _, cert := range state.PeerCertificates[1:] {
+				opts.Intermediates.AddCert(cert)
+			}
+			_, err := state.PeerCertificates[0].Verify(opts)
+			return err
+		}
+	}
+
+	return config
+}
+
+// certNames returns the names in a client certificate: its common name,
+// then its DNS, URI and email SANs.
+func certNames(cert *x509.Certificate) []string {
+	var names []string
+	if cert.Subject.CommonName != "" {
+		names = append(names, cert.Subject.CommonName)
+	}
+	names = append(names, cert.DNSNames...)
+	for _, uri := range cert.URIs {
+		names = append(names, uri.String())
+	}
+	return append(names, cert.EmailAddresses...)
+}
+
+// verifiedCert returns the client certificate of a request, if verified
+// against the client CA bundle.
+func verifiedCert(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return nil
+	}
+	return r.TLS.VerifiedChains[0][0]
+}
+
+// certIdentity returns the first name in the verified client certificate of
+// a request, if any.
+func certIdentity(r *http.Request) string {
+	return certName(verifiedCert(r))
+}
+
+// certName returns the first name in a client certificate, if any.
+func certName(cert *x509.Certificate) string {
+	if cert != nil {
+		if names := certNames(cert); len(names) > 0 {
+			return names[0]
+		}
+	}
+	return ""
+}
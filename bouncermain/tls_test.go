@@ -0,0 +1,261 @@
+package bouncermain
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pair tls.Certificate
+}
+
+// newTestCert issues a certificate from parent, or a self-signed CA if nil.
+func newTestCert(t *testing.T, parent *testCert, template *x509.Certificate) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.Nil(t, err)
+	template.SerialNumber = serial
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.Nil(t, err)
+
+	return &testCert{cert: cert, key: key, pair: tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}}
+}
+
+func (c *testCert) write(t *testing.T, certFile string, keyFile string) {
+	require.Nil(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}), 0o644))
+	if keyFile != "" {
+		der, err := x509.MarshalECPrivateKey(c.key)
+		require.Nil(t, err)
+		require.Nil(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0o600))
+	}
+}
+
+type tlsTest struct {
+	ca     *testCert
+	server *testCert
+	client *testCert
+	dir    string
+	url    string
+}
+
+// serveTLS serves handler with the TLS files set up as in Main.
+func serveTLS(t *testing.T, clientAuth string, handler http.Handler) *tlsTest {
+	tt, config := setupTestTLS(t, clientAuth)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	server := &http.Server{Handler: handler}
+	go server.Serve(tls.NewListener(listener, config))
+	t.Cleanup(func() { server.Close() })
+
+	tt.url = "https://" + listener.Addr().String()
+	return tt
+}
+
+// setupTestTLS writes a CA, server and client certificates, and returns the
+// server TLS config for them.
+func setupTestTLS(t *testing.T, clientAuth string) (*tlsTest, *tls.Config) {
+	tt := &tlsTest{dir: t.TempDir()}
+	tt.ca = newTestCert(t, nil, &x509.Certificate{Subject: pkix.Name{CommonName: "test CA"}})
+	tt.server = newTestCert(t, tt.ca, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "bouncer"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+	})
+	tt.client = newTestCert(t, tt.ca, &x509.Certificate{
+		Subject:  pkix.Name{CommonName: "worker-1"},
+		DNSNames: []string{"worker-1.example.com"},
+	})
+
+	tt.server.write(t, filepath.Join(tt.dir, "tls.crt"), filepath.Join(tt.dir, "tls.key"))
+	tt.ca.write(t, filepath.Join(tt.dir, "ca.crt"), "")
+
+	viper.Set("tlsCert", filepath.Join(tt.dir, "tls.crt"))
+	viper.Set("tlsKey", filepath.Join(tt.dir, "tls.key"))
+	viper.Set("tlsClientCA", filepath.Join(tt.dir, "ca.crt"))
+	viper.Set("tlsClientAuth", clientAuth)
+	defer func() {
+		for _, key := range []string{"tlsCert", "tlsKey", "tlsClientCA", "tlsClientAuth"} {
+			viper.Set(key, nil)
+		}
+	}()
+
+	config, err := setupTLS()
+	require.Nil(t, err)
+	t.Cleanup(func() { peerTLS = nil })
+	return tt, config
+}
+
+func (tt *tlsTest) get(path string, cert *testCert, token string) (int, string, error) {
+	roots := x509.NewCertPool()
+	roots.AddCert(tt.ca.cert)
+	transport := &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}
+	if cert != nil {
+		transport.TLSClientConfig.Certificates = []tls.Certificate{cert.pair}
+	}
+	defer transport.CloseIdleConnections()
+
+	req, _ := http.NewRequest("GET", tt.url+path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rep, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer rep.Body.Close()
+	body, _ := io.ReadAll(rep.Body)
+	return rep.StatusCode, string(body), nil
+}
+
+var echoClientName = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(clientName(r)))
+})
+
+func TestTLSClientIdentity(t *testing.T) {
+	tt := serveTLS(t, "optional", echoClientName)
+
+	_, name, err := tt.get("/", tt.client, "")
+	require.Nil(t, err)
+	require.Equal(t, "worker-1", name)
+
+	_, name, err = tt.get("/", nil, "")
+	require.Nil(t, err)
+	require.Equal(t, "", name)
+
+	// a certificate from another CA isn't taken as an identity
+	other := newTestCert(t, newTestCert(t, nil, &x509.Certificate{}), &x509.Certificate{
+		Subject: pkix.Name{CommonName: "intruder"},
+	})
+	_, name, err = tt.get("/", other, "")
+	require.Nil(t, err)
+	require.Equal(t, "", name)
+}
+
+func TestTLSRequireClientCert(t *testing.T) {
+	tt := serveTLS(t, "require", echoClientName)
+
+	_, _, err := tt.get("/", nil, "")
+	require.NotNil(t, err)
+
+	_, name, err := tt.get("/", tt.client, "")
+	require.Nil(t, err)
+	require.Equal(t, "worker-1", name)
+}
+
+func TestTLSSubjectGrants(t *testing.T) {
+	useConfigFile(t, writeConfigFile(t, "bouncer.yaml", `
+tokens:
+  - name: workers
+    subject: worker-1.example.com
+    grants:
+      - access: acquire
+        prefix: tls.
+  - name: admin
+    token: token-admin
+    grants:
+      - access: admin
+`))
+	defer deleteSemaphore("tls.a")
+
+	router := Router()
+	tt := serveTLS(t, "optional", withAuth(router, router))
+
+	for _, c := range []struct {
+		path   string
+		cert   *testCert
+		token  string
+		status int
+	}{
+		{"/semaphore/tls.a/acquire", tt.client, "", 200},
+		{"/semaphore/other/acquire", tt.client, "", 403},
+		{"/semaphore/tls.a/acquire", nil, "", 401},
+		// a token wins over the certificate
+		{"/semaphore/tls.a/acquire", tt.client, "wrong", 401},
+		{"/metrics", tt.client, "token-admin", 200},
+	} {
+		status, _, err := tt.get(c.path, c.cert, c.token)
+		require.Nil(t, err)
+		require.Equal(t, c.status, status, "%s with %q", c.path, c.token)
+	}
+}
+
+func TestTLSReload(t *testing.T) {
+	tt := serveTLS(t, "optional", echoClientName)
+
+	renewed := newTestCert(t, tt.ca, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "bouncer"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+	})
+	renewed.write(t, filepath.Join(tt.dir, "tls.crt"), filepath.Join(tt.dir, "tls.key"))
+
+	require.Eventually(t, func() bool {
+		roots := x509.NewCertPool()
+		roots.AddCert(tt.ca.cert)
+		conn, err := tls.Dial("tcp", tt.url[len("https://"):], &tls.Config{RootCAs: roots})
+		if err != nil {
+			return false
+		}
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].SerialNumber.Cmp(renewed.cert.SerialNumber) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestTLSInvalidSettings(t *testing.T) {
+	defer func() {
+		for _, key := range []string{"tlsCert", "tlsKey", "tlsClientAuth"} {
+			viper.Set(key, nil)
+		}
+	}()
+
+	config, err := setupTLS()
+	require.Nil(t, err)
+	require.Nil(t, config)
+
+	viper.Set("tlsCert", "tls.crt")
+	_, err = setupTLS()
+	require.NotNil(t, err)
+
+	viper.Set("tlsKey", "tls.key")
+	viper.Set("tlsClientAuth", "sometimes")
+	_, err = setupTLS()
+	require.NotNil(t, err)
+
+	viper.Set("tlsClientAuth", "require")
+	_, err = setupTLS()
+	require.NotNil(t, err)
+}
@@ -1,6 +1,10 @@
 package bouncermain
 
 import (
+	"cmp"
+	"container/list"
+	"context"
+	"errors"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -10,21 +14,44 @@ import (
 
 type TokenBucketStats struct {
 	Acquired        uint64  `json:"acquired"`
+	Tokens          uint64  `json:"tokens"`
 	TotalWaitTime   uint64  `json:"total_wait_time"`
 	TimedOut        uint64  `json:"timed_out"`
+	Canceled        uint64  `json:"canceled"`
+	Denied          uint64  `json:"denied"`
 	CreatedAt       string  `json:"created_at"`
 	AverageWaitTime float64 `json:"average_wait_time"`
 }
 
+// TokenBucketCheck is the outcome of a non-blocking check.
+type TokenBucketCheck struct {
+	Allowed    bool   `json:"allowed"`
+	Limit      uint64 `json:"limit"`                 // bucket size
+	Remaining  uint64 `json:"remaining"`             // tokens left after the check
+	Reset      int64  `json:"reset"`                 // milliseconds until the next refill
+	RetryAfter int64  `json:"retry_after,omitempty"` // milliseconds until count tokens are available, if denied
+}
+
+type TokenBucketConfig struct {
+	Size     uint64 `json:"size"`
+	Interval int64  `json:"interval"` // milliseconds
+}
+
 type TokenBucket struct {
 	Name     string
 	Size     uint64        // private field
 	interval time.Duration // private field
 	Stats    *TokenBucketStats
-	mu       sync.RWMutex // protect size and interval
+	mu       sync.RWMutex // protect size, interval and declared
+	declared bool         // configured explicitly, so acquires don't change it
 
 	available  int64 // atomic counter for available tokens
 	nextRefill int64 // atomic unix nano for next refill
+	waiters    int64
+
+	queue   *list.List // of chan struct{}, signaled when the waiter is at the front
+	queueMu sync.Mutex // protects queue, and taking tokens
+	idleTracker
 }
 
 var buckets = map[string]*TokenBucket{}
@@ -39,38 +66,32 @@ func newTokenBucket(name string, size uint64, interval time.Duration) (bucket *T
 		Stats:      &TokenBucketStats{CreatedAt: now.Format(time.RFC3339)},
 		available:  int64(size),
 		nextRefill: now.Add(interval).UnixNano(),
+		queue:      list.New(),
 	}
 
+	bucket.touch()
 	buckets[name] = bucket
 	return bucket
 }
 
+// getTokenBucket returns the bucket, created on first use with the
+// configured size and interval, or those given, or 1 per second. Zero values
+// aren't given.
 func getTokenBucket(name string, size uint64, interval time.Duration) (bucket *TokenBucket, err error) {
 	bucketsMutex.RLock()
 	bucket, ok := buckets[name]
+	if ok {
+		bucket.touch()
+	}
 	bucketsMutex.RUnlock()
 
 	if ok {
-		bucket.mu.RLock()
-		currentSize := bucket.Size
-		currentInterval := bucket.interval
-		bucket.mu.RUnlock()
-
-		if size != currentSize {
-			log.Warn().
-				Str("name", name).
-				Uint64("current_size", currentSize).
-				Uint64("new_size", size).
-				Msg("tokenbucket size modification through acquire is deprecated and will be removed in a future version")
-		}
+		return bucket, bucket.resize(size, interval)
+	}
 
-		if size != currentSize || interval != currentInterval {
-			bucket.mu.Lock()
-			bucket.Size = size
-			bucket.interval = interval
-			bucket.mu.Unlock()
-		}
-		return bucket, nil
+	config, configured := configuredTokenBucket(name)
+	if strictMode && !configured {
+		return nil, ErrNotFound
 	}
 
 	// Bucket doesn't exist, need to create it
@@ -80,11 +101,56 @@ func getTokenBucket(name string, size uint64, interval time.Duration) (bucket *T
 	// Check again in case another goroutine created it
 	bucket, ok = buckets[name]
 	if ok {
+		bucket.touch()
 		return bucket, nil
 	}
 
-	bucket = newTokenBucket(name, size, interval)
-	return bucket, nil
+	if !configured {
+		return newTokenBucket(name, cmp.Or(size, 1), cmp.Or(interval, time.Second)), nil
+	}
+
+	bucket = newTokenBucket(name, config.Size, time.Duration(config.Interval)*time.Millisecond)
+	bucket.declared = true
+	return bucket, bucket.resize(size, interval)
+}
+
+// resize changes the size and interval as given in an acquire, unless the
+// bucket was configured explicitly.
+func (bucket *TokenBucket) resize(size uint64, interval time.Duration) error {
+	bucket.mu.Lock()
+	defer bucket.mu.Unlock()
+
+	size = cmp.Or(size, bucket.Size)
+	interval = cmp.Or(interval, bucket.interval)
+	if size == bucket.Size && interval == bucket.interval {
+		return nil
+	}
+
+	if bucket.declared {
+		return paramsConflict("tokenbucket", bucket.Name,
+			TokenBucketConfig{Size: size, Interval: interval.Milliseconds()},
+			TokenBucketConfig{Size: bucket.Size, Interval: bucket.interval.Milliseconds()})
+	}
+
+	if size != bucket.Size {
+		log.Warn().
+			Str("name", bucket.Name).
+			Uint64("current_size", bucket.Size).
+			Uint64("new_size", size).
+			Msg("tokenbucket size modification through acquire is deprecated and will be removed in a future version")
+	}
+
+	bucket.setLimits(size, interval)
+	return nil
+}
+
+// setLimits changes the size and interval, with the tokens left and the next
+// refill brought within them. Must be called with the bucket mutex held.
+func (bucket *TokenBucket) setLimits(size uint64, interval time.Duration) {
+	bucket.Size = size
+	bucket.interval = interval
+	lowerInt64(&bucket.available, int64(size))
+	lowerInt64(&bucket.nextRefill, time.Now().Add(interval).UnixNano())
 }
 
 func (bucket *TokenBucket) refillTokens() {
@@ -96,65 +162,277 @@ func (bucket *TokenBucket) refillTokens() {
 		return
 	}
 
+	bucket.mu.RLock()
+	size, interval := bucket.Size, bucket.interval
+	bucket.mu.RUnlock()
+
 	// Try to update nextRefill - if we fail, someone else already did it
-	if atomic.CompareAndSwapInt64(&bucket.nextRefill, next, now+bucket.interval.Nanoseconds()) {
-		bucket.mu.RLock()
-		size := bucket.Size
-		bucket.mu.RUnlock()
+	if atomic.CompareAndSwapInt64(&bucket.nextRefill, next, now+interval.Nanoseconds()) {
 		atomic.StoreInt64(&bucket.available, int64(size))
 	}
 }
 
-func (bucket *TokenBucket) Acquire(maxwait time.Duration, arrival time.Time) error {
+// level returns the tokens available and the size, counting a refill that
+// is due as taken, without taking it.
+func (bucket *TokenBucket) level() (available int64, size uint64) {
+	bucket.mu.RLock()
+	size = bucket.Size
+	bucket.mu.RUnlock()
+
+	if time.Now().UnixNano() >= atomic.LoadInt64(&bucket.nextRefill) {
+		return int64(size), size
+	}
+	return atomic.LoadInt64(&bucket.available), size
+}
+
+// Acquire waits for count tokens and takes them all at once. Waiters are
+// served in order, so a large count isn't starved by smaller ones taking the
+// tokens as they're refilled.
+func (bucket *TokenBucket) Acquire(ctx context.Context, count uint64, maxwait time.Duration, arrival time.Time) error {
 	deadline := time.Now().Add(maxwait)
 
+	atomic.AddInt64(&bucket.waiters, 1)
+	defer atomic.AddInt64(&bucket.waiters, -1)
+
+	// our place in line, once we had to wait
+	var el *list.Element
+	defer func() {
+		if el != nil {
+			bucket.leave(el)
+		}
+	}()
+
 	for {
+		// don't take tokens nobody will use
+		if ctx.Err() != nil {
+			atomic.AddUint64(&bucket.Stats.Canceled, 1)
+			return ErrCanceled
+		}
+
+		// the size may have changed while waiting
+		bucket.mu.RLock()
+		size := bucket.Size
+		bucket.mu.RUnlock()
+
+		if count == 0 || count > size {
+			return ErrInvalidCount
+		}
+
 		bucket.refillTokens()
 
-		// Try to acquire a token
-		for {
-			current := atomic.LoadInt64(&bucket.available)
-			if current <= 0 {
-				break
-			}
-			if atomic.CompareAndSwapInt64(&bucket.available, current, current-1) {
-				wait := uint64(time.Since(arrival) / time.Millisecond)
-				atomic.AddUint64(&bucket.Stats.Acquired, 1)
-				atomic.AddUint64(&bucket.Stats.TotalWaitTime, wait)
-				return nil
-			}
+		bucket.queueMu.Lock()
+		front := bucket.queue.Len() == 0 || bucket.queue.Front() == el
+		if front && bucket.take(count) {
+			bucket.queueMu.Unlock()
+
+			wait := uint64(time.Since(arrival) / time.Millisecond)
+			atomic.AddUint64(&bucket.Stats.Acquired, 1)
+			atomic.AddUint64(&bucket.Stats.Tokens, count)
+			atomic.AddUint64(&bucket.Stats.TotalWaitTime, wait)
+			observeWait("tokenbucket", bucket.Name, time.Since(arrival))
+			return nil
 		}
+		if el == nil {
+			el = bucket.queue.PushBack(make(chan struct{}, 1))
+		}
+		bucket.queueMu.Unlock()
 
-		// No tokens available, check timeout
+		// Not enough tokens, check timeout
 		if maxwait >= 0 && time.Now().After(deadline) {
 			atomic.AddUint64(&bucket.Stats.TimedOut, 1)
 			return ErrTimedOut
 		}
 
-		// Sleep until next refill or deadline
+		// Sleep until next refill, our turn, or deadline
 		now := time.Now()
-		sleepUntil := time.Unix(0, atomic.LoadInt64(&bucket.nextRefill))
+		sleepUntil := now.Add(maxSleepDuration.Get())
+		if front {
+			sleepUntil = time.Unix(0, atomic.LoadInt64(&bucket.nextRefill))
+		}
 		if maxwait >= 0 && deadline.Before(sleepUntil) {
 			sleepUntil = deadline
 		}
 
-		time.Sleep(min(sleepUntil.Sub(now), maxSleepDuration))
+		var err error
+		if front {
+			err = sleep(ctx, min(sleepUntil.Sub(now), maxSleepDuration.Get()))
+		} else if err = waitFor(ctx, el.Value.(chan struct{}), max(sleepUntil.Sub(now), 0)); errors.Is(err, ErrTimedOut) {
+			// checked again on the next round
+			err = nil
+		}
+
+		if err != nil {
+			countGiveUp(err, &bucket.Stats.TimedOut, &bucket.Stats.Canceled)
+			return err
+		}
 	}
 }
 
+// Check takes count tokens if they're available right now, and never waits.
+// Tokens aren't taken ahead of waiting acquires.
+func (bucket *TokenBucket) Check(count uint64) (*TokenBucketCheck, error) {
+	bucket.mu.RLock()
+	size := bucket.Size
+	bucket.mu.RUnlock()
+
+	if count == 0 || count > size {
+		return nil, ErrInvalidCount
+	}
+
+	bucket.refillTokens()
+
+	bucket.queueMu.Lock()
+	allowed := bucket.queue.Len() == 0 && bucket.take(count)
+	bucket.queueMu.Unlock()
+
+	reset := max(time.Until(time.Unix(0, atomic.LoadInt64(&bucket.nextRefill))), 0)
+	check := &TokenBucketCheck{
+		Allowed:   allowed,
+		Limit:     size,
+		Remaining: uint64(max(atomic.LoadInt64(&bucket.available), 0)),
+		Reset:     reset.Milliseconds(),
+	}
+
+	if allowed {
+		atomic.AddUint64(&bucket.Stats.Acquired, 1)
+		atomic.AddUint64(&bucket.Stats.Tokens, count)
+	} else {
+		// the bucket is full after the refill, but waiters go first
+		check.RetryAfter = max(check.Reset, 1)
+		atomic.AddUint64(&bucket.Stats.Denied, 1)
+	}
+
+	return check, nil
+}
+
+// take takes count tokens if available. Must be called with queueMu held.
+func (bucket *TokenBucket) take(count uint64) bool {
+	for {
+		current := atomic.LoadInt64(&bucket.available)
+		if current < int64(count) {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&bucket.available, current, current-int64(count)) {
+			return true
+		}
+	}
+}
+
+// leave removes a waiter from the line, and lets the next one know if it's
+// now at the front.
+func (bucket *TokenBucket) leave(el *list.Element) {
+	bucket.queueMu.Lock()
+	defer bucket.queueMu.Unlock()
+
+	wasFront := bucket.queue.Front() == el
+	bucket.queue.Remove(el)
+
+	if next := bucket.queue.Front(); wasFront && next != nil {
+		select {
+		case next.Value.(chan struct{}) <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// configureTokenBucket creates or reconfigures a token bucket. Acquires no
+// longer change its size or interval.
+func configureTokenBucket(name string, body []byte) (created bool, err error) {
+	config := &TokenBucketConfig{Size: 1, Interval: 1000}
+	if err = decodeConfig(body, config); err != nil {
+		return false, err
+	}
+	if config.Size == 0 {
+		return false, ErrInvalidSize
+	}
+	if config.Interval <= 0 {
+		return false, ErrInvalidInterval
+	}
+
+	return declareTokenBucket(name, *config), nil
+}
+
+func declareTokenBucket(name string, config TokenBucketConfig) (created bool) {
+	interval := time.Duration(config.Interval) * time.Millisecond
+
+	bucketsMutex.Lock()
+	bucket, ok := buckets[name]
+	if ok {
+		bucket.touch()
+	} else {
+		bucket = newTokenBucket(name, config.Size, interval)
+	}
+	bucketsMutex.Unlock()
+
+	bucket.mu.Lock()
+	defer bucket.mu.Unlock()
+
+	bucket.setLimits(config.Size, interval)
+	bucket.declared = true
+	return !ok
+}
+
+// undeclareTokenBucket lets acquires change the bucket again, once it's no
+// longer in the config file.
+func undeclareTokenBucket(name string) {
+	bucketsMutex.RLock()
+	bucket, ok := buckets[name]
+	bucketsMutex.RUnlock()
+	if !ok {
+		return
+	}
+
+	bucket.mu.Lock()
+	defer bucket.mu.Unlock()
+	bucket.declared = false
+}
+
+// busy reports whether the bucket has waiters or tokens taken in the current
+// interval. Evicting it then would hand out tokens early.
+func (bucket *TokenBucket) busy() bool {
+	bucket.refillTokens()
+
+	bucket.mu.RLock()
+	size := bucket.Size
+	bucket.mu.RUnlock()
+
+	return atomic.LoadInt64(&bucket.waiters) > 0 || atomic.LoadInt64(&bucket.available) < int64(size)
+}
+
 func deleteTokenBucket(name string) error {
 	bucketsMutex.Lock()
 	defer bucketsMutex.Unlock()
 
+	return removeTokenBucket(name)
+}
+
+// removeTokenBucket must be called with bucketsMutex held.
+func removeTokenBucket(name string) error {
 	_, ok := buckets[name]
 	if !ok {
 		return ErrNotFound
 	}
 
 	delete(buckets, name) // Remove from global map
+	forgetMetrics("tokenbucket", name)
 	return nil
 }
 
+func getTokenBucketConfig(name string) (interface{}, error) {
+	bucketsMutex.RLock()
+	bucket, ok := buckets[name]
+	bucketsMutex.RUnlock()
+
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	bucket.mu.RLock()
+	defer bucket.mu.RUnlock()
+
+	return &TokenBucketConfig{Size: bucket.Size, Interval: bucket.interval.Milliseconds()}, nil
+}
+
 func getTokenBucketStats(name string) (interface{}, error) {
 	bucketsMutex.RLock()
 	bucket, ok := buckets[name]
@@ -164,11 +442,17 @@ func getTokenBucketStats(name string) (interface{}, error) {
 		return nil, ErrNotFound
 	}
 
-	stats := &TokenBucketStats{}
-	*stats = *bucket.Stats // Copy stats
-	acquired := atomic.LoadUint64(&bucket.Stats.Acquired)
-	if acquired > 0 {
-		stats.AverageWaitTime = float64(atomic.LoadUint64(&bucket.Stats.TotalWaitTime)) / float64(acquired)
+	stats := &TokenBucketStats{
+		Acquired:      atomic.LoadUint64(&bucket.Stats.Acquired),
+		Tokens:        atomic.LoadUint64(&bucket.Stats.Tokens),
+		TotalWaitTime: atomic.LoadUint64(&bucket.Stats.TotalWaitTime),
+		TimedOut:      atomic.LoadUint64(&bucket.Stats.TimedOut),
+		Canceled:      atomic.LoadUint64(&bucket.Stats.Canceled),
+		Denied:        atomic.LoadUint64(&bucket.Stats.Denied),
+		CreatedAt:     bucket.Stats.CreatedAt,
+	}
+	if stats.Acquired > 0 {
+		stats.AverageWaitTime = float64(stats.TotalWaitTime) / float64(stats.Acquired)
 	}
 
 	return stats, nil
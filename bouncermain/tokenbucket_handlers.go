@@ -1,9 +1,11 @@
 package bouncermain
 
 import (
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/julienschmidt/httprouter"
@@ -11,6 +13,7 @@ import (
 
 type TokenBucketAcquireRequest struct {
 	Size     uint64        `schema:"size"`
+	Count    uint64        `schema:"count"`
 	Interval time.Duration `schema:"interval"`
 	MaxWait  time.Duration `schema:"maxwait"`
 	Arrival  time.Time     `schema:"-"`
@@ -19,8 +22,9 @@ type TokenBucketAcquireRequest struct {
 
 func newTokenBuckeAcquireRequest() *TokenBucketAcquireRequest {
 	return &TokenBucketAcquireRequest{
-		Size:     1,
-		Interval: time.Second,
+		Size:     0, // as configured
+		Count:    1,
+		Interval: 0,
 		MaxWait:  -1,
 		Arrival:  time.Now(),
 		ID:       "",
@@ -28,7 +32,22 @@ func newTokenBuckeAcquireRequest() *TokenBucketAcquireRequest {
 }
 
 func (r *TokenBucketAcquireRequest) Decode(values url.Values) error {
-	return decoder.Decode(r, values)
+	if err := decoder.Decode(r, values); err != nil {
+		return err
+	}
+	return checkBucketParams(values, r.Size, r.Interval)
+}
+
+// checkBucketParams rejects a zero size or interval given explicitly, as zero
+// values are taken from the bucket configuration.
+func checkBucketParams(values url.Values, size uint64, interval time.Duration) error {
+	if size == 0 && values.Has("size") {
+		return ErrInvalidSize
+	}
+	if interval < 0 || interval == 0 && values.Has("interval") {
+		return ErrInvalidInterval
+	}
+	return nil
 }
 
 // TokenBucketAcquireHandler godoc
@@ -39,12 +58,15 @@ func (r *TokenBucketAcquireRequest) Decode(values url.Values) error {
 // @Param name path string true "Token bucket name"
 // @Param size query int false "Bucket size" default(1)
 // @Param interval query int false "Refill interval" default(1000)
+// @Param count query int false "Number of tokens to take, at most size" default(1)
 // @Param maxwait query int false "Maximum wait time" default(-1)
 // @Param id query string false "Optional request identifier for logging"
 // @Success 204 {string} Reply "Token acquired successfully"
 // @Failure 400 {string} Reply "Bad Request - invalid parameters"
 // @Failure 404 {string} Reply "Not Found - token bucket not found
 // @Failure 408 {string} Reply "Request Timeout - `maxwait` exceeded"
+// @Failure 409 {string} Reply "Conflict - parameters differ from the configured ones"
+// @Failure 503 {string} Reply "Service Unavailable - server is shutting down"
 // @Router /tokenbucket/{name}/acquire [get]
 func TokenBucketAcquireHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	var err error
@@ -61,7 +83,7 @@ func TokenBucketAcquireHandler(w http.ResponseWriter, r *http.Request, ps httpro
 
 	if err == nil {
 		start := time.Now()
-		err = bucket.Acquire(req.MaxWait, req.Arrival)
+		err = bucket.Acquire(r.Context(), req.Count, req.MaxWait, req.Arrival)
 		wait = time.Since(start)
 
 		if err == nil {
@@ -72,7 +94,124 @@ func TokenBucketAcquireHandler(w http.ResponseWriter, r *http.Request, ps httpro
 	}
 
 	rep.WriteResponse(w, r, err)
-	logRequest(rep.Status, "tokenbucket", "acquire", ps[0].Value, wait, req).Send()
+	logRequest(r, rep.Status, "tokenbucket", "acquire", ps[0].Value, wait, req).Send()
+}
+
+type TokenBucketCheckRequest struct {
+	Size     uint64        `schema:"size"`
+	Interval time.Duration `schema:"interval"`
+	Count    uint64        `schema:"count"`
+	ID       string        `schema:"id"`
+}
+
+func newTokenBucketCheckRequest() *TokenBucketCheckRequest {
+	return &TokenBucketCheckRequest{
+		Size:     0, // as configured
+		Interval: 0,
+		Count:    1,
+		ID:       "",
+	}
+}
+
+func (r *TokenBucketCheckRequest) Decode(values url.Values) error {
+	if err := decoder.Decode(r, values); err != nil {
+		return err
+	}
+	return checkBucketParams(values, r.Size, r.Interval)
+}
+
+// TokenBucketCheckHandler godoc
+// @Summary Take tokens from a token bucket without waiting
+// @description.markdown tokenbucket_check.md
+// @Tags TokenBucket
+// @Produce json
+// @Param name path string true "Token bucket name"
+// @Param size query int false "Bucket size" default(1)
+// @Param interval query int false "Refill interval" default(1000)
+// @Param count query int false "Number of tokens to take, at most size" default(1)
+// @Param id query string false "Optional request identifier for logging"
+// @Success 200 {object} TokenBucketCheck "Tokens taken"
+// @Header 200,429 {integer} RateLimit-Limit "Bucket size"
+// @Header 200,429 {integer} RateLimit-Remaining "Tokens left"
+// @Header 200,429 {integer} RateLimit-Reset "Seconds until the next refill"
+// @Failure 400 {string} Reply "Bad Request - invalid parameters"
+// @Failure 409 {string} Reply "Conflict - parameters differ from the configured ones"
+// @Failure 429 {object} TokenBucketCheck "Too Many Requests - not enough tokens"
+// @Header 429 {integer} Retry-After "Seconds until the tokens are available"
+// @Router /tokenbucket/{name}/check [get]
+func TokenBucketCheckHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var err error
+	var bucket *TokenBucket
+	var check *TokenBucketCheck
+
+	req := newTokenBucketCheckRequest()
+	rep := newReply()
+
+	err = req.Decode(r.URL.Query())
+	if err == nil {
+		bucket, err = getTokenBucket(ps[0].Value, req.Size, req.Interval)
+	}
+
+	if err == nil {
+		check, err = bucket.Check(req.Count)
+	}
+
+	if err == nil {
+		h := w.Header()
+		h.Set("RateLimit-Limit", strconv.FormatUint(check.Limit, 10))
+		h.Set("RateLimit-Remaining", strconv.FormatUint(check.Remaining, 10))
+		h.Set("RateLimit-Reset", strconv.FormatInt(ceilSeconds(check.Reset), 10))
+		h.Set("Content-Type", "application/json")
+
+		rep.Status = http.StatusOK
+		if !check.Allowed {
+			h.Set("Retry-After", strconv.FormatInt(ceilSeconds(check.RetryAfter), 10))
+			rep.Status = http.StatusTooManyRequests
+		}
+
+		buf, _ := json.Marshal(check)
+		rep.Body = string(buf)
+	}
+
+	rep.WriteResponse(w, r, err)
+	logRequest(r, rep.Status, "tokenbucket", "check", ps[0].Value, 0, req).Send()
+}
+
+// ceilSeconds rounds milliseconds up to whole seconds, as used by the
+// Retry-After and RateLimit headers.
+func ceilSeconds(ms int64) int64 {
+	return (ms + 999) / 1000
+}
+
+// TokenBucketConfigureHandler godoc
+// @Summary Create or reconfigure a token bucket
+// @description.markdown tokenbucket_configure.md
+// @Tags TokenBucket
+// @Accept json
+// @Produce json
+// @Param name path string true "Token bucket name"
+// @Param config body TokenBucketConfig false "Token bucket configuration"
+// @Success 200 {object} TokenBucketConfig "Token bucket reconfigured"
+// @Success 201 {object} TokenBucketConfig "Token bucket created"
+// @Failure 400 {string} Reply "Bad Request - invalid configuration"
+// @Router /tokenbucket/{name} [put]
+func TokenBucketConfigureHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	status := ConfigureHandler(w, r, ps, configureTokenBucket, getTokenBucketConfig)
+	logRequest(r, status, "tokenbucket", "configure", ps[0].Value, 0, nil).Send()
+}
+
+// TokenBucketConfigHandler godoc
+// @Summary Get token bucket configuration
+// @Description Get the current configuration of a token bucket
+// @Tags TokenBucket
+// @Produce json
+// @Param name path string true "Token bucket name"
+// @Success 200 {object} TokenBucketConfig "Token bucket configuration"
+// @Failure 404 {string} Reply "Not Found - token bucket not found"
+// @Router /tokenbucket/{name} [get]
+func TokenBucketConfigHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	status := ConfigHandler(w, r, ps, getTokenBucketConfig)
+	logRequest(r, status, "tokenbucket", "config", ps[0].Value, 0, nil).Send()
 }
 
 // TokenBucketDeleteHandler godoc
@@ -86,7 +225,7 @@ func TokenBucketAcquireHandler(w http.ResponseWriter, r *http.Request, ps httpro
 // @Router /tokenbucket/{name} [delete]
 func TokenBucketDeleteHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	status := DeleteHandler(w, r, ps, deleteTokenBucket)
-	logRequest(status, "tokenbucket", "delete", ps[0].Value, 0, nil).Send()
+	logRequest(r, status, "tokenbucket", "delete", ps[0].Value, 0, nil).Send()
 }
 
 // TokenBucketStatsHandler godoc
@@ -101,5 +240,21 @@ func TokenBucketDeleteHandler(w http.ResponseWriter, r *http.Request, ps httprou
 // @Router /tokenbucket/{name}/stats [get]
 func TokenBucketStatsHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	status := StatsHandler(w, r, ps, getTokenBucketStats)
-	logRequest(status, "tokenbucket", "stats", ps[0].Value, 0, nil).Send()
+	logRequest(r, status, "tokenbucket", "stats", ps[0].Value, 0, nil).Send()
+}
+
+// TokenBucketListHandler godoc
+// @Summary List token buckets
+// @Description List existing token buckets with their configuration and stats, ordered by name
+// @Tags TokenBucket
+// @Produce json
+// @Param prefix query string false "Only list names starting with prefix"
+// @Param limit query int false "Maximum number of items" default(100)
+// @Param after query string false "Only list names after this one, as returned in 'next'"
+// @Success 200 {object} ResourceList "Token buckets"
+// @Failure 400 {string} Reply "Bad Request - invalid parameters"
+// @Router /tokenbucket/ [get]
+func TokenBucketListHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	status := ListHandler(w, r, "tokenbucket")
+	logRequest(r, status, "tokenbucket", "list", "", 0, nil).Send()
 }
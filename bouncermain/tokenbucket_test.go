@@ -75,3 +75,107 @@ func TestTokenBucketTimeout(t *testing.T) {
 	require.Nil(t, err)
 	require.Equal(t, 408, status)
 }
+
+func TestTokenBucketWeightedAcquire(t *testing.T) {
+	url := fmt.Sprintf("%s/tokenbucket/weighted/acquire?size=10&interval=60000&maxwait=0", server.URL)
+
+	status, _, err := GetRequest(url + "&count=4")
+	require.Nil(t, err)
+	require.Equal(t, 204, status)
+
+	status, _, err = GetRequest(url + "&count=4")
+	require.Nil(t, err)
+	require.Equal(t, 204, status)
+
+	// only 2 left, and none are taken by a request for more
+	status, _, err = GetRequest(url + "&count=4")
+	require.Nil(t, err)
+	require.Equal(t, 408, status)
+
+	status, _, err = GetRequest(url + "&count=2")
+	require.Nil(t, err)
+	require.Equal(t, 204, status)
+
+	status, body, err := GetRequest(fmt.Sprintf("%s/tokenbucket/weighted/stats", server.URL))
+	require.Nil(t, err)
+	require.Equal(t, 200, status)
+	require.Contains(t, body, `"acquired":3`)
+	require.Contains(t, body, `"tokens":10`)
+}
+
+func TestTokenBucketCountLargerThanSize(t *testing.T) {
+	for _, count := range []int{0, 11} {
+		url := fmt.Sprintf("%s/tokenbucket/weighted-invalid/acquire?size=10&count=%d", server.URL, count)
+		status, _, err := GetRequest(url)
+		require.Nil(t, err)
+		require.Equal(t, 400, status)
+	}
+}
+
+func TestTokenBucketWeightedAcquireNotStarved(t *testing.T) {
+	url := fmt.Sprintf("%s/tokenbucket/weighted-starve/acquire?size=10&interval=200", server.URL)
+
+	// drain the bucket
+	status, _, err := GetRequest(url + "&count=10")
+	require.Nil(t, err)
+	require.Equal(t, 204, status)
+
+	done := make(chan int)
+	go func() {
+		status, _, _ := GetRequest(url + "&count=10&maxwait=1000")
+		done <- status
+	}()
+
+	// let the large request get in line, then keep asking for single
+	// tokens behind it
+	time.Sleep(50 * time.Millisecond)
+	stop := make(chan struct{})
+	defer close(stop)
+	for i := 0; i < 5; i++ {
+		go func() {
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					GetRequest(url + "&count=1&maxwait=100")
+				}
+			}
+		}()
+	}
+
+	require.Equal(t, 204, <-done)
+}
+
+func TestTokenBucketCheck(t *testing.T) {
+	url := fmt.Sprintf("%s/tokenbucket/check1/check?size=3&interval=10000", server.URL)
+
+	status, body, header, err := GetRequestWithHeader(url + "&count=2")
+	require.Nil(t, err)
+	require.Equal(t, 200, status)
+	require.Contains(t, body, `"allowed":true`)
+	require.Equal(t, "3", header.Get("RateLimit-Limit"))
+	require.Equal(t, "1", header.Get("RateLimit-Remaining"))
+	require.Equal(t, "10", header.Get("RateLimit-Reset"))
+	require.Empty(t, header.Get("Retry-After"))
+
+	// denied without waiting, and without taking the token left
+	start := time.Now()
+	status, body, header, err = GetRequestWithHeader(url + "&count=2")
+	require.Nil(t, err)
+	require.Equal(t, 429, status)
+	require.Less(t, time.Since(start), time.Second)
+	require.Contains(t, body, `"allowed":false`)
+	require.Equal(t, "1", header.Get("RateLimit-Remaining"))
+	require.Equal(t, "10", header.Get("Retry-After"))
+
+	status, _, _, err = GetRequestWithHeader(url + "&count=1")
+	require.Nil(t, err)
+	require.Equal(t, 200, status)
+
+	status, body, err = GetRequest(fmt.Sprintf("%s/tokenbucket/check1/stats", server.URL))
+	require.Nil(t, err)
+	require.Equal(t, 200, status)
+	require.Contains(t, body, `"acquired":2`)
+	require.Contains(t, body, `"denied":1`)
+}
@@ -2,6 +2,7 @@ package bouncermain
 
 import (
 	"reflect"
+	"sync/atomic"
 	"time"
 )
 
@@ -53,3 +54,13 @@ func RecvTimeout(ch <-chan bool, d time.Duration) (v bool, err error) {
 	}
 	return v, err
 }
+
+// lowerInt64 atomically lowers the value at addr to at most value.
+func lowerInt64(addr *int64, value int64) {
+	for {
+		current := atomic.LoadInt64(addr)
+		if current <= value || atomic.CompareAndSwapInt64(addr, current, value) {
+			return
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package bouncermain
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// waitFor waits up to maxwait for readyC to be closed, or forever if maxwait
+// is negative. It gives up early if the client goes away or the server is
+// shutting down.
+func waitFor(ctx context.Context, readyC <-chan struct{}, maxwait time.Duration) error {
+	if maxwait == 0 {
+		select {
+		case <-readyC:
+			return nil
+		default:
+			return ErrTimedOut
+		}
+	}
+
+	var timeoutC <-chan time.Time
+	if maxwait > 0 {
+		timer := time.NewTimer(maxwait)
+		defer timer.Stop()
+		timeoutC = timer.C
+	}
+
+	select {
+	case <-readyC:
+		return nil
+	case <-timeoutC:
+		return ErrTimedOut
+	case <-ctx.Done():
+		return ErrCanceled
+	case <-shuttingDown:
+		return ErrShuttingDown
+	}
+}
+
+// sleep waits for d, giving up early like waitFor.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ErrCanceled
+	case <-shuttingDown:
+		return ErrShuttingDown
+	}
+}
+
+// watchEach runs watch for each of n objects concurrently, until all of them
+// return, or one fails and the others are canceled.
+func watchEach(ctx context.Context, n int, watch func(ctx context.Context, i int) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errC := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() { errC <- watch(ctx, i) }()
+	}
+
+	var first error
+	for i := 0; i < n; i++ {
+		if err := <-errC; err != nil && first == nil {
+			first = err
+			cancel()
+		}
+	}
+	return first
+}
+
+// countGiveUp counts a failed wait as timed out or canceled. Shutdowns
+// aren't counted.
+func countGiveUp(err error, timedOut *uint64, canceled *uint64) {
+	switch {
+	case errors.Is(err, ErrTimedOut):
+		atomic.AddUint64(timedOut, 1)
+	case errors.Is(err, ErrCanceled):
+		atomic.AddUint64(canceled, 1)
+	}
+}
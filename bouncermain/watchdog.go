@@ -1,6 +1,9 @@
 package bouncermain
 
 import (
+	"cmp"
+	"context"
+	"fmt"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -9,15 +12,29 @@ import (
 type WatchdogStats struct {
 	Waited    uint64 `json:"waited"`
 	TimedOut  uint64 `json:"timed_out"`
+	Canceled  uint64 `json:"canceled"`
 	Kicks     uint64 `json:"kicks"`
 	LastKick  string `json:"last_kick"`
 	CreatedAt string `json:"created_at"`
 }
 
+type WatchdogConfig struct {
+	Expires   int64  `json:"expires"` // milliseconds, as given in the last kick
+	ExpiresAt string `json:"expires_at"`
+}
+
 type Watchdog struct {
-	Name    string
-	Stats   *WatchdogStats
-	expires int64 // atomic unix nano when watchdog expires
+	Name      string
+	Stats     *WatchdogStats
+	mu        *sync.Mutex   // serializes kicks
+	timeout   time.Duration // expiration given in the last kick
+	expires   int64         // atomic unix nano when watchdog expires
+	timer     *time.Timer   // fires at expires, reset on each kick
+	expiredC  chan struct{} // closed when the watchdog expires
+	kickedC   chan struct{} // closed when a kick replaces a closed expiredC
+	expiredAt time.Time     // when expiredC was last closed
+	waiters   int64
+	idleTracker
 }
 
 var watchdogs = map[string]*Watchdog{}
@@ -26,62 +43,142 @@ var watchdogsMutex = &sync.RWMutex{}
 func newWatchdog(name string, expires time.Duration) *Watchdog {
 	now := time.Now()
 	watchdog := &Watchdog{
-		Name:    name,
-		Stats:   &WatchdogStats{CreatedAt: now.Format(time.RFC3339)},
-		expires: now.Add(expires).UnixNano(),
+		Name:     name,
+		Stats:    &WatchdogStats{CreatedAt: now.Format(time.RFC3339)},
+		mu:       &sync.Mutex{},
+		timeout:  expires,
+		expires:  now.Add(expires).UnixNano(),
+		expiredC: make(chan struct{}),
+		kickedC:  make(chan struct{}),
 	}
+	watchdog.timer = time.AfterFunc(expires, watchdog.expire)
+	watchdog.touch()
 	watchdogs[name] = watchdog
 	return watchdog
 }
 
 func (w *Watchdog) Kick(expires time.Duration) {
-	atomic.StoreInt64(&w.expires, time.Now().Add(expires).UnixNano())
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	deadline := time.Now().Add(expires).UnixNano()
+	w.timeout = expires
+	w.setDeadline(deadline)
 	atomic.AddUint64(&w.Stats.Kicks, 1)
 	w.Stats.LastKick = time.Now().Format(time.RFC3339)
+
+	recordChange(stateRecord{Op: opWatchdogKick, Type: "watchdog", Name: w.Name, Expires: deadline})
+}
+
+// setDeadline moves the expiration and rearms the timer. Must be called
+// with the watchdog mutex held.
+func (w *Watchdog) setDeadline(deadline int64) {
+	atomic.StoreInt64(&w.expires, deadline)
+
+	// waiters arriving after a kick wait for the new deadline
+	select {
+	case <-w.expiredC:
+		w.expiredC = make(chan struct{})
+		close(w.kickedC)
+		w.kickedC = make(chan struct{})
+	default:
+	}
+
+	w.timer.Reset(time.Until(time.Unix(0, deadline)))
 }
 
-func (w *Watchdog) Wait(maxwait time.Duration) error {
-	deadline := time.Now().Add(maxwait)
+// expire wakes every waiter. A timer that fires just as the watchdog is
+// kicked finds the deadline moved and does nothing, the reset timer fires
+// again later.
+func (w *Watchdog) expire() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 
-	// Check if already expired
-	if time.Now().UnixNano() >= atomic.LoadInt64(&w.expires) {
-		atomic.AddUint64(&w.Stats.Waited, 1)
-		return nil
+	if time.Now().UnixNano() < atomic.LoadInt64(&w.expires) {
+		return
 	}
 
+	select {
+	case <-w.expiredC:
+	default:
+		w.expiredAt = time.Now()
+		close(w.expiredC)
+	}
+}
+
+func (w *Watchdog) Wait(ctx context.Context, maxwait time.Duration) error {
+	started := time.Now()
+
+	atomic.AddInt64(&w.waiters, 1)
+	defer atomic.AddInt64(&w.waiters, -1)
+
+	w.mu.Lock()
+	expiredC := w.expiredC
+	w.mu.Unlock()
+
+	if err := waitFor(ctx, expiredC, maxwait); err != nil {
+		countGiveUp(err, &w.Stats.TimedOut, &w.Stats.Canceled)
+		return err
+	}
+
+	atomic.AddUint64(&w.Stats.Waited, 1)
+	observeWait("watchdog", w.Name, time.Since(started))
+	return nil
+}
+
+// waitExpiry waits for an expiration other than the one of last, which is
+// nil on the first call, and returns its channel and time. An expiration
+// already reached is returned at once, so watchers see each one exactly once.
+func (w *Watchdog) waitExpiry(ctx context.Context, last chan struct{}) (chan struct{}, time.Time, error) {
+	atomic.AddInt64(&w.waiters, 1)
+	defer atomic.AddInt64(&w.waiters, -1)
+
 	for {
-		// Check timeout
-		if maxwait >= 0 && time.Now().After(deadline) {
-			atomic.AddUint64(&w.Stats.TimedOut, 1)
-			return ErrTimedOut
-		}
+		w.mu.Lock()
+		expiredC, kickedC := w.expiredC, w.kickedC
+		w.mu.Unlock()
 
-		// Check expiration
-		if time.Now().UnixNano() >= atomic.LoadInt64(&w.expires) {
-			atomic.AddUint64(&w.Stats.Waited, 1)
-			return nil
+		// the last expiration was seen, wait for a kick to replace it
+		readyC := expiredC
+		if expiredC == last {
+			readyC = kickedC
 		}
-
-		// Sleep until next check or deadline
-		now := time.Now()
-		sleepUntil := time.Unix(0, atomic.LoadInt64(&w.expires))
-		if maxwait >= 0 && deadline.Before(sleepUntil) {
-			sleepUntil = deadline
+		if err := waitFor(ctx, readyC, -1); err != nil {
+			return nil, time.Time{}, err
+		}
+		if expiredC == last {
+			continue
 		}
 
-		time.Sleep(min(sleepUntil.Sub(now), maxSleepDuration))
+		w.mu.Lock()
+		expiredAt := w.expiredAt
+		w.mu.Unlock()
+		return expiredC, expiredAt, nil
 	}
 }
 
+// getWatchdog returns the watchdog, created on first use expiring after the
+// configured time, or the one given, or a minute. A zero expires isn't given.
 func getWatchdog(name string, expires time.Duration) (watchdog *Watchdog, err error) {
 	watchdogsMutex.RLock()
 	watchdog, ok := watchdogs[name]
+	if ok {
+		watchdog.touch()
+	}
 	watchdogsMutex.RUnlock()
 
 	if ok {
 		return watchdog, nil
 	}
 
+	config, configured := configuredWatchdog(name)
+	if strictMode && !configured {
+		return nil, ErrNotFound
+	}
+	if configured {
+		expires = time.Duration(config.Expires) * time.Millisecond
+	}
+
 	// Watchdog doesn't exist, need to create it
 	watchdogsMutex.Lock()
 	defer watchdogsMutex.Unlock()
@@ -89,13 +186,23 @@ func getWatchdog(name string, expires time.Duration) (watchdog *Watchdog, err er
 	// Check again in case another goroutine created it
 	watchdog, ok = watchdogs[name]
 	if ok {
+		watchdog.touch()
 		return watchdog, nil
 	}
 
-	watchdog = newWatchdog(name, expires)
+	watchdog = newWatchdog(name, cmp.Or(expires, time.Minute))
 	return watchdog, err
 }
 
+// kickExpiry returns the expiration for a kick of a configured watchdog that
+// doesn't give one.
+func kickExpiry(name string, expires time.Duration, given bool) time.Duration {
+	if config, ok := configuredWatchdog(name); ok && !given {
+		return time.Duration(config.Expires) * time.Millisecond
+	}
+	return expires
+}
+
 func getWatchdogStats(name string) (interface{}, error) {
 	watchdogsMutex.RLock()
 	defer watchdogsMutex.RUnlock()
@@ -105,21 +212,116 @@ func getWatchdogStats(name string) (interface{}, error) {
 		return nil, ErrNotFound
 	}
 
-	stats := &WatchdogStats{}
-	*stats = *watchdog.Stats
+	stats := &WatchdogStats{
+		Waited:    atomic.LoadUint64(&watchdog.Stats.Waited),
+		TimedOut:  atomic.LoadUint64(&watchdog.Stats.TimedOut),
+		Canceled:  atomic.LoadUint64(&watchdog.Stats.Canceled),
+		Kicks:     atomic.LoadUint64(&watchdog.Stats.Kicks),
+		CreatedAt: watchdog.Stats.CreatedAt,
+	}
+
+	// written by kicks
+	watchdog.mu.Lock()
+	stats.LastKick = watchdog.Stats.LastKick
+	watchdog.mu.Unlock()
 
 	return stats, nil
 }
 
+func getWatchdogConfig(name string) (interface{}, error) {
+	watchdogsMutex.RLock()
+	watchdog, ok := watchdogs[name]
+	watchdogsMutex.RUnlock()
+
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	watchdog.mu.Lock()
+	defer watchdog.mu.Unlock()
+
+	return &WatchdogConfig{
+		Expires:   watchdog.timeout.Milliseconds(),
+		ExpiresAt: time.Unix(0, atomic.LoadInt64(&watchdog.expires)).Format(time.RFC3339Nano),
+	}, nil
+}
+
+// configureWatchdog creates a watchdog expiring after the given time. Each
+// kick sets its own expiration, so an existing watchdog is left as it is.
+func configureWatchdog(name string, body []byte) (created bool, err error) {
+	config := &WatchdogConfig{Expires: 60000}
+	if err = decodeConfig(body, config); err != nil {
+		return false, err
+	}
+	if config.Expires < 0 {
+		return false, fmt.Errorf("%w: 'expires' must not be negative", ErrInvalidConfig)
+	}
+
+	return declareWatchdog(name, *config), nil
+}
+
+func declareWatchdog(name string, config WatchdogConfig) (created bool) {
+	watchdogsMutex.Lock()
+	defer watchdogsMutex.Unlock()
+
+	watchdog, ok := watchdogs[name]
+	if ok {
+		watchdog.touch()
+	} else {
+		newWatchdog(name, time.Duration(config.Expires)*time.Millisecond)
+	}
+	return !ok
+}
+
+// busy reports whether the watchdog has waiters or hasn't expired yet.
+func (w *Watchdog) busy() bool {
+	return atomic.LoadInt64(&w.waiters) > 0 || time.Now().UnixNano() < atomic.LoadInt64(&w.expires)
+}
+
 func deleteWatchdog(name string) error {
 	watchdogsMutex.Lock()
 	defer watchdogsMutex.Unlock()
 
+	return removeWatchdog(name)
+}
+
+// removeWatchdog must be called with watchdogsMutex held. The timer is left
+// running, so clients already waiting are still notified when it expires.
+func removeWatchdog(name string) error {
 	_, ok := watchdogs[name]
 	if !ok {
 		return ErrNotFound
 	}
 
 	delete(watchdogs, name)
+	forgetMetrics("watchdog", name)
+	recordChange(stateRecord{Op: opDelete, Type: "watchdog", Name: name})
 	return nil
 }
+
+func restoreWatchdog(rec stateRecord) {
+	// restored even in strict mode, so not through getWatchdog
+	watchdogsMutex.Lock()
+	watchdog, ok := watchdogs[rec.Name]
+	if !ok {
+		watchdog = newWatchdog(rec.Name, 0)
+	}
+	watchdogsMutex.Unlock()
+
+	watchdog.mu.Lock()
+	defer watchdog.mu.Unlock()
+
+	watchdog.setDeadline(rec.Expires)
+	recordChange(rec)
+}
+
+func captureWatchdogs() []stateRecord {
+	watchdogsMutex.RLock()
+	defer watchdogsMutex.RUnlock()
+
+	records := make([]stateRecord, 0, len(watchdogs))
+	for name, watchdog := range watchdogs {
+		records = append(records, stateRecord{Op: opWatchdogKick, Type: "watchdog", Name: name, Expires: atomic.LoadInt64(&watchdog.expires)})
+	}
+	return records
+}
@@ -53,6 +53,7 @@ func (r *WatchdogKickRequest) Decode(values url.Values) error {
 // @Failure 400 {string} Reply "Bad Request - invalid parameters"
 // @Failure 404 {string} Reply "Not Found - watchdog not found"
 // @Failure 408 {string} Reply "Request Timeout - maxWait exceeded"
+// @Failure 503 {string} Reply "Service Unavailable - server is shutting down"
 // @Router /watchdog/{name}/wait [get]
 func WatchdogWaitHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	var err error
@@ -64,12 +65,12 @@ func WatchdogWaitHandler(w http.ResponseWriter, r *http.Request, ps httprouter.P
 
 	err = req.Decode(r.URL.Query())
 	if err == nil {
-		watchdog, err = getWatchdog(ps[0].Value, time.Minute) // Default expiry
+		watchdog, err = getWatchdog(ps[0].Value, 0)
 	}
 
 	if err == nil {
 		start := time.Now()
-		err = watchdog.Wait(req.MaxWait)
+		err = watchdog.Wait(r.Context(), req.MaxWait)
 		wait = time.Since(start)
 
 		if errors.Is(err, ErrTimedOut) {
@@ -81,7 +82,7 @@ func WatchdogWaitHandler(w http.ResponseWriter, r *http.Request, ps httprouter.P
 	}
 
 	rep.WriteResponse(w, r, err)
-	logRequest(rep.Status, "watchdog", "wait", ps[0].Value, wait, req).Send()
+	logRequest(r, rep.Status, "watchdog", "wait", ps[0].Value, wait, req).Send()
 }
 
 // WatchdogKickHandler godoc
@@ -105,6 +106,7 @@ func WatchdogKickHandler(w http.ResponseWriter, r *http.Request, ps httprouter.P
 
 	err = req.Decode(r.URL.Query())
 	if err == nil {
+		req.Expires = kickExpiry(ps[0].Value, req.Expires, r.URL.Query().Has("expires"))
 		watchdog, err = getWatchdog(ps[0].Value, req.Expires)
 	}
 
@@ -116,7 +118,38 @@ func WatchdogKickHandler(w http.ResponseWriter, r *http.Request, ps httprouter.P
 	}
 
 	rep.WriteResponse(w, r, err)
-	logRequest(rep.Status, "watchdog", "kick", ps[0].Value, 0, req).Send()
+	logRequest(r, rep.Status, "watchdog", "kick", ps[0].Value, 0, req).Send()
+}
+
+// WatchdogConfigureHandler godoc
+// @Summary Create or reconfigure a watchdog
+// @description.markdown watchdog_configure.md
+// @Tags Watchdog
+// @Accept json
+// @Produce json
+// @Param name path string true "Watchdog name"
+// @Param config body WatchdogConfig false "Watchdog configuration"
+// @Success 200 {object} WatchdogConfig "Watchdog reconfigured"
+// @Success 201 {object} WatchdogConfig "Watchdog created"
+// @Failure 400 {string} Reply "Bad Request - invalid configuration"
+// @Router /watchdog/{name} [put]
+func WatchdogConfigureHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	status := ConfigureHandler(w, r, ps, configureWatchdog, getWatchdogConfig)
+	logRequest(r, status, "watchdog", "configure", ps[0].Value, 0, nil).Send()
+}
+
+// WatchdogConfigHandler godoc
+// @Summary Get watchdog configuration
+// @Description Get the current configuration of a watchdog
+// @Tags Watchdog
+// @Produce json
+// @Param name path string true "Watchdog name"
+// @Success 200 {object} WatchdogConfig "Watchdog configuration"
+// @Failure 404 {string} Reply "Not Found - watchdog not found"
+// @Router /watchdog/{name} [get]
+func WatchdogConfigHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	status := ConfigHandler(w, r, ps, getWatchdogConfig)
+	logRequest(r, status, "watchdog", "config", ps[0].Value, 0, nil).Send()
 }
 
 // WatchdogDeleteHandler godoc
@@ -130,7 +163,7 @@ func WatchdogKickHandler(w http.ResponseWriter, r *http.Request, ps httprouter.P
 // @Router /watchdog/{name} [delete]
 func WatchdogDeleteHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	status := DeleteHandler(w, r, ps, deleteWatchdog)
-	logRequest(status, "watchdog", "delete", ps[0].Value, 0, nil).Send()
+	logRequest(r, status, "watchdog", "delete", ps[0].Value, 0, nil).Send()
 }
 
 // WatchdogStatsHandler godoc
@@ -144,5 +177,21 @@ func WatchdogDeleteHandler(w http.ResponseWriter, r *http.Request, ps httprouter
 // @Router /watchdog/{name}/stats [get]
 func WatchdogStatsHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	status := StatsHandler(w, r, ps, getWatchdogStats)
-	logRequest(status, "watchdog", "stats", ps[0].Value, 0, nil).Send()
+	logRequest(r, status, "watchdog", "stats", ps[0].Value, 0, nil).Send()
+}
+
+// WatchdogListHandler godoc
+// @Summary List watchdogs
+// @Description List existing watchdogs with their configuration and stats, ordered by name
+// @Tags Watchdog
+// @Produce json
+// @Param prefix query string false "Only list names starting with prefix"
+// @Param limit query int false "Maximum number of items" default(100)
+// @Param after query string false "Only list names after this one, as returned in 'next'"
+// @Success 200 {object} ResourceList "Watchdogs"
+// @Failure 400 {string} Reply "Bad Request - invalid parameters"
+// @Router /watchdog/ [get]
+func WatchdogListHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	status := ListHandler(w, r, "watchdog")
+	logRequest(r, status, "watchdog", "list", "", 0, nil).Send()
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -73,3 +74,49 @@ func TestWatchdogMultipleWaiters(t *testing.T) {
 	}
 	require.Equal(t, 5, successCount)
 }
+
+func TestWatchdogKickShortensDeadline(t *testing.T) {
+	baseURL := fmt.Sprintf("%s/watchdog/shorten-test", server.URL)
+
+	status, _, err := GetRequest(fmt.Sprintf("%s/kick?expires=60000", baseURL))
+	require.Nil(t, err)
+	require.Equal(t, 204, status)
+
+	results := make(chan int, 1)
+	started := time.Now()
+	go func() {
+		status, _, _ := GetRequest(fmt.Sprintf("%s/wait?maxWait=5000", baseURL))
+		results <- status
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	status, _, err = GetRequest(fmt.Sprintf("%s/kick?expires=50", baseURL))
+	require.Nil(t, err)
+	require.Equal(t, 204, status)
+
+	// the waiter is notified at the new deadline, not the original one
+	require.Equal(t, 204, <-results)
+	require.Less(t, time.Since(started), time.Second)
+}
+
+func TestWatchdogWaitAfterKickAgain(t *testing.T) {
+	baseURL := fmt.Sprintf("%s/watchdog/rearm-test", server.URL)
+
+	status, _, err := GetRequest(fmt.Sprintf("%s/kick?expires=10", baseURL))
+	require.Nil(t, err)
+	require.Equal(t, 204, status)
+
+	time.Sleep(50 * time.Millisecond)
+	status, _, err = GetRequest(fmt.Sprintf("%s/wait?maxWait=0", baseURL))
+	require.Nil(t, err)
+	require.Equal(t, 204, status)
+
+	// kicking an expired watchdog makes new waiters block again
+	status, _, err = GetRequest(fmt.Sprintf("%s/kick?expires=1000", baseURL))
+	require.Nil(t, err)
+	require.Equal(t, 204, status)
+
+	status, _, err = GetRequest(fmt.Sprintf("%s/wait?maxWait=50", baseURL))
+	require.Nil(t, err)
+	require.Equal(t, 408, status)
+}
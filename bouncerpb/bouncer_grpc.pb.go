@@ -0,0 +1,1252 @@
+// The bouncer gRPC API, mirroring the HTTP one.
+//
+// Times are in milliseconds. A maxwait of -1, or left unset, waits without a
+// limit. Settings left unset or zero are taken from the object
+// configuration, as when omitted from an HTTP call. Calls take objects
+// within the namespace in the "bouncer-namespace" metadata, if any, and the
+// API token in the "authorization" metadata, as "Bearer <token>".
+//
+// Errors have the message of the HTTP API, with these codes:
+//
+//   DEADLINE_EXCEEDED    'maxwait' exceeded
+//   FAILED_PRECONDITION  key released or expired, event or barrier closed,
+//                        stale fencing token or configuration conflict
+//   NOT_FOUND            object not found, in strict mode
+//   INVALID_ARGUMENT     invalid parameters
+//   UNAUTHENTICATED      missing or unknown token
+//   PERMISSION_DENIED    token not allowed to make the call
+//   RESOURCE_EXHAUSTED   namespace at its object limit
+//   UNAVAILABLE          shutting down, or not the cluster leader
+//   CANCELLED            client went away while waiting
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: bouncer.proto
+
+package bouncerpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Semaphore_Acquire_FullMethodName = "/bouncer.v1.Semaphore/Acquire"
+	Semaphore_Release_FullMethodName = "/bouncer.v1.Semaphore/Release"
+	Semaphore_Renew_FullMethodName   = "/bouncer.v1.Semaphore/Renew"
+)
+
+// SemaphoreClient is the client API for Semaphore service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type SemaphoreClient interface {
+	Acquire(ctx context.Context, in *SemaphoreAcquireRequest, opts ...grpc.CallOption) (*KeyReply, error)
+	Release(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (*Empty, error)
+	Renew(ctx context.Context, in *RenewRequest, opts ...grpc.CallOption) (*Empty, error)
+}
+
+type semaphoreClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSemaphoreClient(cc grpc.ClientConnInterface) SemaphoreClient {
+	return &semaphoreClient{cc}
+}
+
+func (c *semaphoreClient) Acquire(ctx context.Context, in *SemaphoreAcquireRequest, opts ...grpc.CallOption) (*KeyReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(KeyReply)
+	err := c.cc.Invoke(ctx, Semaphore_Acquire_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *semaphoreClient) Release(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, Semaphore_Release_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *semaphoreClient) Renew(ctx context.Context, in *RenewRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, Semaphore_Renew_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SemaphoreServer is the server API for Semaphore service.
+// All implementations must embed UnimplementedSemaphoreServer
+// for forward compatibility.
+type SemaphoreServer interface {
+	Acquire(context.Context, *SemaphoreAcquireRequest) (*KeyReply, error)
+	Release(context.Context, *KeyRequest) (*Empty, error)
+	Renew(context.Context, *RenewRequest) (*Empty, error)
+	mustEmbedUnimplementedSemaphoreServer()
+}
+
+// UnimplementedSemaphoreServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedSemaphoreServer struct{}
+
+func (UnimplementedSemaphoreServer) Acquire(context.Context, *SemaphoreAcquireRequest) (*KeyReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Acquire not implemented")
+}
+func (UnimplementedSemaphoreServer) Release(context.Context, *KeyRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Release not implemented")
+}
+func (UnimplementedSemaphoreServer) Renew(context.Context, *RenewRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Renew not implemented")
+}
+func (UnimplementedSemaphoreServer) mustEmbedUnimplementedSemaphoreServer() {}
+func (UnimplementedSemaphoreServer) testEmbeddedByValue()                   {}
+
+// UnsafeSemaphoreServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SemaphoreServer will
+// result in compilation errors.
+type UnsafeSemaphoreServer interface {
+	mustEmbedUnimplementedSemaphoreServer()
+}
+
+func RegisterSemaphoreServer(s grpc.ServiceRegistrar, srv SemaphoreServer) {
+	// If the following call pancis, it indicates UnimplementedSemaphoreServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Semaphore_ServiceDesc, srv)
+}
+
+func _Semaphore_Acquire_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SemaphoreAcquireRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SemaphoreServer).Acquire(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Semaphore_Acquire_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SemaphoreServer).Acquire(ctx, req.(*SemaphoreAcquireRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Semaphore_Release_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SemaphoreServer).Release(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Semaphore_Release_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SemaphoreServer).Release(ctx, req.(*KeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Semaphore_Renew_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenewRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SemaphoreServer).Renew(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Semaphore_Renew_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SemaphoreServer).Renew(ctx, req.(*RenewRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Semaphore_ServiceDesc is the grpc.ServiceDesc for Semaphore service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Semaphore_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "bouncer.v1.Semaphore",
+	HandlerType: (*SemaphoreServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Acquire",
+			Handler:    _Semaphore_Acquire_Handler,
+		},
+		{
+			MethodName: "Release",
+			Handler:    _Semaphore_Release_Handler,
+		},
+		{
+			MethodName: "Renew",
+			Handler:    _Semaphore_Renew_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "bouncer.proto",
+}
+
+const (
+	RWLock_RLock_FullMethodName  = "/bouncer.v1.RWLock/RLock"
+	RWLock_Lock_FullMethodName   = "/bouncer.v1.RWLock/Lock"
+	RWLock_Unlock_FullMethodName = "/bouncer.v1.RWLock/Unlock"
+)
+
+// RWLockClient is the client API for RWLock service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type RWLockClient interface {
+	RLock(ctx context.Context, in *LockRequest, opts ...grpc.CallOption) (*KeyReply, error)
+	Lock(ctx context.Context, in *LockRequest, opts ...grpc.CallOption) (*KeyReply, error)
+	Unlock(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (*Empty, error)
+}
+
+type rWLockClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRWLockClient(cc grpc.ClientConnInterface) RWLockClient {
+	return &rWLockClient{cc}
+}
+
+func (c *rWLockClient) RLock(ctx context.Context, in *LockRequest, opts ...grpc.CallOption) (*KeyReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(KeyReply)
+	err := c.cc.Invoke(ctx, RWLock_RLock_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rWLockClient) Lock(ctx context.Context, in *LockRequest, opts ...grpc.CallOption) (*KeyReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(KeyReply)
+	err := c.cc.Invoke(ctx, RWLock_Lock_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rWLockClient) Unlock(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, RWLock_Unlock_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RWLockServer is the server API for RWLock service.
+// All implementations must embed UnimplementedRWLockServer
+// for forward compatibility.
+type RWLockServer interface {
+	RLock(context.Context, *LockRequest) (*KeyReply, error)
+	Lock(context.Context, *LockRequest) (*KeyReply, error)
+	Unlock(context.Context, *KeyRequest) (*Empty, error)
+	mustEmbedUnimplementedRWLockServer()
+}
+
+// UnimplementedRWLockServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedRWLockServer struct{}
+
+func (UnimplementedRWLockServer) RLock(context.Context, *LockRequest) (*KeyReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RLock not implemented")
+}
+func (UnimplementedRWLockServer) Lock(context.Context, *LockRequest) (*KeyReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Lock not implemented")
+}
+func (UnimplementedRWLockServer) Unlock(context.Context, *KeyRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Unlock not implemented")
+}
+func (UnimplementedRWLockServer) mustEmbedUnimplementedRWLockServer() {}
+func (UnimplementedRWLockServer) testEmbeddedByValue()                {}
+
+// UnsafeRWLockServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RWLockServer will
+// result in compilation errors.
+type UnsafeRWLockServer interface {
+	mustEmbedUnimplementedRWLockServer()
+}
+
+func RegisterRWLockServer(s grpc.ServiceRegistrar, srv RWLockServer) {
+	// If the following call pancis, it indicates UnimplementedRWLockServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&RWLock_ServiceDesc, srv)
+}
+
+func _RWLock_RLock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RWLockServer).RLock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RWLock_RLock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RWLockServer).RLock(ctx, req.(*LockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RWLock_Lock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RWLockServer).Lock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RWLock_Lock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RWLockServer).Lock(ctx, req.(*LockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RWLock_Unlock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RWLockServer).Unlock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RWLock_Unlock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RWLockServer).Unlock(ctx, req.(*KeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// RWLock_ServiceDesc is the grpc.ServiceDesc for RWLock service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RWLock_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "bouncer.v1.RWLock",
+	HandlerType: (*RWLockServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "RLock",
+			Handler:    _RWLock_RLock_Handler,
+		},
+		{
+			MethodName: "Lock",
+			Handler:    _RWLock_Lock_Handler,
+		},
+		{
+			MethodName: "Unlock",
+			Handler:    _RWLock_Unlock_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "bouncer.proto",
+}
+
+const (
+	TokenBucket_Acquire_FullMethodName = "/bouncer.v1.TokenBucket/Acquire"
+)
+
+// TokenBucketClient is the client API for TokenBucket service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type TokenBucketClient interface {
+	Acquire(ctx context.Context, in *TokenBucketAcquireRequest, opts ...grpc.CallOption) (*Empty, error)
+}
+
+type tokenBucketClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTokenBucketClient(cc grpc.ClientConnInterface) TokenBucketClient {
+	return &tokenBucketClient{cc}
+}
+
+func (c *tokenBucketClient) Acquire(ctx context.Context, in *TokenBucketAcquireRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, TokenBucket_Acquire_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TokenBucketServer is the server API for TokenBucket service.
+// All implementations must embed UnimplementedTokenBucketServer
+// for forward compatibility.
+type TokenBucketServer interface {
+	Acquire(context.Context, *TokenBucketAcquireRequest) (*Empty, error)
+	mustEmbedUnimplementedTokenBucketServer()
+}
+
+// UnimplementedTokenBucketServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedTokenBucketServer struct{}
+
+func (UnimplementedTokenBucketServer) Acquire(context.Context, *TokenBucketAcquireRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Acquire not implemented")
+}
+func (UnimplementedTokenBucketServer) mustEmbedUnimplementedTokenBucketServer() {}
+func (UnimplementedTokenBucketServer) testEmbeddedByValue()                     {}
+
+// UnsafeTokenBucketServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TokenBucketServer will
+// result in compilation errors.
+type UnsafeTokenBucketServer interface {
+	mustEmbedUnimplementedTokenBucketServer()
+}
+
+func RegisterTokenBucketServer(s grpc.ServiceRegistrar, srv TokenBucketServer) {
+	// If the following call pancis, it indicates UnimplementedTokenBucketServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&TokenBucket_ServiceDesc, srv)
+}
+
+func _TokenBucket_Acquire_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TokenBucketAcquireRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenBucketServer).Acquire(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TokenBucket_Acquire_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenBucketServer).Acquire(ctx, req.(*TokenBucketAcquireRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// TokenBucket_ServiceDesc is the grpc.ServiceDesc for TokenBucket service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var TokenBucket_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "bouncer.v1.TokenBucket",
+	HandlerType: (*TokenBucketServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Acquire",
+			Handler:    _TokenBucket_Acquire_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "bouncer.proto",
+}
+
+const (
+	RateLimit_Acquire_FullMethodName = "/bouncer.v1.RateLimit/Acquire"
+)
+
+// RateLimitClient is the client API for RateLimit service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type RateLimitClient interface {
+	Acquire(ctx context.Context, in *RateLimitAcquireRequest, opts ...grpc.CallOption) (*Empty, error)
+}
+
+type rateLimitClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRateLimitClient(cc grpc.ClientConnInterface) RateLimitClient {
+	return &rateLimitClient{cc}
+}
+
+func (c *rateLimitClient) Acquire(ctx context.Context, in *RateLimitAcquireRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, RateLimit_Acquire_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RateLimitServer is the server API for RateLimit service.
+// All implementations must embed UnimplementedRateLimitServer
+// for forward compatibility.
+type RateLimitServer interface {
+	Acquire(context.Context, *RateLimitAcquireRequest) (*Empty, error)
+	mustEmbedUnimplementedRateLimitServer()
+}
+
+// UnimplementedRateLimitServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedRateLimitServer struct{}
+
+func (UnimplementedRateLimitServer) Acquire(context.Context, *RateLimitAcquireRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Acquire not implemented")
+}
+func (UnimplementedRateLimitServer) mustEmbedUnimplementedRateLimitServer() {}
+func (UnimplementedRateLimitServer) testEmbeddedByValue()                   {}
+
+// UnsafeRateLimitServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RateLimitServer will
+// result in compilation errors.
+type UnsafeRateLimitServer interface {
+	mustEmbedUnimplementedRateLimitServer()
+}
+
+func RegisterRateLimitServer(s grpc.ServiceRegistrar, srv RateLimitServer) {
+	// If the following call pancis, it indicates UnimplementedRateLimitServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&RateLimit_ServiceDesc, srv)
+}
+
+func _RateLimit_Acquire_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RateLimitAcquireRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RateLimitServer).Acquire(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RateLimit_Acquire_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RateLimitServer).Acquire(ctx, req.(*RateLimitAcquireRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// RateLimit_ServiceDesc is the grpc.ServiceDesc for RateLimit service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RateLimit_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "bouncer.v1.RateLimit",
+	HandlerType: (*RateLimitServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Acquire",
+			Handler:    _RateLimit_Acquire_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "bouncer.proto",
+}
+
+const (
+	Event_Wait_FullMethodName  = "/bouncer.v1.Event/Wait"
+	Event_Send_FullMethodName  = "/bouncer.v1.Event/Send"
+	Event_Watch_FullMethodName = "/bouncer.v1.Event/Watch"
+)
+
+// EventClient is the client API for Event service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type EventClient interface {
+	Wait(ctx context.Context, in *WaitRequest, opts ...grpc.CallOption) (*EventMessage, error)
+	Send(ctx context.Context, in *SendRequest, opts ...grpc.CallOption) (*Empty, error)
+	// Watch streams the message of each event as it's sent, and ends once
+	// every event was.
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[EventMessage], error)
+}
+
+type eventClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewEventClient(cc grpc.ClientConnInterface) EventClient {
+	return &eventClient{cc}
+}
+
+func (c *eventClient) Wait(ctx context.Context, in *WaitRequest, opts ...grpc.CallOption) (*EventMessage, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EventMessage)
+	err := c.cc.Invoke(ctx, Event_Wait_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventClient) Send(ctx context.Context, in *SendRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, Event_Send_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[EventMessage], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Event_ServiceDesc.Streams[0], Event_Watch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchRequest, EventMessage]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Event_WatchClient = grpc.ServerStreamingClient[EventMessage]
+
+// EventServer is the server API for Event service.
+// All implementations must embed UnimplementedEventServer
+// for forward compatibility.
+type EventServer interface {
+	Wait(context.Context, *WaitRequest) (*EventMessage, error)
+	Send(context.Context, *SendRequest) (*Empty, error)
+	// Watch streams the message of each event as it's sent, and ends once
+	// every event was.
+	Watch(*WatchRequest, grpc.ServerStreamingServer[EventMessage]) error
+	mustEmbedUnimplementedEventServer()
+}
+
+// UnimplementedEventServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedEventServer struct{}
+
+func (UnimplementedEventServer) Wait(context.Context, *WaitRequest) (*EventMessage, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Wait not implemented")
+}
+func (UnimplementedEventServer) Send(context.Context, *SendRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Send not implemented")
+}
+func (UnimplementedEventServer) Watch(*WatchRequest, grpc.ServerStreamingServer[EventMessage]) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedEventServer) mustEmbedUnimplementedEventServer() {}
+func (UnimplementedEventServer) testEmbeddedByValue()               {}
+
+// UnsafeEventServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to EventServer will
+// result in compilation errors.
+type UnsafeEventServer interface {
+	mustEmbedUnimplementedEventServer()
+}
+
+func RegisterEventServer(s grpc.ServiceRegistrar, srv EventServer) {
+	// If the following call pancis, it indicates UnimplementedEventServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Event_ServiceDesc, srv)
+}
+
+func _Event_Wait_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WaitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventServer).Wait(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Event_Wait_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventServer).Wait(ctx, req.(*WaitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Event_Send_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventServer).Send(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Event_Send_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventServer).Send(ctx, req.(*SendRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Event_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(EventServer).Watch(m, &grpc.GenericServerStream[WatchRequest, EventMessage]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Event_WatchServer = grpc.ServerStreamingServer[EventMessage]
+
+// Event_ServiceDesc is the grpc.ServiceDesc for Event service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Event_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "bouncer.v1.Event",
+	HandlerType: (*EventServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Wait",
+			Handler:    _Event_Wait_Handler,
+		},
+		{
+			MethodName: "Send",
+			Handler:    _Event_Send_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _Event_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "bouncer.proto",
+}
+
+const (
+	Watchdog_Kick_FullMethodName  = "/bouncer.v1.Watchdog/Kick"
+	Watchdog_Wait_FullMethodName  = "/bouncer.v1.Watchdog/Wait"
+	Watchdog_Watch_FullMethodName = "/bouncer.v1.Watchdog/Watch"
+)
+
+// WatchdogClient is the client API for Watchdog service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type WatchdogClient interface {
+	Kick(ctx context.Context, in *KickRequest, opts ...grpc.CallOption) (*Empty, error)
+	Wait(ctx context.Context, in *WaitRequest, opts ...grpc.CallOption) (*Empty, error)
+	// Watch streams every expiration of the watchdogs, including the current
+	// one if already expired, until the client goes away.
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchdogExpiration], error)
+}
+
+type watchdogClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewWatchdogClient(cc grpc.ClientConnInterface) WatchdogClient {
+	return &watchdogClient{cc}
+}
+
+func (c *watchdogClient) Kick(ctx context.Context, in *KickRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, Watchdog_Kick_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *watchdogClient) Wait(ctx context.Context, in *WaitRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, Watchdog_Wait_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *watchdogClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchdogExpiration], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Watchdog_ServiceDesc.Streams[0], Watchdog_Watch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchRequest, WatchdogExpiration]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Watchdog_WatchClient = grpc.ServerStreamingClient[WatchdogExpiration]
+
+// WatchdogServer is the server API for Watchdog service.
+// All implementations must embed UnimplementedWatchdogServer
+// for forward compatibility.
+type WatchdogServer interface {
+	Kick(context.Context, *KickRequest) (*Empty, error)
+	Wait(context.Context, *WaitRequest) (*Empty, error)
+	// Watch streams every expiration of the watchdogs, including the current
+	// one if already expired, until the client goes away.
+	Watch(*WatchRequest, grpc.ServerStreamingServer[WatchdogExpiration]) error
+	mustEmbedUnimplementedWatchdogServer()
+}
+
+// UnimplementedWatchdogServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedWatchdogServer struct{}
+
+func (UnimplementedWatchdogServer) Kick(context.Context, *KickRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Kick not implemented")
+}
+func (UnimplementedWatchdogServer) Wait(context.Context, *WaitRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Wait not implemented")
+}
+func (UnimplementedWatchdogServer) Watch(*WatchRequest, grpc.ServerStreamingServer[WatchdogExpiration]) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedWatchdogServer) mustEmbedUnimplementedWatchdogServer() {}
+func (UnimplementedWatchdogServer) testEmbeddedByValue()                  {}
+
+// UnsafeWatchdogServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to WatchdogServer will
+// result in compilation errors.
+type UnsafeWatchdogServer interface {
+	mustEmbedUnimplementedWatchdogServer()
+}
+
+func RegisterWatchdogServer(s grpc.ServiceRegistrar, srv WatchdogServer) {
+	// If the following call pancis, it indicates UnimplementedWatchdogServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Watchdog_ServiceDesc, srv)
+}
+
+func _Watchdog_Kick_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KickRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WatchdogServer).Kick(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Watchdog_Kick_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WatchdogServer).Kick(ctx, req.(*KickRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Watchdog_Wait_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WaitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WatchdogServer).Wait(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Watchdog_Wait_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WatchdogServer).Wait(ctx, req.(*WaitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Watchdog_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(WatchdogServer).Watch(m, &grpc.GenericServerStream[WatchRequest, WatchdogExpiration]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Watchdog_WatchServer = grpc.ServerStreamingServer[WatchdogExpiration]
+
+// Watchdog_ServiceDesc is the grpc.ServiceDesc for Watchdog service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Watchdog_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "bouncer.v1.Watchdog",
+	HandlerType: (*WatchdogServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Kick",
+			Handler:    _Watchdog_Kick_Handler,
+		},
+		{
+			MethodName: "Wait",
+			Handler:    _Watchdog_Wait_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _Watchdog_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "bouncer.proto",
+}
+
+const (
+	Counter_Count_FullMethodName = "/bouncer.v1.Counter/Count"
+	Counter_Reset_FullMethodName = "/bouncer.v1.Counter/Reset"
+	Counter_Value_FullMethodName = "/bouncer.v1.Counter/Value"
+)
+
+// CounterClient is the client API for Counter service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type CounterClient interface {
+	Count(ctx context.Context, in *CountRequest, opts ...grpc.CallOption) (*CounterValue, error)
+	Reset(ctx context.Context, in *ResetRequest, opts ...grpc.CallOption) (*Empty, error)
+	Value(ctx context.Context, in *NameRequest, opts ...grpc.CallOption) (*CounterValue, error)
+}
+
+type counterClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCounterClient(cc grpc.ClientConnInterface) CounterClient {
+	return &counterClient{cc}
+}
+
+func (c *counterClient) Count(ctx context.Context, in *CountRequest, opts ...grpc.CallOption) (*CounterValue, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CounterValue)
+	err := c.cc.Invoke(ctx, Counter_Count_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *counterClient) Reset(ctx context.Context, in *ResetRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, Counter_Reset_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *counterClient) Value(ctx context.Context, in *NameRequest, opts ...grpc.CallOption) (*CounterValue, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CounterValue)
+	err := c.cc.Invoke(ctx, Counter_Value_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CounterServer is the server API for Counter service.
+// All implementations must embed UnimplementedCounterServer
+// for forward compatibility.
+type CounterServer interface {
+	Count(context.Context, *CountRequest) (*CounterValue, error)
+	Reset(context.Context, *ResetRequest) (*Empty, error)
+	Value(context.Context, *NameRequest) (*CounterValue, error)
+	mustEmbedUnimplementedCounterServer()
+}
+
+// UnimplementedCounterServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedCounterServer struct{}
+
+func (UnimplementedCounterServer) Count(context.Context, *CountRequest) (*CounterValue, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Count not implemented")
+}
+func (UnimplementedCounterServer) Reset(context.Context, *ResetRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Reset not implemented")
+}
+func (UnimplementedCounterServer) Value(context.Context, *NameRequest) (*CounterValue, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Value not implemented")
+}
+func (UnimplementedCounterServer) mustEmbedUnimplementedCounterServer() {}
+func (UnimplementedCounterServer) testEmbeddedByValue()                 {}
+
+// UnsafeCounterServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CounterServer will
+// result in compilation errors.
+type UnsafeCounterServer interface {
+	mustEmbedUnimplementedCounterServer()
+}
+
+func RegisterCounterServer(s grpc.ServiceRegistrar, srv CounterServer) {
+	// If the following call pancis, it indicates UnimplementedCounterServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Counter_ServiceDesc, srv)
+}
+
+func _Counter_Count_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CounterServer).Count(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Counter_Count_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CounterServer).Count(ctx, req.(*CountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Counter_Reset_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CounterServer).Reset(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Counter_Reset_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CounterServer).Reset(ctx, req.(*ResetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Counter_Value_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NameRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CounterServer).Value(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Counter_Value_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CounterServer).Value(ctx, req.(*NameRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Counter_ServiceDesc is the grpc.ServiceDesc for Counter service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Counter_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "bouncer.v1.Counter",
+	HandlerType: (*CounterServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Count",
+			Handler:    _Counter_Count_Handler,
+		},
+		{
+			MethodName: "Reset",
+			Handler:    _Counter_Reset_Handler,
+		},
+		{
+			MethodName: "Value",
+			Handler:    _Counter_Value_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "bouncer.proto",
+}
+
+const (
+	Barrier_Wait_FullMethodName = "/bouncer.v1.Barrier/Wait"
+)
+
+// BarrierClient is the client API for Barrier service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type BarrierClient interface {
+	Wait(ctx context.Context, in *BarrierWaitRequest, opts ...grpc.CallOption) (*Empty, error)
+}
+
+type barrierClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBarrierClient(cc grpc.ClientConnInterface) BarrierClient {
+	return &barrierClient{cc}
+}
+
+func (c *barrierClient) Wait(ctx context.Context, in *BarrierWaitRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, Barrier_Wait_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// BarrierServer is the server API for Barrier service.
+// All implementations must embed UnimplementedBarrierServer
+// for forward compatibility.
+type BarrierServer interface {
+	Wait(context.Context, *BarrierWaitRequest) (*Empty, error)
+	mustEmbedUnimplementedBarrierServer()
+}
+
+// UnimplementedBarrierServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedBarrierServer struct{}
+
+func (UnimplementedBarrierServer) Wait(context.Context, *BarrierWaitRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Wait not implemented")
+}
+func (UnimplementedBarrierServer) mustEmbedUnimplementedBarrierServer() {}
+func (UnimplementedBarrierServer) testEmbeddedByValue()                 {}
+
+// UnsafeBarrierServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BarrierServer will
+// result in compilation errors.
+type UnsafeBarrierServer interface {
+	mustEmbedUnimplementedBarrierServer()
+}
+
+func RegisterBarrierServer(s grpc.ServiceRegistrar, srv BarrierServer) {
+	// If the following call pancis, it indicates UnimplementedBarrierServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Barrier_ServiceDesc, srv)
+}
+
+func _Barrier_Wait_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BarrierWaitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BarrierServer).Wait(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Barrier_Wait_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BarrierServer).Wait(ctx, req.(*BarrierWaitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Barrier_ServiceDesc is the grpc.ServiceDesc for Barrier service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Barrier_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "bouncer.v1.Barrier",
+	HandlerType: (*BarrierServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Wait",
+			Handler:    _Barrier_Wait_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "bouncer.proto",
+}
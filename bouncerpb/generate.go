@@ -0,0 +1,4 @@
+// Package bouncerpb is the gRPC API of bouncer, generated from bouncer.proto.
+package bouncerpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative bouncer.proto
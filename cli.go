@@ -0,0 +1,400 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pjwerneck/bouncer/client"
+)
+
+// Exit codes. A timed out wait exits like timeout(1), so scripts can tell it
+// apart from errors.
+const (
+	exitOK       = 0
+	exitError    = 1
+	exitUsage    = 2
+	exitTimedOut = 124
+)
+
+const usage = `Usage: bouncer [command] [arguments]
+
+Commands:
+  serve                                   run the server (the default)
+  acquire semaphore NAME [-- CMD ...]     acquire a semaphore, or run CMD holding it
+  acquire tokenbucket NAME [-- CMD ...]   acquire a token, then run CMD
+  release semaphore NAME KEY              release a semaphore key
+  wait event|watchdog|barrier NAME        wait for an event, watchdog or barrier
+  send event NAME [MESSAGE]               send an event
+  kick watchdog NAME                      kick a watchdog, once or --every interval
+  count counter NAME                      increment a counter and print the value
+  value counter NAME                      print a counter value
+  reset counter NAME                      reset a counter
+  stats [TYPE [NAME]]                     print the stats of all objects, a type or one object
+
+Run 'bouncer COMMAND -h' for the options of a command. The server URL is
+taken from --url or BOUNCER_URL, and defaults to http://localhost:5505. The
+API token, if the server requires one, from --token or BOUNCER_TOKEN, and
+the namespace of the objects, if any, from --namespace or BOUNCER_NAMESPACE.
+`
+
+// cli holds the options shared by every client command.
+type cli struct {
+	flags     *flag.FlagSet
+	url       string
+	token     string
+	namespace string
+	size      uint64
+	maxwait   time.Duration
+	expires   time.Duration
+	stdout    io.Writer
+	stderr    io.Writer
+}
+
+func newCLI(name string, stdout io.Writer, stderr io.Writer) *cli {
+	c := &cli{flags: flag.NewFlagSet("bouncer "+name, flag.ContinueOnError), stdout: stdout, stderr: stderr}
+	c.flags.SetOutput(stderr)
+
+	url := os.Getenv("BOUNCER_URL")
+	if url == "" {
+		url = "http://localhost:5505"
+	}
+	c.flags.StringVar(&c.url, "url", url, "bouncer server URL")
+	c.flags.StringVar(&c.token, "token", os.Getenv("BOUNCER_TOKEN"), "API token")
+	c.flags.StringVar(&c.namespace, "namespace", os.Getenv("BOUNCER_NAMESPACE"), "namespace of the objects")
+	return c
+}
+
+func (c *cli) client() *client.Client {
+	bouncer := client.New(c.url)
+	bouncer.Token = c.token
+	bouncer.Namespace = c.namespace
+	return bouncer
+}
+
+func (c *cli) waitFlags() {
+	c.flags.DurationVar(&c.maxwait, "maxwait", client.Forever, "maximum time to wait, negative waits forever")
+}
+
+// parse takes exactly want positional arguments followed by flags, and
+// returns what follows a "--".
+func (c *cli) parse(args []string, want int, names string) (positional []string, rest []string, err error) {
+	for len(args) > 0 && len(positional) < want && !strings.HasPrefix(args[0], "-") {
+		positional, args = append(positional, args[0]), args[1:]
+	}
+	if err := c.flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+	if len(positional) < want {
+		return nil, nil, fmt.Errorf("expected %s", names)
+	}
+	return positional, c.flags.Args(), nil
+}
+
+// cliCommands are the client commands handled by runCLI.
+var cliCommands = map[string]bool{
+	"acquire": true,
+	"release": true,
+	"wait":    true,
+	"send":    true,
+	"kick":    true,
+	"count":   true,
+	"value":   true,
+	"reset":   true,
+	"stats":   true,
+}
+
+// runCLI runs a client command and returns the exit code.
+func runCLI(ctx context.Context, command string, args []string, stdout io.Writer, stderr io.Writer) int {
+	c := newCLI(command, stdout, stderr)
+
+	var run func() error
+	switch command {
+	case "acquire":
+		run = c.acquire(ctx, args)
+	case "release":
+		run = c.release(ctx, args)
+	case "wait":
+		run = c.wait(ctx, args)
+	case "send":
+		run = c.send(ctx, args)
+	case "kick":
+		run = c.kick(ctx, args)
+	case "count", "value", "reset":
+		run = c.counter(ctx, command, args)
+	case "stats":
+		run = c.stats(ctx, args)
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", command, usage)
+		return exitUsage
+	}
+
+	if run == nil {
+		return exitUsage
+	}
+
+	err := run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &exitErr):
+		return exitErr.ExitCode()
+	case errors.Is(err, client.ErrTimedOut):
+		fmt.Fprintln(stderr, err)
+		return exitTimedOut
+	default:
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+}
+
+// usageError prints err and the command options, and returns nil so runCLI
+// exits with exitUsage.
+func (c *cli) usageError(err error) func() error {
+	if !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintf(c.stderr, "%s: %v\n", c.flags.Name(), err)
+		c.flags.Usage()
+	}
+	return nil
+}
+
+func (c *cli) acquire(ctx context.Context, args []string) func() error {
+	c.flags.Uint64Var(&c.size, "size", 0, "semaphore or token bucket size, if not the server's")
+	c.flags.DurationVar(&c.expires, "expires", time.Minute, "semaphore key expiration")
+	interval := c.flags.Duration("interval", 0, "token bucket refill interval, if not the server's")
+	c.waitFlags()
+
+	pos, command, err := c.parse(args, 2, "TYPE NAME")
+	if err != nil {
+		return c.usageError(err)
+	}
+
+	bouncer := c.client()
+	switch pos[0] {
+	case "semaphore":
+		return func() error {
+			semaphore := bouncer.Semaphore(pos[1])
+			key, err := semaphore.Acquire(ctx, c.size, c.maxwait, c.expires)
+			if err != nil {
+				return err
+			}
+
+			if len(command) == 0 {
+				fmt.Fprintln(c.stdout, key)
+				return nil
+			}
+
+			// release even if the command fails or we're interrupted
+			defer semaphore.Release(context.WithoutCancel(ctx), key)
+			return c.exec(command)
+		}
+	case "tokenbucket":
+		return func() error {
+			if err := bouncer.TokenBucket(pos[1]).Acquire(ctx, c.size, *interval, c.maxwait); err != nil {
+				return err
+			}
+			if len(command) == 0 {
+				return nil
+			}
+			return c.exec(command)
+		}
+	}
+	return c.usageError(fmt.Errorf("can't acquire a %q", pos[0]))
+}
+
+// exec runs command with our stdio. Interrupts reach it through the
+// terminal, or are forwarded here, so it can exit before we clean up.
+func (c *cli) exec(command []string) error {
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, c.stdout, c.stderr
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	for {
+		select {
+		case err := <-done:
+			return err
+		case sig := <-signals:
+			cmd.Process.Signal(sig)
+		}
+	}
+}
+
+func (c *cli) release(ctx context.Context, args []string) func() error {
+	pos, _, err := c.parse(args, 3, "semaphore NAME KEY")
+	if err != nil {
+		return c.usageError(err)
+	}
+	if pos[0] != "semaphore" {
+		return c.usageError(fmt.Errorf("can't release a %q", pos[0]))
+	}
+
+	return func() error {
+		return c.client().Semaphore(pos[1]).Release(ctx, pos[2])
+	}
+}
+
+func (c *cli) wait(ctx context.Context, args []string) func() error {
+	c.flags.Uint64Var(&c.size, "size", 0, "barrier size, if not the server's")
+	c.waitFlags()
+
+	pos, _, err := c.parse(args, 2, "TYPE NAME")
+	if err != nil {
+		return c.usageError(err)
+	}
+
+	bouncer := c.client()
+	switch pos[0] {
+	case "event":
+		return func() error {
+			message, err := bouncer.Event(pos[1]).Wait(ctx, c.maxwait)
+			if err == nil && message != "" {
+				fmt.Fprintln(c.stdout, message)
+			}
+			return err
+		}
+	case "watchdog":
+		return func() error {
+			return bouncer.Watchdog(pos[1]).Wait(ctx, c.maxwait)
+		}
+	case "barrier":
+		return func() error {
+			return bouncer.Barrier(pos[1]).Wait(ctx, c.size, c.maxwait)
+		}
+	}
+	return c.usageError(fmt.Errorf("can't wait for a %q", pos[0]))
+}
+
+func (c *cli) send(ctx context.Context, args []string) func() error {
+	pos, rest, err := c.parse(args, 2, "event NAME [MESSAGE]")
+	if err != nil {
+		return c.usageError(err)
+	}
+	if pos[0] != "event" {
+		return c.usageError(fmt.Errorf("can't send a %q", pos[0]))
+	}
+
+	return func() error {
+		return c.client().Event(pos[1]).Send(ctx, strings.Join(rest, " "))
+	}
+}
+
+func (c *cli) kick(ctx context.Context, args []string) func() error {
+	c.flags.DurationVar(&c.expires, "expires", time.Minute, "time until the watchdog expires")
+	every := c.flags.Duration("every", 0, "keep kicking at this interval until interrupted")
+
+	pos, _, err := c.parse(args, 2, "watchdog NAME")
+	if err != nil {
+		return c.usageError(err)
+	}
+	if pos[0] != "watchdog" {
+		return c.usageError(fmt.Errorf("can't kick a %q", pos[0]))
+	}
+
+	return func() error {
+		watchdog := c.client().Watchdog(pos[1])
+		if err := watchdog.Kick(ctx, c.expires); err != nil || *every <= 0 {
+			return err
+		}
+
+		ticker := time.NewTicker(*every)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+				// a failed kick is retried on the next tick, the watchdog
+				// only expires if they keep failing
+				if err := watchdog.Kick(ctx, c.expires); err != nil && ctx.Err() == nil {
+					fmt.Fprintln(c.stderr, err)
+				}
+			}
+		}
+	}
+}
+
+func (c *cli) counter(ctx context.Context, command string, args []string) func() error {
+	amount := c.flags.Int64("amount", 1, "amount to add")
+	value := c.flags.Int64("value", 0, "value to reset to")
+
+	pos, _, err := c.parse(args, 2, "counter NAME")
+	if err != nil {
+		return c.usageError(err)
+	}
+	if pos[0] != "counter" {
+		return c.usageError(fmt.Errorf("%s only works with counters", command))
+	}
+
+	counter := c.client().Counter(pos[1])
+	return func() (err error) {
+		var current int64
+		switch command {
+		case "count":
+			current, err = counter.Count(ctx, *amount)
+		case "value":
+			current, err = counter.Value(ctx)
+		case "reset":
+			return counter.Reset(ctx, *value)
+		}
+		if err == nil {
+			fmt.Fprintln(c.stdout, current)
+		}
+		return err
+	}
+}
+
+func (c *cli) stats(ctx context.Context, args []string) func() error {
+	var pos []string
+	for len(args) > 0 && len(pos) < 2 && !strings.HasPrefix(args[0], "-") {
+		pos, args = append(pos, args[0]), args[1:]
+	}
+	if err := c.flags.Parse(args); err != nil {
+		return c.usageError(err)
+	}
+
+	// without a name, list objects with their stats
+	path := "/resources"
+	switch len(pos) {
+	case 1:
+		path = "/" + pos[0] + "/"
+	case 2:
+		path = "/" + pos[0] + "/" + pos[1] + "/stats"
+	}
+
+	return func() error {
+		var v interface{}
+		if err := c.client().GetJSON(ctx, path, &v); err != nil {
+			return err
+		}
+
+		enc := json.NewEncoder(c.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+}
+
+// interruptible returns a context canceled on SIGINT or SIGTERM.
+func interruptible() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pjwerneck/bouncer/bouncermain"
+	"github.com/pjwerneck/bouncer/client"
+	"github.com/stretchr/testify/require"
+)
+
+var server = httptest.NewServer(bouncermain.Router())
+
+func init() {
+	os.Setenv("BOUNCER_URL", server.URL)
+}
+
+func run(args ...string) (code int, stdout string, stderr string) {
+	var out, errOut bytes.Buffer
+	code = runCLI(context.Background(), args[0], args[1:], &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+func TestAcquireSemaphoreRunsCommand(t *testing.T) {
+	code, stdout, _ := run("acquire", "semaphore", "cli-run", "--", "sh", "-c", "echo running; exit 3")
+	require.Equal(t, 3, code)
+	require.Equal(t, "running\n", stdout)
+
+	// the key was released, so it can be acquired right away
+	code, stdout, _ = run("acquire", "semaphore", "cli-run", "--maxwait", "0s")
+	require.Equal(t, exitOK, code)
+	key := strings.TrimSpace(stdout)
+
+	code, _, stderr := run("acquire", "semaphore", "cli-run", "--maxwait", "0s")
+	require.Equal(t, exitTimedOut, code)
+	require.Contains(t, stderr, "timeout")
+
+	code, _, _ = run("release", "semaphore", "cli-run", key)
+	require.Equal(t, exitOK, code)
+}
+
+func TestAcquireKeepsServerSettings(t *testing.T) {
+	bucket := client.New(server.URL).TokenBucket("cli-bucket")
+	require.Nil(t, bucket.Configure(context.Background(), 5, time.Minute))
+
+	// without --size and --interval the configured ones apply
+	code, _, _ := run("acquire", "tokenbucket", "cli-bucket")
+	require.Equal(t, exitOK, code)
+
+	check, err := bucket.Check(context.Background(), 1, 0, 0)
+	require.Nil(t, err)
+	require.Equal(t, uint64(5), check.Limit)
+	require.Equal(t, uint64(3), check.Remaining)
+	require.Greater(t, check.Reset, int64(time.Second/time.Millisecond))
+}
+
+func TestEventCommands(t *testing.T) {
+	code, _, _ := run("wait", "event", "cli-event", "--maxwait", "0s")
+	require.Equal(t, exitTimedOut, code)
+
+	code, _, _ = run("send", "event", "cli-event", "hello", "world")
+	require.Equal(t, exitOK, code)
+
+	code, stdout, _ := run("wait", "event", "cli-event")
+	require.Equal(t, exitOK, code)
+	require.Equal(t, "hello world\n", stdout)
+}
+
+func TestCounterCommands(t *testing.T) {
+	code, stdout, _ := run("count", "counter", "cli-counter", "--amount", "5")
+	require.Equal(t, exitOK, code)
+	require.Equal(t, "5\n", stdout)
+
+	code, stdout, _ = run("stats", "counter", "cli-counter")
+	require.Equal(t, exitOK, code)
+	require.Contains(t, stdout, `"increments": 1`)
+}
+
+func TestSplitCommand(t *testing.T) {
+	for _, c := range []struct {
+		args    []string
+		command string
+		rest    []string
+	}{
+		{[]string{}, "serve", []string{}},
+		{[]string{"-version"}, "serve", []string{"-version"}},
+		{[]string{"bouncer"}, "serve", []string{"bouncer"}},
+		{[]string{"serve", "-version"}, "serve", []string{"-version"}},
+		{[]string{"stats", "semaphore"}, "stats", []string{"semaphore"}},
+	} {
+		command, rest := splitCommand(c.args)
+		require.Equal(t, c.command, command)
+		require.Equal(t, c.rest, rest)
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	code, _, stderr := run("acquire", "barrier", "cli-usage")
+	require.Equal(t, exitUsage, code)
+	require.Contains(t, stderr, `can't acquire a "barrier"`)
+
+	code, _, _ = run("frobnicate", "x", "y")
+	require.Equal(t, exitUsage, code)
+}
@@ -0,0 +1,276 @@
+// Package client is a Go client for the bouncer HTTP API.
+//
+// Error statuses are mapped back to the server's sentinel errors, so callers
+// can check them with errors.Is:
+//
+//	key, err := c.Semaphore("db").Acquire(ctx, 10, time.Second, time.Minute)
+//	if errors.Is(err, client.ErrTimedOut) {
+//		...
+//	}
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pjwerneck/bouncer/bouncerapi"
+)
+
+var (
+	ErrInvalidSize     = bouncerapi.ErrInvalidSize
+	ErrInvalidLimit    = bouncerapi.ErrInvalidLimit
+	ErrInvalidCount    = bouncerapi.ErrInvalidCount
+	ErrInvalidRate     = bouncerapi.ErrInvalidRate
+	ErrInvalidAlgo     = bouncerapi.ErrInvalidAlgo
+	ErrInvalidBurst    = bouncerapi.ErrInvalidBurst
+	ErrInvalidConfig   = bouncerapi.ErrInvalidConfig
+	ErrInvalidInterval = bouncerapi.ErrInvalidInterval
+	ErrInvalidName     = bouncerapi.ErrInvalidName
+	ErrNoSubscriptions = bouncerapi.ErrNoSubscriptions
+	ErrNotFound        = bouncerapi.ErrNotFound
+	ErrTimedOut        = bouncerapi.ErrTimedOut
+	ErrCanceled        = bouncerapi.ErrCanceled
+	ErrKeyError        = bouncerapi.ErrKeyError
+	ErrEventClosed     = bouncerapi.ErrEventClosed
+	ErrBarrierClosed   = bouncerapi.ErrBarrierClosed
+	ErrNoLeader        = bouncerapi.ErrNoLeader
+	ErrNotLeader       = bouncerapi.ErrNotLeader
+	ErrShuttingDown    = bouncerapi.ErrShuttingDown
+	ErrStaleToken      = bouncerapi.ErrStaleToken
+	ErrConfigConflict  = bouncerapi.ErrConfigConflict
+	ErrUnauthorized    = bouncerapi.ErrUnauthorized
+	ErrForbidden       = bouncerapi.ErrForbidden
+	ErrTooManyObjects  = bouncerapi.ErrTooManyObjects
+)
+
+// Every error response with these statuses matches their error, whatever
+// the body, so ErrConflict matches ErrKeyError and ErrConfigConflict too.
+var (
+	ErrConflict    = errors.New("bouncer: conflict")
+	ErrUnavailable = errors.New("bouncer: unavailable")
+)
+
+var statusErrors = map[int]error{
+	http.StatusRequestTimeout:     ErrTimedOut,
+	http.StatusNotFound:           ErrNotFound,
+	http.StatusConflict:           ErrConflict,
+	http.StatusServiceUnavailable: ErrUnavailable,
+}
+
+// sentinels are matched against the response body, which is the error
+// message, since a status like 409 is shared by several errors.
+var sentinels = []error{
+	ErrInvalidSize,
+	ErrInvalidLimit,
+	ErrInvalidCount,
+	ErrInvalidRate,
+	ErrInvalidAlgo,
+	ErrInvalidBurst,
+	ErrInvalidConfig,
+	ErrInvalidInterval,
+	ErrInvalidName,
+	ErrNoSubscriptions,
+	ErrNotFound,
+	ErrTimedOut,
+	ErrCanceled,
+	ErrKeyError,
+	ErrEventClosed,
+	ErrBarrierClosed,
+	ErrNoLeader,
+	ErrNotLeader,
+	ErrShuttingDown,
+	ErrStaleToken,
+	ErrConfigConflict,
+	ErrUnauthorized,
+	ErrForbidden,
+	ErrTooManyObjects,
+}
+
+// StatusError is returned for error responses. It matches the sentinel
+// error in the body, if any, and the error of its status.
+type StatusError struct {
+	Status int
+	Body   string
+	Err    error // the sentinel error in the body, if any
+}
+
+func (e *StatusError) Error() string {
+	if e.Err != nil {
+		return e.Body
+	}
+	return fmt.Sprintf("bouncer: status %d: %s", e.Status, e.Body)
+}
+
+func (e *StatusError) Unwrap() []error {
+	errs := []error{}
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	if err, ok := statusErrors[e.Status]; ok && err != e.Err {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// Client talks to a bouncer server. It's safe for concurrent use.
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	Token      string // sent as a bearer token, if not empty
+	Namespace  string // objects are taken within it, if not empty
+}
+
+// New returns a client for the server at baseURL, e.g.
+// "http://localhost:5505".
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+// Forever can be passed as maxwait to wait without a time limit, bounded
+// only by the context.
+const Forever time.Duration = -1
+
+// omitZero removes the given settings when zero, so the server takes them
+// from the object configuration.
+func omitZero(params url.Values, keys ...string) url.Values {
+	for _, key := range keys {
+		if v := params.Get(key); v == "" || v == "0" {
+			params.Del(key)
+		}
+	}
+	return params
+}
+
+func millis(d time.Duration) string {
+	if d < 0 {
+		return "-1"
+	}
+	return strconv.FormatInt(d.Milliseconds(), 10)
+}
+
+// capWait caps a wait to the context deadline, so the server gives up on
+// its side instead of granting something nobody will receive.
+func capWait(ctx context.Context, d time.Duration) time.Duration {
+	if deadline, ok := ctx.Deadline(); ok {
+		left := max(time.Until(deadline), 0)
+		if d < 0 || left < d {
+			return left
+		}
+	}
+	return d
+}
+
+func (c *Client) do(ctx context.Context, method string, path string, params url.Values, payload io.Reader) (header http.Header, body string, err error) {
+	u := c.BaseURL + path
+	if c.Namespace != "" {
+		u = c.BaseURL + "/ns/" + url.PathEscape(c.Namespace) + path
+	}
+	if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, payload)
+	if err != nil {
+		return nil, "", err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	rep, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rep.Body.Close()
+
+	bs, err := io.ReadAll(rep.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	body = string(bs)
+	if rep.StatusCode >= 400 {
+		return rep.Header, body, responseError(rep.StatusCode, body)
+	}
+	return rep.Header, body, nil
+}
+
+func responseError(status int, body string) error {
+	for _, err := range sentinels {
+		// wrapped errors add details after the message
+		if body == err.Error() || strings.HasPrefix(body, err.Error()+":") {
+			return &StatusError{Status: status, Body: body, Err: err}
+		}
+	}
+	return &StatusError{Status: status, Body: body}
+}
+
+func (c *Client) get(ctx context.Context, path string, params url.Values) (string, error) {
+	_, body, err := c.do(ctx, http.MethodGet, path, params, nil)
+	return body, err
+}
+
+func (c *Client) delete(ctx context.Context, path string) error {
+	_, _, err := c.do(ctx, http.MethodDelete, path, nil, nil)
+	return err
+}
+
+// Configure creates or reconfigures an object with the JSON encoding of
+// config, e.g. a SemaphoreConfig for a "semaphore".
+func (c *Client) Configure(ctx context.Context, typeName string, name string, config interface{}) error {
+	buf, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = c.do(ctx, http.MethodPut, objectPath(typeName, name, ""), nil, bytes.NewReader(buf))
+	return err
+}
+
+// getFenced sends an acquire request and returns the key with its fencing
+// token.
+func (c *Client) getFenced(ctx context.Context, path string, params url.Values) (key string, token uint64, err error) {
+	header, key, err := c.do(ctx, http.MethodGet, path, params, nil)
+	if err != nil {
+		return "", 0, err
+	}
+
+	token, err = strconv.ParseUint(header.Get(bouncerapi.FencingTokenHeader), 10, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("bouncer: invalid fencing token: %w", err)
+	}
+	return key, token, nil
+}
+
+// GetJSON decodes the response to a GET request for path, e.g. "/resources",
+// within the namespace if set.
+func (c *Client) GetJSON(ctx context.Context, path string, v interface{}) error {
+	body, err := c.get(ctx, path, nil)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(body), v)
+}
+
+func objectPath(typeName string, name string, call string) string {
+	path := "/" + typeName + "/" + url.PathEscape(name)
+	if call != "" {
+		path += "/" + call
+	}
+	return path
+}
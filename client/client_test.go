@@ -0,0 +1,392 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pjwerneck/bouncer/bouncermain"
+	"github.com/pjwerneck/bouncer/client"
+	"github.com/stretchr/testify/require"
+)
+
+var server = httptest.NewServer(bouncermain.Router())
+
+func newClient() *client.Client {
+	return client.New(server.URL)
+}
+
+func TestSemaphore(t *testing.T) {
+	ctx := context.Background()
+	semaphore := newClient().Semaphore("client-semaphore")
+
+	key, err := semaphore.Acquire(ctx, 1, 0, time.Minute)
+	require.Nil(t, err)
+	require.NotEmpty(t, key)
+
+	_, err = semaphore.Acquire(ctx, 1, 0, time.Minute)
+	require.ErrorIs(t, err, client.ErrTimedOut)
+
+	require.Nil(t, semaphore.Renew(ctx, key, time.Hour))
+	require.Nil(t, semaphore.Release(ctx, key))
+	require.ErrorIs(t, semaphore.Release(ctx, key), client.ErrKeyError)
+	require.ErrorIs(t, semaphore.Renew(ctx, key, time.Hour), client.ErrKeyError)
+
+	stats, err := semaphore.Stats(ctx)
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), stats.Acquired)
+	require.Equal(t, uint64(1), stats.TimedOut)
+
+	require.Nil(t, semaphore.Delete(ctx))
+	require.ErrorIs(t, semaphore.Delete(ctx), client.ErrNotFound)
+}
+
+func TestSemaphoreFencing(t *testing.T) {
+	ctx := context.Background()
+	semaphore := newClient().Semaphore("client-fencing")
+	defer semaphore.Delete(ctx)
+
+	key, token, err := semaphore.AcquireFenced(ctx, 1, 0, time.Minute)
+	require.Nil(t, err)
+	require.NotZero(t, token)
+	require.Nil(t, semaphore.Validate(ctx, key, token))
+	require.Nil(t, semaphore.Validate(ctx, "", token))
+
+	require.Nil(t, semaphore.Release(ctx, key))
+	require.ErrorIs(t, semaphore.Validate(ctx, key, token), client.ErrStaleToken)
+}
+
+func TestRWLock(t *testing.T) {
+	ctx := context.Background()
+	rwlock := newClient().RWLock("client-rwlock")
+
+	r1, t1, err := rwlock.RLock(ctx, 0, time.Minute)
+	require.Nil(t, err)
+	r2, t2, err := rwlock.RLock(ctx, 0, time.Minute)
+	require.Nil(t, err)
+	require.Greater(t, t2, t1)
+	require.Nil(t, rwlock.Validate(ctx, r1, t1))
+
+	_, _, err = rwlock.Lock(ctx, 0, time.Minute)
+	require.ErrorIs(t, err, client.ErrTimedOut)
+
+	require.Nil(t, rwlock.Unlock(ctx, r1))
+	require.Nil(t, rwlock.Unlock(ctx, r2))
+	require.ErrorIs(t, rwlock.Unlock(ctx, r2), client.ErrKeyError)
+	require.ErrorIs(t, rwlock.Validate(ctx, r1, t1), client.ErrStaleToken)
+
+	w, _, err := rwlock.Lock(ctx, 0, time.Minute)
+	require.Nil(t, err)
+	require.Nil(t, rwlock.Unlock(ctx, w))
+
+	stats, err := rwlock.Stats(ctx)
+	require.Nil(t, err)
+	require.Equal(t, uint64(2), stats.ReadAcquired)
+	require.Equal(t, uint64(1), stats.WriteAcquired)
+	require.Equal(t, uint64(1), stats.TimedOut)
+
+	require.Nil(t, rwlock.Delete(ctx))
+	require.ErrorIs(t, rwlock.Delete(ctx), client.ErrNotFound)
+}
+
+func TestTokenBucket(t *testing.T) {
+	ctx := context.Background()
+	bucket := newClient().TokenBucket("client-bucket")
+
+	require.Nil(t, bucket.Acquire(ctx, 1, time.Minute, 0))
+	require.ErrorIs(t, bucket.Acquire(ctx, 1, time.Minute, 0), client.ErrTimedOut)
+
+	stats, err := bucket.Stats(ctx)
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), stats.Acquired)
+}
+
+func TestTokenBucketAcquireN(t *testing.T) {
+	ctx := context.Background()
+	bucket := newClient().TokenBucket("client-bucket-n")
+
+	require.Nil(t, bucket.AcquireN(ctx, 3, 5, time.Minute, 0))
+	require.ErrorIs(t, bucket.AcquireN(ctx, 3, 5, time.Minute, 0), client.ErrTimedOut)
+	require.Nil(t, bucket.AcquireN(ctx, 2, 5, time.Minute, 0))
+
+	stats, err := bucket.Stats(ctx)
+	require.Nil(t, err)
+	require.Equal(t, uint64(2), stats.Acquired)
+	require.Equal(t, uint64(5), stats.Tokens)
+}
+
+func TestRateLimit(t *testing.T) {
+	ctx := context.Background()
+	limiter := newClient().RateLimit("client-ratelimit")
+
+	require.Nil(t, limiter.Acquire(ctx, "gcra", 1, time.Minute, 1, 0))
+	require.ErrorIs(t, limiter.Acquire(ctx, "gcra", 1, time.Minute, 1, 0), client.ErrTimedOut)
+
+	stats, err := limiter.Stats(ctx)
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), stats.Acquired)
+	require.Equal(t, uint64(1), stats.TimedOut)
+
+	require.Nil(t, limiter.Delete(ctx))
+}
+
+func TestConfigure(t *testing.T) {
+	ctx := context.Background()
+	c := newClient()
+	semaphore := c.Semaphore("client-configured")
+
+	require.Nil(t, semaphore.Configure(ctx, 2))
+
+	// the size given to acquire is ignored
+	_, err := semaphore.Acquire(ctx, 1, 0, time.Minute)
+	require.Nil(t, err)
+	_, err = semaphore.Acquire(ctx, 1, 0, time.Minute)
+	require.Nil(t, err)
+
+	config := &bouncermain.SemaphoreConfig{}
+	require.Nil(t, c.GetJSON(ctx, "/semaphore/client-configured", config))
+	require.Equal(t, uint64(2), config.Size)
+
+	require.ErrorIs(t, semaphore.Configure(ctx, 0), client.ErrInvalidSize)
+	require.Nil(t, c.Configure(ctx, "counter", "client-configured", struct{}{}))
+}
+
+func TestTokenBucketCheck(t *testing.T) {
+	ctx := context.Background()
+	bucket := newClient().TokenBucket("client-bucket-check")
+
+	check, err := bucket.Check(ctx, 2, 2, time.Minute)
+	require.Nil(t, err)
+	require.True(t, check.Allowed)
+	require.Equal(t, uint64(0), check.Remaining)
+
+	check, err = bucket.Check(ctx, 1, 2, time.Minute)
+	require.Nil(t, err)
+	require.False(t, check.Allowed)
+	require.Greater(t, check.RetryAfter, int64(0))
+}
+
+func TestEvent(t *testing.T) {
+	ctx := context.Background()
+	event := newClient().Event("client-event")
+
+	_, err := event.Wait(ctx, 0)
+	require.ErrorIs(t, err, client.ErrTimedOut)
+
+	require.Nil(t, event.Send(ctx, "hello"))
+	require.ErrorIs(t, event.Send(ctx, "again"), client.ErrEventClosed)
+
+	message, err := event.Wait(ctx, client.Forever)
+	require.Nil(t, err)
+	require.Equal(t, "hello", message)
+}
+
+func TestCounter(t *testing.T) {
+	ctx := context.Background()
+	counter := newClient().Counter("client-counter")
+
+	value, err := counter.Count(ctx, 5)
+	require.Nil(t, err)
+	require.Equal(t, int64(5), value)
+
+	value, err = counter.Count(ctx, -2)
+	require.Nil(t, err)
+	require.Equal(t, int64(3), value)
+
+	require.Nil(t, counter.Reset(ctx, 10))
+	value, err = counter.Value(ctx)
+	require.Nil(t, err)
+	require.Equal(t, int64(10), value)
+}
+
+func TestBarrier(t *testing.T) {
+	ctx := context.Background()
+	barrier := newClient().Barrier("client-barrier")
+
+	done := make(chan error)
+	go func() {
+		done <- barrier.Wait(ctx, 2, time.Second)
+	}()
+
+	require.Nil(t, barrier.Wait(ctx, 2, time.Second))
+	require.Nil(t, <-done)
+	require.ErrorIs(t, barrier.Wait(ctx, 2, time.Second), client.ErrBarrierClosed)
+}
+
+func TestWatchdog(t *testing.T) {
+	ctx := context.Background()
+	watchdog := newClient().Watchdog("client-watchdog")
+
+	require.Nil(t, watchdog.Kick(ctx, time.Minute))
+	require.ErrorIs(t, watchdog.Wait(ctx, 0), client.ErrTimedOut)
+
+	require.Nil(t, watchdog.Kick(ctx, 10*time.Millisecond))
+	require.Nil(t, watchdog.Wait(ctx, time.Second))
+
+	stats, err := watchdog.Stats(ctx)
+	require.Nil(t, err)
+	require.Equal(t, uint64(2), stats.Kicks)
+}
+
+func TestContextCancellation(t *testing.T) {
+	event := newClient().Event("client-cancel")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+
+	_, err := event.Wait(ctx, client.Forever)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestContextDeadlineCapsWait(t *testing.T) {
+	watchdog := newClient().Watchdog("client-deadline")
+	require.Nil(t, watchdog.Kick(context.Background(), time.Minute))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// the server gives up at the context deadline instead of waiting forever
+	err := watchdog.Wait(ctx, client.Forever)
+	require.True(t, errors.Is(err, client.ErrTimedOut) || errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestStatusError(t *testing.T) {
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer broken.Close()
+
+	_, err := client.New(broken.URL).Counter("client-broken").Value(context.Background())
+
+	var statusErr *client.StatusError
+	require.ErrorAs(t, err, &statusErr)
+	require.Equal(t, 500, statusErr.Status)
+}
+
+func TestResponseErrors(t *testing.T) {
+	for _, c := range []struct {
+		status int
+		body   string
+		is     []error
+		isNot  []error
+	}{
+		{400, bouncermain.ErrInvalidSize.Error(), []error{client.ErrInvalidSize}, nil},
+		{400, bouncermain.ErrInvalidLimit.Error(), []error{client.ErrInvalidLimit}, nil},
+		{400, bouncermain.ErrInvalidCount.Error(), []error{client.ErrInvalidCount}, nil},
+		{400, bouncermain.ErrInvalidRate.Error(), []error{client.ErrInvalidRate}, nil},
+		{400, bouncermain.ErrInvalidAlgo.Error(), []error{client.ErrInvalidAlgo}, nil},
+		{400, bouncermain.ErrInvalidBurst.Error(), []error{client.ErrInvalidBurst}, nil},
+		{400, bouncermain.ErrInvalidConfig.Error() + ": unknown field", []error{client.ErrInvalidConfig}, nil},
+		{400, bouncermain.ErrInvalidInterval.Error(), []error{client.ErrInvalidInterval}, nil},
+		{400, bouncermain.ErrInvalidName.Error(), []error{client.ErrInvalidName}, nil},
+		{400, bouncermain.ErrNoSubscriptions.Error(), []error{client.ErrNoSubscriptions}, nil},
+		{400, "request: something new", nil, []error{client.ErrInvalidSize, client.ErrInvalidConfig}},
+		{499, bouncermain.ErrCanceled.Error(), []error{client.ErrCanceled}, nil},
+		{409, bouncermain.ErrKeyError.Error(), []error{client.ErrKeyError, client.ErrConflict}, nil},
+		{409, bouncermain.ErrConfigConflict.Error() + ": size 2", []error{client.ErrConfigConflict, client.ErrConflict}, nil},
+		{409, "conflict: something new", []error{client.ErrConflict}, []error{client.ErrKeyError, client.ErrConfigConflict}},
+		{503, bouncermain.ErrShuttingDown.Error(), []error{client.ErrShuttingDown, client.ErrUnavailable}, []error{client.ErrNoLeader}},
+		{503, bouncermain.ErrNotLeader.Error(), []error{client.ErrNotLeader, client.ErrUnavailable}, []error{client.ErrNoLeader}},
+	} {
+		replying := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(c.status)
+			w.Write([]byte(c.body))
+		}))
+
+		_, err := client.New(replying.URL).Counter("client-errors").Value(context.Background())
+		replying.Close()
+
+		var statusErr *client.StatusError
+		require.ErrorAs(t, err, &statusErr)
+		require.Equal(t, c.status, statusErr.Status)
+		for _, target := range c.is {
+			require.ErrorIs(t, err, target, c.body)
+		}
+		for _, target := range c.isNot {
+			require.NotErrorIs(t, err, target, c.body)
+		}
+	}
+}
+
+// The client has its own copies of the server types, so it doesn't pull in
+// the server.
+func TestTypesMatchServer(t *testing.T) {
+	fields := func(v interface{}) map[string]reflect.Type {
+		fields := map[string]reflect.Type{}
+		typ := reflect.TypeOf(v)
+		for i := 0; i < typ.NumField(); i++ {
+			fields[typ.Field(i).Tag.Get("json")] = typ.Field(i).Type
+		}
+		return fields
+	}
+
+	for _, c := range [][2]interface{}{
+		{client.SemaphoreConfig{}, bouncermain.SemaphoreConfig{}},
+		{client.SemaphoreStats{}, bouncermain.SemaphoreStats{}},
+		{client.RWLockStats{}, bouncermain.RWLockStats{}},
+		{client.TokenBucketConfig{}, bouncermain.TokenBucketConfig{}},
+		{client.TokenBucketCheck{}, bouncermain.TokenBucketCheck{}},
+		{client.TokenBucketStats{}, bouncermain.TokenBucketStats{}},
+		{client.RateLimiterConfig{}, bouncermain.RateLimiterConfig{}},
+		{client.RateLimiterStats{}, bouncermain.RateLimiterStats{}},
+		{client.EventStats{}, bouncermain.EventStats{}},
+		{client.CounterStats{}, bouncermain.CounterStats{}},
+		{client.BarrierConfig{}, bouncermain.BarrierConfig{}},
+		{client.BarrierStats{}, bouncermain.BarrierStats{}},
+		{client.WatchdogStats{}, bouncermain.WatchdogStats{}},
+	} {
+		require.Equal(t, fields(c[1]), fields(c[0]), reflect.TypeOf(c[0]).Name())
+	}
+}
+
+func TestToken(t *testing.T) {
+	guarded := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(bouncermain.ErrUnauthorized.Error()))
+			return
+		}
+		w.Write([]byte("42"))
+	}))
+	defer guarded.Close()
+
+	ctx := context.Background()
+	bouncer := client.New(guarded.URL)
+
+	_, err := bouncer.Counter("client-guarded").Value(ctx)
+	require.ErrorIs(t, err, client.ErrUnauthorized)
+
+	bouncer.Token = "secret"
+	value, err := bouncer.Counter("client-guarded").Value(ctx)
+	require.Nil(t, err)
+	require.Equal(t, int64(42), value)
+}
+
+func TestNamespace(t *testing.T) {
+	ctx := context.Background()
+	a, b := newClient(), newClient()
+	a.Namespace, b.Namespace = "client-ns-a", "client-ns-b"
+
+	_, err := a.Counter("client-ns-hits").Count(ctx, 2)
+	require.Nil(t, err)
+	_, err = b.Counter("client-ns-hits").Count(ctx, 1)
+	require.Nil(t, err)
+
+	value, err := a.Counter("client-ns-hits").Value(ctx)
+	require.Nil(t, err)
+	require.Equal(t, int64(2), value)
+
+	value, err = b.Counter("client-ns-hits").Value(ctx)
+	require.Nil(t, err)
+	require.Equal(t, int64(1), value)
+
+	require.Nil(t, a.Counter("client-ns-hits").Delete(ctx))
+	require.Nil(t, b.Counter("client-ns-hits").Delete(ctx))
+}
@@ -0,0 +1,400 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Semaphore limits the number of concurrent holders of a resource.
+type Semaphore struct {
+	c    *Client
+	Name string
+}
+
+func (c *Client) Semaphore(name string) *Semaphore {
+	return &Semaphore{c: c, Name: name}
+}
+
+// Acquire waits up to maxwait for one of size slots and returns the key to
+// release it with. The key expires after expires. A zero size is taken from
+// the server configuration.
+func (s *Semaphore) Acquire(ctx context.Context, size uint64, maxwait time.Duration, expires time.Duration) (key string, err error) {
+	key, _, err = s.AcquireFenced(ctx, size, maxwait, expires)
+	return key, err
+}
+
+// AcquireFenced is like Acquire, and also returns the fencing token of the
+// key, for downstream services to check with Validate.
+func (s *Semaphore) AcquireFenced(ctx context.Context, size uint64, maxwait time.Duration, expires time.Duration) (key string, token uint64, err error) {
+	return s.c.getFenced(ctx, objectPath("semaphore", s.Name, "acquire"), omitZero(url.Values{
+		"size":    {strconv.FormatUint(size, 10)},
+		"maxwait": {millis(capWait(ctx, maxwait))},
+		"expires": {millis(expires)},
+	}, "size"))
+}
+
+// Renew resets the expiration of key to expires from now. Returns
+// ErrKeyError if the key was already released or expired.
+func (s *Semaphore) Renew(ctx context.Context, key string, expires time.Duration) error {
+	_, err := s.c.get(ctx, objectPath("semaphore", s.Name, "renew"), url.Values{
+		"key":     {key},
+		"expires": {millis(expires)},
+	})
+	return err
+}
+
+// Validate checks that token still belongs to a holder of the semaphore, and
+// to key if it's not empty. Returns ErrStaleToken otherwise.
+func (s *Semaphore) Validate(ctx context.Context, key string, token uint64) error {
+	return validate(ctx, s.c, objectPath("semaphore", s.Name, "validate"), key, token)
+}
+
+func validate(ctx context.Context, c *Client, path string, key string, token uint64) error {
+	params := url.Values{"token": {strconv.FormatUint(token, 10)}}
+	if key != "" {
+		params.Set("key", key)
+	}
+	_, err := c.get(ctx, path, params)
+	return err
+}
+
+// Release frees the slot held by key. Returns ErrKeyError if the key was
+// already released or expired.
+func (s *Semaphore) Release(ctx context.Context, key string) error {
+	_, err := s.c.get(ctx, objectPath("semaphore", s.Name, "release"), url.Values{"key": {key}})
+	return err
+}
+
+// Configure creates the semaphore or changes its size. The size given to
+// acquires is ignored from then on.
+func (s *Semaphore) Configure(ctx context.Context, size uint64) error {
+	return s.c.Configure(ctx, "semaphore", s.Name, SemaphoreConfig{Size: size})
+}
+
+func (s *Semaphore) Stats(ctx context.Context) (stats *SemaphoreStats, err error) {
+	stats = &SemaphoreStats{}
+	err = s.c.GetJSON(ctx, objectPath("semaphore", s.Name, "stats"), stats)
+	return stats, err
+}
+
+func (s *Semaphore) Delete(ctx context.Context) error {
+	return s.c.delete(ctx, objectPath("semaphore", s.Name, ""))
+}
+
+// RWLock is held by many readers or a single writer.
+type RWLock struct {
+	c    *Client
+	Name string
+}
+
+func (c *Client) RWLock(name string) *RWLock {
+	return &RWLock{c: c, Name: name}
+}
+
+// RLock waits up to maxwait for a shared read lock and returns the key to
+// unlock it with and its fencing token. The key expires after expires.
+func (l *RWLock) RLock(ctx context.Context, maxwait time.Duration, expires time.Duration) (key string, token uint64, err error) {
+	return l.acquire(ctx, "rlock", maxwait, expires)
+}
+
+// Lock waits up to maxwait for the exclusive write lock and returns the key
+// to unlock it with and its fencing token. The key expires after expires.
+func (l *RWLock) Lock(ctx context.Context, maxwait time.Duration, expires time.Duration) (key string, token uint64, err error) {
+	return l.acquire(ctx, "lock", maxwait, expires)
+}
+
+func (l *RWLock) acquire(ctx context.Context, action string, maxwait time.Duration, expires time.Duration) (key string, token uint64, err error) {
+	return l.c.getFenced(ctx, objectPath("rwlock", l.Name, action), url.Values{
+		"maxwait": {millis(capWait(ctx, maxwait))},
+		"expires": {millis(expires)},
+	})
+}
+
+// Unlock releases the read or write lock held by key. Returns ErrKeyError if
+// the key was already released or expired.
+func (l *RWLock) Unlock(ctx context.Context, key string) error {
+	_, err := l.c.get(ctx, objectPath("rwlock", l.Name, "unlock"), url.Values{"key": {key}})
+	return err
+}
+
+// Validate checks that token still belongs to a holder of the lock, and to
+// key if it's not empty. Returns ErrStaleToken otherwise.
+func (l *RWLock) Validate(ctx context.Context, key string, token uint64) error {
+	return validate(ctx, l.c, objectPath("rwlock", l.Name, "validate"), key, token)
+}
+
+func (l *RWLock) Stats(ctx context.Context) (stats *RWLockStats, err error) {
+	stats = &RWLockStats{}
+	err = l.c.GetJSON(ctx, objectPath("rwlock", l.Name, "stats"), stats)
+	return stats, err
+}
+
+func (l *RWLock) Delete(ctx context.Context) error {
+	return l.c.delete(ctx, objectPath("rwlock", l.Name, ""))
+}
+
+// TokenBucket limits the rate of operations to size per interval.
+type TokenBucket struct {
+	c    *Client
+	Name string
+}
+
+func (c *Client) TokenBucket(name string) *TokenBucket {
+	return &TokenBucket{c: c, Name: name}
+}
+
+// Acquire waits up to maxwait for a token. A zero size or interval is taken
+// from the server configuration.
+func (b *TokenBucket) Acquire(ctx context.Context, size uint64, interval time.Duration, maxwait time.Duration) error {
+	return b.AcquireN(ctx, 1, size, interval, maxwait)
+}
+
+// AcquireN waits up to maxwait for count tokens, taking them all at once.
+func (b *TokenBucket) AcquireN(ctx context.Context, count uint64, size uint64, interval time.Duration, maxwait time.Duration) error {
+	_, err := b.c.get(ctx, objectPath("tokenbucket", b.Name, "acquire"), omitZero(url.Values{
+		"size":     {strconv.FormatUint(size, 10)},
+		"interval": {millis(interval)},
+		"count":    {strconv.FormatUint(count, 10)},
+		"maxwait":  {millis(capWait(ctx, maxwait))},
+	}, "size", "interval"))
+	return err
+}
+
+// Check takes count tokens if they're available right now, without waiting.
+// A denied check isn't an error: Allowed is false, and RetryAfter says when
+// to try again.
+func (b *TokenBucket) Check(ctx context.Context, count uint64, size uint64, interval time.Duration) (check *TokenBucketCheck, err error) {
+	_, body, err := b.c.do(ctx, http.MethodGet, objectPath("tokenbucket", b.Name, "check"), omitZero(url.Values{
+		"size":     {strconv.FormatUint(size, 10)},
+		"interval": {millis(interval)},
+		"count":    {strconv.FormatUint(count, 10)},
+	}, "size", "interval"), nil)
+
+	var statusErr *StatusError
+	denied := errors.As(err, &statusErr) && statusErr.Status == http.StatusTooManyRequests && statusErr.Err == nil
+	if err != nil && !denied {
+		return nil, err
+	}
+
+	check = &TokenBucketCheck{}
+	return check, json.Unmarshal([]byte(body), check)
+}
+
+// Configure creates the bucket or changes its size and interval. The ones
+// given to acquires and checks are ignored from then on.
+func (b *TokenBucket) Configure(ctx context.Context, size uint64, interval time.Duration) error {
+	return b.c.Configure(ctx, "tokenbucket", b.Name, TokenBucketConfig{Size: size, Interval: interval.Milliseconds()})
+}
+
+func (b *TokenBucket) Stats(ctx context.Context) (stats *TokenBucketStats, err error) {
+	stats = &TokenBucketStats{}
+	err = b.c.GetJSON(ctx, objectPath("tokenbucket", b.Name, "stats"), stats)
+	return stats, err
+}
+
+func (b *TokenBucket) Delete(ctx context.Context) error {
+	return b.c.delete(ctx, objectPath("tokenbucket", b.Name, ""))
+}
+
+// RateLimit limits the rate of operations with the GCRA or sliding window
+// algorithm, as given by algo.
+type RateLimit struct {
+	c    *Client
+	Name string
+}
+
+func (c *Client) RateLimit(name string) *RateLimit {
+	return &RateLimit{c: c, Name: name}
+}
+
+// Acquire waits up to maxwait until an operation is allowed at rate per
+// period. Burst is ignored by the sliding window. Zero settings are taken
+// from the server configuration.
+func (l *RateLimit) Acquire(ctx context.Context, algo string, rate uint64, per time.Duration, burst uint64, maxwait time.Duration) error {
+	_, err := l.c.get(ctx, objectPath("ratelimit", l.Name, "acquire"), omitZero(url.Values{
+		"algo":    {algo},
+		"rate":    {strconv.FormatUint(rate, 10)},
+		"per":     {millis(per)},
+		"burst":   {strconv.FormatUint(burst, 10)},
+		"maxwait": {millis(capWait(ctx, maxwait))},
+	}, "algo", "rate", "per", "burst"))
+	return err
+}
+
+// Configure creates the limiter or changes its configuration. The one given
+// to acquires is ignored from then on.
+func (l *RateLimit) Configure(ctx context.Context, algo string, rate uint64, per time.Duration, burst uint64) error {
+	return l.c.Configure(ctx, "ratelimit", l.Name, RateLimiterConfig{
+		Algorithm: algo,
+		Rate:      rate,
+		Per:       per.Milliseconds(),
+		Burst:     burst,
+	})
+}
+
+func (l *RateLimit) Stats(ctx context.Context) (stats *RateLimiterStats, err error) {
+	stats = &RateLimiterStats{}
+	err = l.c.GetJSON(ctx, objectPath("ratelimit", l.Name, "stats"), stats)
+	return stats, err
+}
+
+func (l *RateLimit) Delete(ctx context.Context) error {
+	return l.c.delete(ctx, objectPath("ratelimit", l.Name, ""))
+}
+
+// Event lets clients wait for a one-time signal.
+type Event struct {
+	c    *Client
+	Name string
+}
+
+func (c *Client) Event(name string) *Event {
+	return &Event{c: c, Name: name}
+}
+
+// Wait waits up to maxwait for the event and returns the message it was
+// sent with.
+func (e *Event) Wait(ctx context.Context, maxwait time.Duration) (message string, err error) {
+	return e.c.get(ctx, objectPath("event", e.Name, "wait"), url.Values{
+		"maxwait": {millis(capWait(ctx, maxwait))},
+	})
+}
+
+// Send triggers the event. Returns ErrEventClosed if it was already sent.
+func (e *Event) Send(ctx context.Context, message string) error {
+	_, err := e.c.get(ctx, objectPath("event", e.Name, "send"), url.Values{"message": {message}})
+	return err
+}
+
+func (e *Event) Stats(ctx context.Context) (stats *EventStats, err error) {
+	stats = &EventStats{}
+	err = e.c.GetJSON(ctx, objectPath("event", e.Name, "stats"), stats)
+	return stats, err
+}
+
+func (e *Event) Delete(ctx context.Context) error {
+	return e.c.delete(ctx, objectPath("event", e.Name, ""))
+}
+
+// Counter is a shared integer.
+type Counter struct {
+	c    *Client
+	Name string
+}
+
+func (c *Client) Counter(name string) *Counter {
+	return &Counter{c: c, Name: name}
+}
+
+// Count adds amount to the counter and returns the new value.
+func (n *Counter) Count(ctx context.Context, amount int64) (int64, error) {
+	body, err := n.c.get(ctx, objectPath("counter", n.Name, "count"), url.Values{
+		"amount": {strconv.FormatInt(amount, 10)},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(body, 10, 64)
+}
+
+func (n *Counter) Reset(ctx context.Context, value int64) error {
+	_, err := n.c.get(ctx, objectPath("counter", n.Name, "reset"), url.Values{
+		"value": {strconv.FormatInt(value, 10)},
+	})
+	return err
+}
+
+func (n *Counter) Value(ctx context.Context) (int64, error) {
+	body, err := n.c.get(ctx, objectPath("counter", n.Name, "value"), nil)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(body, 10, 64)
+}
+
+func (n *Counter) Stats(ctx context.Context) (stats *CounterStats, err error) {
+	stats = &CounterStats{}
+	err = n.c.GetJSON(ctx, objectPath("counter", n.Name, "stats"), stats)
+	return stats, err
+}
+
+func (n *Counter) Delete(ctx context.Context) error {
+	return n.c.delete(ctx, objectPath("counter", n.Name, ""))
+}
+
+// Barrier releases its waiters once size of them arrive.
+type Barrier struct {
+	c    *Client
+	Name string
+}
+
+func (c *Client) Barrier(name string) *Barrier {
+	return &Barrier{c: c, Name: name}
+}
+
+// Wait waits up to maxwait for size clients to arrive. Returns
+// ErrBarrierClosed if the barrier was already triggered. A zero size is taken
+// from the server configuration.
+func (b *Barrier) Wait(ctx context.Context, size uint64, maxwait time.Duration) error {
+	_, err := b.c.get(ctx, objectPath("barrier", b.Name, "wait"), omitZero(url.Values{
+		"size":    {strconv.FormatUint(size, 10)},
+		"maxwait": {millis(capWait(ctx, maxwait))},
+	}, "size"))
+	return err
+}
+
+// Configure creates the barrier or changes its size.
+func (b *Barrier) Configure(ctx context.Context, size uint64) error {
+	return b.c.Configure(ctx, "barrier", b.Name, BarrierConfig{Size: size})
+}
+
+func (b *Barrier) Stats(ctx context.Context) (stats *BarrierStats, err error) {
+	stats = &BarrierStats{}
+	err = b.c.GetJSON(ctx, objectPath("barrier", b.Name, "stats"), stats)
+	return stats, err
+}
+
+func (b *Barrier) Delete(ctx context.Context) error {
+	return b.c.delete(ctx, objectPath("barrier", b.Name, ""))
+}
+
+// Watchdog lets clients wait until it's not kicked for some time.
+type Watchdog struct {
+	c    *Client
+	Name string
+}
+
+func (c *Client) Watchdog(name string) *Watchdog {
+	return &Watchdog{c: c, Name: name}
+}
+
+// Kick postpones the watchdog expiration to expires from now.
+func (w *Watchdog) Kick(ctx context.Context, expires time.Duration) error {
+	_, err := w.c.get(ctx, objectPath("watchdog", w.Name, "kick"), url.Values{
+		"expires": {millis(expires)},
+	})
+	return err
+}
+
+// Wait waits up to maxwait for the watchdog to expire.
+func (w *Watchdog) Wait(ctx context.Context, maxwait time.Duration) error {
+	_, err := w.c.get(ctx, objectPath("watchdog", w.Name, "wait"), url.Values{
+		"maxwait": {millis(capWait(ctx, maxwait))},
+	})
+	return err
+}
+
+func (w *Watchdog) Stats(ctx context.Context) (stats *WatchdogStats, err error) {
+	stats = &WatchdogStats{}
+	err = w.c.GetJSON(ctx, objectPath("watchdog", w.Name, "stats"), stats)
+	return stats, err
+}
+
+func (w *Watchdog) Delete(ctx context.Context) error {
+	return w.c.delete(ctx, objectPath("watchdog", w.Name, ""))
+}
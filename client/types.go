@@ -0,0 +1,118 @@
+package client
+
+// The configurations and stats of objects, as sent by the server.
+
+type SemaphoreConfig struct {
+	Size uint64 `json:"size"`
+}
+
+type SemaphoreStats struct {
+	Acquired        uint64  `json:"acquired"`
+	Reacquired      uint64  `json:"reacquired"`
+	Released        uint64  `json:"released"`
+	Expired         uint64  `json:"expired"`
+	Renewed         uint64  `json:"renewed"`
+	TotalWaitTime   uint64  `json:"total_wait_time"`
+	AverageWaitTime float64 `json:"average_wait_time"`
+	TimedOut        uint64  `json:"timed_out"`
+	Canceled        uint64  `json:"canceled"`
+	MaxEverHeld     uint64  `json:"max_ever_held"`
+	CreatedAt       string  `json:"created_at"`
+}
+
+type RWLockStats struct {
+	ReadAcquired    uint64  `json:"read_acquired"`
+	WriteAcquired   uint64  `json:"write_acquired"`
+	Reacquired      uint64  `json:"reacquired"`
+	Released        uint64  `json:"released"`
+	Expired         uint64  `json:"expired"`
+	TotalWaitTime   uint64  `json:"total_wait_time"`
+	AverageWaitTime float64 `json:"average_wait_time"`
+	TimedOut        uint64  `json:"timed_out"`
+	Canceled        uint64  `json:"canceled"`
+	MaxEverReaders  uint64  `json:"max_ever_readers"`
+	CreatedAt       string  `json:"created_at"`
+}
+
+type TokenBucketConfig struct {
+	Size     uint64 `json:"size"`
+	Interval int64  `json:"interval"` // milliseconds
+}
+
+// TokenBucketCheck is the outcome of a non-blocking check.
+type TokenBucketCheck struct {
+	Allowed    bool   `json:"allowed"`
+	Limit      uint64 `json:"limit"`                 // bucket size
+	Remaining  uint64 `json:"remaining"`             // tokens left after the check
+	Reset      int64  `json:"reset"`                 // milliseconds until the next refill
+	RetryAfter int64  `json:"retry_after,omitempty"` // milliseconds until count tokens are available, if denied
+}
+
+type TokenBucketStats struct {
+	Acquired        uint64  `json:"acquired"`
+	Tokens          uint64  `json:"tokens"`
+	TotalWaitTime   uint64  `json:"total_wait_time"`
+	TimedOut        uint64  `json:"timed_out"`
+	Canceled        uint64  `json:"canceled"`
+	Denied          uint64  `json:"denied"`
+	CreatedAt       string  `json:"created_at"`
+	AverageWaitTime float64 `json:"average_wait_time"`
+}
+
+type RateLimiterConfig struct {
+	Algorithm string `json:"algo"`
+	Rate      uint64 `json:"rate"`
+	Per       int64  `json:"per"` // milliseconds
+	Burst     uint64 `json:"burst,omitempty"`
+}
+
+type RateLimiterStats struct {
+	Acquired        uint64  `json:"acquired"`
+	TotalWaitTime   uint64  `json:"total_wait_time"`
+	TimedOut        uint64  `json:"timed_out"`
+	Canceled        uint64  `json:"canceled"`
+	CreatedAt       string  `json:"created_at"`
+	AverageWaitTime float64 `json:"average_wait_time"`
+}
+
+type EventStats struct {
+	Waited          uint64  `json:"waited"`
+	TimedOut        uint64  `json:"timed_out"`
+	Canceled        uint64  `json:"canceled"`
+	Triggered       uint64  `json:"triggered"`
+	TotalWaitTime   uint64  `json:"total_wait_time"`
+	AverageWaitTime float64 `json:"average_wait_time"`
+	CreatedAt       string  `json:"created_at"`
+}
+
+type CounterStats struct {
+	Value      uint64 `json:"value"`
+	Increments uint64 `json:"increments"`
+	Resets     uint64 `json:"resets"`
+	CreatedAt  string `json:"created_at"`
+}
+
+type BarrierConfig struct {
+	Size uint64 `json:"size"`
+}
+
+type BarrierStats struct {
+	Waiting         uint64  `json:"waiting"`
+	Size            uint64  `json:"size"`
+	TotalWaited     uint64  `json:"total_waited"`
+	TimedOut        uint64  `json:"timed_out"`
+	Canceled        uint64  `json:"canceled"`
+	Triggered       uint64  `json:"triggered"`
+	TotalWaitTime   uint64  `json:"total_wait_time"`
+	CreatedAt       string  `json:"created_at"`
+	AverageWaitTime float64 `json:"average_wait_time"`
+}
+
+type WatchdogStats struct {
+	Waited    uint64 `json:"waited"`
+	TimedOut  uint64 `json:"timed_out"`
+	Canceled  uint64 `json:"canceled"`
+	Kicks     uint64 `json:"kicks"`
+	LastKick  string `json:"last_kick"`
+	CreatedAt string `json:"created_at"`
+}
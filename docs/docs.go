@@ -32,11 +32,147 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "503": {
+                        "description": "Service is shutting down",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/barrier/": {
+            "get": {
+                "description": "List existing barriers with their configuration and stats, ordered by name",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Barrier"
+                ],
+                "summary": "List barriers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only list names starting with prefix",
+                        "name": "prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 100,
+                        "description": "Maximum number of items",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only list names after this one, as returned in 'next'",
+                        "name": "after",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Barriers",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.ResourceList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - invalid parameters",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             }
         },
         "/barrier/{name}": {
+            "get": {
+                "description": "Get the current configuration of a barrier",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Barrier"
+                ],
+                "summary": "Get barrier configuration",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Barrier name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Barrier configuration",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.BarrierConfig"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found - barrier not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Create a barrier, or change its size.\n\n### Basic Operation\n- The body is a JSON object like ` + "`" + `{\"size\": 5}` + "`" + `, and missing fields take their defaults\n- Returns 201 with the configuration if the barrier was created, or 200 if it already existed\n- Lowering the size to the number of clients already waiting triggers the barrier\n- The ` + "`" + `size` + "`" + ` passed to ` + "`" + `wait` + "`" + ` only applies when the barrier is created by it\n- In strict mode (` + "`" + `BOUNCER_STRICT=true` + "`" + `) this is the only way to create a barrier\n",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Barrier"
+                ],
+                "summary": "Create or reconfigure a barrier",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Barrier name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Barrier configuration",
+                        "name": "config",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.BarrierConfig"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Barrier reconfigured",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.BarrierConfig"
+                        }
+                    },
+                    "201": {
+                        "description": "Barrier created",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.BarrierConfig"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - invalid configuration",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
             "delete": {
                 "description": "Remove a barrier",
                 "produces": [
@@ -153,7 +289,86 @@ const docTemplate = `{
                         }
                     },
                     "409": {
-                        "description": "Conflict - barrier already completed",
+                        "description": "Conflict - barrier already completed, or parameters differ from the configured ones",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable - server is shutting down",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/cluster/status": {
+            "get": {
+                "description": "Get the raft state of this node and the current leader",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Cluster status",
+                "responses": {
+                    "200": {
+                        "description": "Cluster status",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.ClusterStatus"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found - cluster mode is disabled",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/counter/": {
+            "get": {
+                "description": "List existing counters with their configuration and stats, ordered by name",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Counter"
+                ],
+                "summary": "List counters",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only list names starting with prefix",
+                        "name": "prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 100,
+                        "description": "Maximum number of items",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only list names after this one, as returned in 'next'",
+                        "name": "after",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Counters",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.ResourceList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - invalid parameters",
                         "schema": {
                             "type": "string"
                         }
@@ -162,6 +377,81 @@ const docTemplate = `{
             }
         },
         "/counter/{name}": {
+            "get": {
+                "description": "Get the current configuration of a counter",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Counter"
+                ],
+                "summary": "Get counter configuration",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Counter name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Counter configuration",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found - counter not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Create a counter explicitly, as required in strict mode. Counters have no settings, so the body is empty or ` + "`" + `{}` + "`" + `",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Counter"
+                ],
+                "summary": "Create a counter",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Counter name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Counter reconfigured",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "201": {
+                        "description": "Counter created",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - invalid configuration",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
             "delete": {
                 "description": "Remove a counter",
                 "produces": [
@@ -368,31 +658,46 @@ const docTemplate = `{
                 }
             }
         },
-        "/event/{name}": {
-            "delete": {
-                "description": "Remove an event",
+        "/event/": {
+            "get": {
+                "description": "List existing events with their configuration and stats, ordered by name",
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
                     "Event"
                 ],
-                "summary": "Delete an event",
+                "summary": "List events",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Event name",
-                        "name": "name",
-                        "in": "path",
-                        "required": true
+                        "description": "Only list names starting with prefix",
+                        "name": "prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 100,
+                        "description": "Maximum number of items",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only list names after this one, as returned in 'next'",
+                        "name": "after",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "Event deleted successfully"
+                    "200": {
+                        "description": "Events",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.ResourceList"
+                        }
                     },
-                    "404": {
-                        "description": "Not Found - event not found",
+                    "400": {
+                        "description": "Bad Request - invalid parameters",
                         "schema": {
                             "type": "string"
                         }
@@ -400,16 +705,123 @@ const docTemplate = `{
                 }
             }
         },
-        "/event/{name}/send": {
+        "/event/{name}": {
             "get": {
-                "description": "Triggers an event, sending a message to all waiting clients.\n\n### Basic Operation\n- Sends optional message to all waiting clients\n- Returns immediately\n- Can only be triggered once\n- Already triggered events return 409 Conflict\n\n### Usage Tips\n- Use meaningful messages for easier debugging\n- Empty message is allowed but not recommended\n- All waiting clients receive the same message\n",
+                "description": "Get the current configuration of an event",
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
                     "Event"
                 ],
-                "summary": "Send an event",
+                "summary": "Get event configuration",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Event name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Event configuration",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found - event not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Create an event explicitly, as required in strict mode. Events have no settings, so the body is empty or ` + "`" + `{}` + "`" + `",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Event"
+                ],
+                "summary": "Create an event",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Event name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Event reconfigured",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "201": {
+                        "description": "Event created",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - invalid configuration",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Remove an event",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Event"
+                ],
+                "summary": "Delete an event",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Event name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Event deleted successfully"
+                    },
+                    "404": {
+                        "description": "Not Found - event not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/event/{name}/send": {
+            "get": {
+                "description": "Triggers an event, sending a message to all waiting clients.\n\n### Basic Operation\n- Sends optional message to all waiting clients\n- Returns immediately\n- Can only be triggered once\n- Already triggered events return 409 Conflict\n\n### Usage Tips\n- Use meaningful messages for easier debugging\n- Empty message is allowed but not recommended\n- All waiting clients receive the same message\n",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Event"
+                ],
+                "summary": "Send an event",
                 "parameters": [
                     {
                         "type": "string",
@@ -547,35 +959,49 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable - server is shutting down",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             }
         },
-        "/semaphore/{name}": {
-            "delete": {
-                "description": "Remove a semaphore",
+        "/janitor/stats": {
+            "get": {
+                "description": "Get the number of idle objects evicted, by type",
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
-                    "Semaphore"
+                    "Health"
                 ],
-                "summary": "Delete a semaphore",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Semaphore name",
-                        "name": "name",
-                        "in": "path",
-                        "required": true
+                "summary": "Get janitor statistics",
+                "responses": {
+                    "200": {
+                        "description": "Janitor statistics",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.JanitorStats"
+                        }
                     }
+                }
+            }
+        },
+        "/metrics": {
+            "get": {
+                "description": "Statistics of every live object and HTTP request latency in Prometheus text format",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Health"
                 ],
+                "summary": "Prometheus metrics",
                 "responses": {
-                    "204": {
-                        "description": "Semaphore deleted successfully"
-                    },
-                    "404": {
-                        "description": "Not Found - semaphore not found",
+                    "200": {
+                        "description": "Metrics in Prometheus text exposition format",
                         "schema": {
                             "type": "string"
                         }
@@ -583,57 +1009,42 @@ const docTemplate = `{
                 }
             }
         },
-        "/semaphore/{name}/acquire": {
+        "/ns/": {
             "get": {
-                "description": "A ` + "`" + `semaphore` + "`" + ` can be used to control concurrent access to shared resources.\n\n### Basic Operation\n- Up to ` + "`" + `size` + "`" + ` locks can be held simultaneously\n- Each acquire returns a unique release key\n- Waits up to ` + "`" + `maxwait` + "`" + ` milliseconds for an available lock\n- If ` + "`" + `maxwait` + "`" + ` is negative, waits indefinitely\n- If ` + "`" + `maxwait` + "`" + ` is 0, returns immediately\n\n### Usage Tips\n- Locks expire automatically after ` + "`" + `expires` + "`" + ` milliseconds\n- Set reasonable ` + "`" + `expires` + "`" + ` time to prevent orphaned locks\n- Use ` + "`" + `size\u003e1` + "`" + ` for resource pools\n",
+                "description": "List namespaces with objects in them, with their object counts, ordered by name",
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
-                    "Semaphore"
+                    "Namespace"
                 ],
-                "summary": "Acquire a semaphore",
+                "summary": "List namespaces",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Semaphore name",
-                        "name": "name",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Semaphore size",
-                        "name": "size",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": -1,
-                        "description": "Maximum wait time",
-                        "name": "maxwait",
+                        "description": "Only list namespaces starting with prefix",
+                        "name": "prefix",
                         "in": "query"
                     },
                     {
                         "type": "integer",
-                        "default": 60000,
-                        "description": "Expiration time",
-                        "name": "expires",
+                        "default": 100,
+                        "description": "Maximum number of items",
+                        "name": "limit",
                         "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "Optional request identifier for logging",
-                        "name": "id",
+                        "description": "Only list namespaces after this one, as returned in 'next'",
+                        "name": "after",
                         "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "The semaphore release key",
+                        "description": "Namespaces",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/bouncermain.NamespaceList"
                         }
                     },
                     "400": {
@@ -641,72 +1052,35 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
-                    },
-                    "404": {
-                        "description": "Not Found - semaphore not found",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "408": {
-                        "description": "Request Timeout - ` + "`" + `maxWait` + "`" + ` exceeded",
-                        "schema": {
-                            "type": "string"
-                        }
                     }
                 }
             }
         },
-        "/semaphore/{name}/release": {
-            "get": {
-                "description": "Releases a previously acquired ` + "`" + `semaphore` + "`" + ` lock.\n\n### Basic Operation\n- Release using the key returned by acquire\n- Returns immediately\n- Invalid or already released keys return 409 Conflict\n",
+        "/ns/{namespace}": {
+            "delete": {
+                "description": "Delete every object in a namespace",
                 "produces": [
                     "text/plain"
                 ],
                 "tags": [
-                    "Semaphore"
+                    "Namespace"
                 ],
-                "summary": "Release a semaphore",
+                "summary": "Delete a namespace",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Semaphore name",
-                        "name": "name",
+                        "description": "Namespace name",
+                        "name": "namespace",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Release key",
-                        "name": "key",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Optional request identifier for logging",
-                        "name": "id",
-                        "in": "query"
                     }
                 ],
                 "responses": {
                     "204": {
-                        "description": "Semaphore released successfully"
-                    },
-                    "400": {
-                        "description": "Bad Request - invalid parameters",
-                        "schema": {
-                            "type": "string"
-                        }
+                        "description": "Namespace deleted successfully"
                     },
                     "404": {
-                        "description": "Not Found - semaphore not found",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "409": {
-                        "description": "Conflict - key is invalid or already released",
+                        "description": "Not Found - no objects in namespace",
                         "schema": {
                             "type": "string"
                         }
@@ -714,66 +1088,75 @@ const docTemplate = `{
                 }
             }
         },
-        "/semaphore/{name}/stats": {
+        "/ns/{namespace}/stats": {
             "get": {
-                "description": "Get current statistics for the semaphore",
+                "description": "Get the number of objects in a namespace, in total and by type, and its limit",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Semaphore"
+                    "Namespace"
                 ],
-                "summary": "Get semaphore statistics",
+                "summary": "Get namespace statistics",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Semaphore name",
-                        "name": "name",
+                        "description": "Namespace name",
+                        "name": "namespace",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Semaphore statistics",
-                        "schema": {
-                            "$ref": "#/definitions/bouncermain.SemaphoreStats"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found - semaphore not found",
+                        "description": "Namespace statistics",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/bouncermain.NamespaceStats"
                         }
                     }
                 }
             }
         },
-        "/tokenbucket/{name}": {
-            "delete": {
-                "description": "Remove a token bucket",
+        "/ratelimit/": {
+            "get": {
+                "description": "List existing rate limiters with their configuration and stats, ordered by name",
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
-                    "TokenBucket"
+                    "RateLimit"
                 ],
-                "summary": "Delete a token bucket",
+                "summary": "List rate limiters",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Token bucket name",
-                        "name": "name",
-                        "in": "path",
-                        "required": true
+                        "description": "Only list names starting with prefix",
+                        "name": "prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 100,
+                        "description": "Maximum number of items",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only list names after this one, as returned in 'next'",
+                        "name": "after",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "Token bucket deleted successfully"
+                    "200": {
+                        "description": "Rate limiters",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.ResourceList"
+                        }
                     },
-                    "404": {
-                        "description": "Not Found - token bucket not found",
+                    "400": {
+                        "description": "Bad Request - invalid parameters",
                         "schema": {
                             "type": "string"
                         }
@@ -781,14 +1164,1497 @@ const docTemplate = `{
                 }
             }
         },
-        "/tokenbucket/{name}/acquire": {
+        "/ratelimit/{name}": {
             "get": {
-                "description": "Rate limiting endpoint that implements the Token Bucket algorithm.\n\n### Basic Operation\n- Each request consumes one token\n- Bucket is refilled with ` + "`" + `size` + "`" + ` tokens every ` + "`" + `interval` + "`" + ` milliseconds\n- Waits up to ` + "`" + `maxwait` + "`" + ` milliseconds for available token\n- If ` + "`" + `maxwait` + "`" + ` is negative, waits indefinitely\n- If ` + "`" + `maxwait` + "`" + ` is 0, returns immediately\n\n### Usage Tips\n- For N operations per second, set ` + "`" + `size=N` + "`" + ` and ` + "`" + `interval=1000` + "`" + `\n- For fractional rates, reduce the fraction:\n  - 10 ops/minute: use ` + "`" + `size=1\u0026interval=6000` + "`" + `\n  - Not ` + "`" + `size=10\u0026interval=60000` + "`" + ` (causes long waits)\n- To prevent burst behavior (thundering herd):\n  - Reduce size and interval proportionally\n  - Example: ` + "`" + `size=1\u0026interval=50` + "`" + ` instead of ` + "`" + `size=20\u0026interval=1000` + "`" + `\n  - Note: Very high rates with small intervals increase CPU load",
+                "description": "Get the current configuration of a rate limiter",
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
-                    "TokenBucket"
+                    "RateLimit"
+                ],
+                "summary": "Get rate limiter configuration",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Rate limiter name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Rate limiter configuration",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.RateLimiterConfig"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found - rate limiter not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Create a rate limiter, or change its algorithm and rate.\n\n### Basic Operation\n- The body is a JSON object like ` + "`" + `{\"algo\": \"gcra\", \"rate\": 20, \"per\": 1000, \"burst\": 5}` + "`" + `, and missing fields take their defaults\n- Returns 201 with the configuration if the limiter was created, or 200 if it already existed\n- Changing the configuration resets the limiter\n- Once configured, the parameters passed to ` + "`" + `acquire` + "`" + ` are ignored\n- In strict mode (` + "`" + `BOUNCER_STRICT=true` + "`" + `) this is the only way to create a rate limiter\n",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "RateLimit"
+                ],
+                "summary": "Create or reconfigure a rate limiter",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Rate limiter name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Rate limiter configuration",
+                        "name": "config",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.RateLimiterConfig"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Rate limiter reconfigured",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.RateLimiterConfig"
+                        }
+                    },
+                    "201": {
+                        "description": "Rate limiter created",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.RateLimiterConfig"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - invalid configuration",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Remove a rate limiter",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "RateLimit"
+                ],
+                "summary": "Delete a rate limiter",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Rate limiter name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Rate limiter deleted successfully"
+                    },
+                    "404": {
+                        "description": "Not Found - rate limiter not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/ratelimit/{name}/acquire": {
+            "get": {
+                "description": "Rate limiting endpoint with a choice of algorithm, as an alternative to the token bucket.\n\n### Algorithms\n- ` + "`" + `gcra` + "`" + ` (default): the Generic Cell Rate Algorithm. Requests are spaced evenly, one every ` + "`" + `per/rate` + "`" + ` milliseconds, with up to ` + "`" + `burst` + "`" + ` of them allowed at once after the limiter has been idle. ` + "`" + `burst` + "`" + ` is at most 1000 times ` + "`" + `rate` + "`" + `\n- ` + "`" + `sliding` + "`" + `: a sliding window log. Allows up to ` + "`" + `rate` + "`" + ` requests in any window of ` + "`" + `per` + "`" + ` milliseconds, without the bursts at window boundaries of a fixed window. ` + "`" + `burst` + "`" + ` is ignored, and ` + "`" + `rate` + "`" + ` is at most 10000\n\n### Basic Operation\n- Each request is allowed or waits for its turn\n- Waits up to ` + "`" + `maxwait` + "`" + ` milliseconds for the request to be allowed\n- If ` + "`" + `maxwait` + "`" + ` is negative, waits indefinitely\n- If ` + "`" + `maxwait` + "`" + ` is 0, returns immediately\n- Returns 408 right away if the request can't be allowed within ` + "`" + `maxwait` + "`" + `\n- Changing the parameters of an existing limiter resets it\n\n### Usage Tips\n- For smooth traffic shaping, use ` + "`" + `gcra` + "`" + ` with ` + "`" + `burst=1` + "`" + `\n- For \"at most N per period\" quotas, use ` + "`" + `sliding` + "`" + `\n",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "RateLimit"
+                ],
+                "summary": "Acquire from a GCRA or sliding-window rate limiter",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Rate limiter name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "gcra",
+                        "description": "Algorithm, gcra or sliding",
+                        "name": "algo",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Requests allowed per period",
+                        "name": "rate",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1000,
+                        "description": "Period",
+                        "name": "per",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Requests allowed at once, for gcra, at most 1000 times rate",
+                        "name": "burst",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": -1,
+                        "description": "Maximum wait time",
+                        "name": "maxwait",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Optional request identifier for logging",
+                        "name": "id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Request allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - invalid parameters",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "408": {
+                        "description": "Request Timeout - ` + "`" + `maxwait` + "`" + ` exceeded",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict - parameters differ from the configured ones",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable - server is shutting down",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/ratelimit/{name}/stats": {
+            "get": {
+                "description": "Get rate limiter statistics",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "RateLimit"
+                ],
+                "summary": "View rate limiter stats",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Rate limiter name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Rate limiter statistics",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.RateLimiterStats"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found - rate limiter not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/resources": {
+            "get": {
+                "description": "List objects of every type with their configuration and stats, ordered by type and name",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Resources"
+                ],
+                "summary": "List all objects",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only list names starting with prefix",
+                        "name": "prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 100,
+                        "description": "Maximum number of items",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only list items after this type/name, as returned in 'next'",
+                        "name": "after",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Objects",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.ResourceList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - invalid parameters",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/rwlock/": {
+            "get": {
+                "description": "List existing read-write locks with their holders and stats, ordered by name",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "RWLock"
+                ],
+                "summary": "List read-write locks",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only list names starting with prefix",
+                        "name": "prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 100,
+                        "description": "Maximum number of items",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only list names after this one, as returned in 'next'",
+                        "name": "after",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Read-write locks",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.ResourceList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - invalid parameters",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/rwlock/{name}": {
+            "get": {
+                "description": "Get the current configuration of a read-write lock",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "RWLock"
+                ],
+                "summary": "Get read-write lock configuration",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Lock name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Lock configuration",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.RWLockConfig"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found - lock not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Create a read-write lock explicitly, as required in strict mode. Locks have no settings, so the body is empty or ` + "`" + `{}` + "`" + `",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "RWLock"
+                ],
+                "summary": "Create or reconfigure a read-write lock",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Lock name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Lock configuration",
+                        "name": "config",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.RWLockConfig"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Lock reconfigured",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.RWLockConfig"
+                        }
+                    },
+                    "201": {
+                        "description": "Lock created",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.RWLockConfig"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - invalid configuration",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Remove a read-write lock",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "RWLock"
+                ],
+                "summary": "Delete a read-write lock",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Lock name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Lock deleted successfully"
+                    },
+                    "404": {
+                        "description": "Not Found - lock not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/rwlock/{name}/lock": {
+            "get": {
+                "description": "Acquires the exclusive write lock on an ` + "`" + `rwlock` + "`" + `.\n\n### Basic Operation\n- Only one writer can hold the lock, and only when no readers hold it\n- Each acquire returns a unique release key\n- The ` + "`" + `X-Fencing-Token` + "`" + ` header has an increasing token for the key, see ` + "`" + `validate` + "`" + `\n- Waits up to ` + "`" + `maxwait` + "`" + ` milliseconds for current holders to release the lock\n- Waiting clients get the lock in arrival order\n- If ` + "`" + `maxwait` + "`" + ` is negative, waits indefinitely\n- If ` + "`" + `maxwait` + "`" + ` is 0, returns immediately\n\n### Usage Tips\n- Locks expire automatically after ` + "`" + `expires` + "`" + ` milliseconds\n- Set reasonable ` + "`" + `expires` + "`" + ` time to prevent orphaned locks\n",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "RWLock"
+                ],
+                "summary": "Acquire a write lock",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Lock name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": -1,
+                        "description": "Maximum wait time",
+                        "name": "maxwait",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 60000,
+                        "description": "Expiration time",
+                        "name": "expires",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Optional request identifier for logging",
+                        "name": "id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The lock release key",
+                        "schema": {
+                            "type": "string"
+                        },
+                        "headers": {
+                            "X-Fencing-Token": {
+                                "type": "integer",
+                                "description": "Fencing token of the key"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - invalid parameters",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "408": {
+                        "description": "Request Timeout - ` + "`" + `maxWait` + "`" + ` exceeded",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable - server is shutting down",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/rwlock/{name}/rlock": {
+            "get": {
+                "description": "Acquires a shared read lock on an ` + "`" + `rwlock` + "`" + `.\n\n### Basic Operation\n- Any number of readers can hold the lock at the same time\n- Each acquire returns a unique release key\n- The ` + "`" + `X-Fencing-Token` + "`" + ` header has an increasing token for the key, see ` + "`" + `validate` + "`" + `\n- Waits up to ` + "`" + `maxwait` + "`" + ` milliseconds while a writer holds or is waiting for the lock\n- If ` + "`" + `maxwait` + "`" + ` is negative, waits indefinitely\n- If ` + "`" + `maxwait` + "`" + ` is 0, returns immediately\n\n### Usage Tips\n- Readers arriving after a waiting writer queue behind it, so writers are never starved\n- Locks expire automatically after ` + "`" + `expires` + "`" + ` milliseconds\n",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "RWLock"
+                ],
+                "summary": "Acquire a read lock",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Lock name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": -1,
+                        "description": "Maximum wait time",
+                        "name": "maxwait",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 60000,
+                        "description": "Expiration time",
+                        "name": "expires",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Optional request identifier for logging",
+                        "name": "id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The lock release key",
+                        "schema": {
+                            "type": "string"
+                        },
+                        "headers": {
+                            "X-Fencing-Token": {
+                                "type": "integer",
+                                "description": "Fencing token of the key"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - invalid parameters",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "408": {
+                        "description": "Request Timeout - ` + "`" + `maxWait` + "`" + ` exceeded",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable - server is shutting down",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/rwlock/{name}/stats": {
+            "get": {
+                "description": "Get current statistics for the read-write lock",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "RWLock"
+                ],
+                "summary": "Get read-write lock statistics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Lock name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Lock statistics",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.RWLockStats"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found - lock not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/rwlock/{name}/unlock": {
+            "get": {
+                "description": "Releases a read or write lock previously acquired on an ` + "`" + `rwlock` + "`" + `.\n\n### Basic Operation\n- Release using the key returned by ` + "`" + `rlock` + "`" + ` or ` + "`" + `lock` + "`" + `\n- Returns immediately\n- Invalid or already released keys return 409 Conflict\n",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "RWLock"
+                ],
+                "summary": "Release a read or write lock",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Lock name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Release key",
+                        "name": "key",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Optional request identifier for logging",
+                        "name": "id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Lock released successfully"
+                    },
+                    "400": {
+                        "description": "Bad Request - invalid parameters",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict - key is invalid or already released",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/rwlock/{name}/validate": {
+            "get": {
+                "description": "Checks a fencing token returned by an ` + "`" + `rwlock` + "`" + ` lock or rlock.\n\n### Basic Operation\n- Every acquire returns a fencing token in the ` + "`" + `X-Fencing-Token` + "`" + ` header\n- Tokens increase with every acquire, and keep increasing across restarts\n- Returns 204 No Content if the token belongs to a key still holding the lock\n- Returns 409 Conflict if that key was released or expired\n- If ` + "`" + `key` + "`" + ` is given, the token must also have been issued to it\n",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "RWLock"
+                ],
+                "summary": "Validate a fencing token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Lock name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Fencing token",
+                        "name": "token",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Release key the token was issued with",
+                        "name": "key",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Optional request identifier for logging",
+                        "name": "id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Token is held"
+                    },
+                    "400": {
+                        "description": "Bad Request - invalid parameters",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found - lock not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict - token is stale",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/semaphore/": {
+            "get": {
+                "description": "List existing semaphores with their configuration and stats, ordered by name",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Semaphore"
+                ],
+                "summary": "List semaphores",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only list names starting with prefix",
+                        "name": "prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 100,
+                        "description": "Maximum number of items",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only list names after this one, as returned in 'next'",
+                        "name": "after",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Semaphores",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.ResourceList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - invalid parameters",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/semaphore/{name}": {
+            "get": {
+                "description": "Get the current configuration of a semaphore",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Semaphore"
+                ],
+                "summary": "Get semaphore configuration",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Semaphore name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Semaphore configuration",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.SemaphoreConfig"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found - semaphore not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Create a semaphore, or change its size.\n\n### Basic Operation\n- The body is a JSON object like ` + "`" + `{\"size\": 10}` + "`" + `, and missing fields take their defaults\n- Returns 201 with the configuration if the semaphore was created, or 200 if it already existed\n- Growing the size hands the new slots to waiting clients right away\n- Shrinking it doesn't revoke keys already held, but no new key is granted until the holders are below the new size\n- Once configured, the ` + "`" + `size` + "`" + ` passed to ` + "`" + `acquire` + "`" + ` is ignored\n- In strict mode (` + "`" + `BOUNCER_STRICT=true` + "`" + `) this is the only way to create a semaphore\n",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Semaphore"
+                ],
+                "summary": "Create or reconfigure a semaphore",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Semaphore name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Semaphore configuration",
+                        "name": "config",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.SemaphoreConfig"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Semaphore reconfigured",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.SemaphoreConfig"
+                        }
+                    },
+                    "201": {
+                        "description": "Semaphore created",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.SemaphoreConfig"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - invalid configuration",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Remove a semaphore",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Semaphore"
+                ],
+                "summary": "Delete a semaphore",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Semaphore name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Semaphore deleted successfully"
+                    },
+                    "404": {
+                        "description": "Not Found - semaphore not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/semaphore/{name}/acquire": {
+            "get": {
+                "description": "A ` + "`" + `semaphore` + "`" + ` can be used to control concurrent access to shared resources.\n\n### Basic Operation\n- Up to ` + "`" + `size` + "`" + ` locks can be held simultaneously\n- Each acquire returns a unique release key\n- The ` + "`" + `X-Fencing-Token` + "`" + ` header has an increasing token for the key, see ` + "`" + `validate` + "`" + `\n- Waits up to ` + "`" + `maxwait` + "`" + ` milliseconds for an available lock\n- Waiting clients get locks in arrival order, as soon as they are released\n- If ` + "`" + `maxwait` + "`" + ` is negative, waits indefinitely\n- If ` + "`" + `maxwait` + "`" + ` is 0, returns immediately\n\n### Usage Tips\n- Locks expire automatically after ` + "`" + `expires` + "`" + ` milliseconds\n- Set reasonable ` + "`" + `expires` + "`" + ` time to prevent orphaned locks\n- Use ` + "`" + `size\u003e1` + "`" + ` for resource pools\n",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Semaphore"
+                ],
+                "summary": "Acquire a semaphore",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Semaphore name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Semaphore size",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": -1,
+                        "description": "Maximum wait time",
+                        "name": "maxwait",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 60000,
+                        "description": "Expiration time",
+                        "name": "expires",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Optional request identifier for logging",
+                        "name": "id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The semaphore release key",
+                        "schema": {
+                            "type": "string"
+                        },
+                        "headers": {
+                            "X-Fencing-Token": {
+                                "type": "integer",
+                                "description": "Fencing token of the key"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - invalid parameters",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found - semaphore not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "408": {
+                        "description": "Request Timeout - ` + "`" + `maxWait` + "`" + ` exceeded",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict - parameters differ from the configured ones",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable - server is shutting down",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/semaphore/{name}/release": {
+            "get": {
+                "description": "Releases a previously acquired ` + "`" + `semaphore` + "`" + ` lock.\n\n### Basic Operation\n- Release using the key returned by acquire\n- Returns immediately\n- Invalid or already released keys return 409 Conflict\n",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Semaphore"
+                ],
+                "summary": "Release a semaphore",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Semaphore name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Release key",
+                        "name": "key",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Optional request identifier for logging",
+                        "name": "id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Semaphore released successfully"
+                    },
+                    "400": {
+                        "description": "Bad Request - invalid parameters",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found - semaphore not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict - key is invalid or already released",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/semaphore/{name}/renew": {
+            "get": {
+                "description": "Extends the expiration of a held ` + "`" + `semaphore` + "`" + ` key.\n\n### Basic Operation\n- The key expires ` + "`" + `expires` + "`" + ` milliseconds from now, instead of its previous deadline\n- The key keeps its fencing token\n- Returns immediately\n- Keys already released or expired return 409 Conflict\n\n### Usage Tips\n- Long-running jobs can acquire with a short ` + "`" + `expires` + "`" + ` and renew it periodically, so a crashed job doesn't hold the slot for long\n",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Semaphore"
+                ],
+                "summary": "Renew a semaphore key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Semaphore name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Release key",
+                        "name": "key",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 60000,
+                        "description": "New expiration time, from now",
+                        "name": "expires",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Optional request identifier for logging",
+                        "name": "id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Key renewed successfully"
+                    },
+                    "400": {
+                        "description": "Bad Request - invalid parameters",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found - semaphore not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict - key already released or expired",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/semaphore/{name}/stats": {
+            "get": {
+                "description": "Get current statistics for the semaphore",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Semaphore"
+                ],
+                "summary": "Get semaphore statistics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Semaphore name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Semaphore statistics",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.SemaphoreStats"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found - semaphore not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/semaphore/{name}/validate": {
+            "get": {
+                "description": "Checks a fencing token returned by a ` + "`" + `semaphore` + "`" + ` acquire.\n\n### Basic Operation\n- Every acquire returns a fencing token in the ` + "`" + `X-Fencing-Token` + "`" + ` header\n- Tokens increase with every acquire, and keep increasing across restarts\n- Returns 204 No Content if the token belongs to a key still holding the semaphore\n- Returns 409 Conflict if that key was released or expired\n- If ` + "`" + `key` + "`" + ` is given, the token must also have been issued to it\n\n### Usage Tips\n- Have the client send its token along with writes to a downstream service\n- The downstream service validates the token, or rejects tokens lower than the highest it has seen\n",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Semaphore"
+                ],
+                "summary": "Validate a fencing token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Semaphore name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Fencing token",
+                        "name": "token",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Release key the token was issued with",
+                        "name": "key",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Optional request identifier for logging",
+                        "name": "id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Token is held"
+                    },
+                    "400": {
+                        "description": "Bad Request - invalid parameters",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found - semaphore not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict - token is stale",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/stream": {
+            "get": {
+                "description": "Open a WebSocket to subscribe to notifications and hold semaphore locks, many at once over a single connection.\n\n### Basic Operation\n- Send JSON requests with an ` + "`" + `op` + "`" + `, a ` + "`" + `type` + "`" + ` and a ` + "`" + `name` + "`" + `, and an optional ` + "`" + `id` + "`" + ` echoed in the reply\n- Every request gets a reply with its ` + "`" + `id` + "`" + ` and a ` + "`" + `status` + "`" + `, and an ` + "`" + `error` + "`" + ` if it failed\n- Names are in the namespace of the connection, under ` + "`" + `/ns/\u003cnamespace\u003e/stream` + "`" + `\n- The connection is pinged every 30 seconds, and closed if the client sends nothing, not even a pong, for 60 seconds, or when the server shuts down\n\n### Subscriptions\n- ` + "`" + `{\"op\": \"subscribe\", \"type\": \"event\", \"name\": \"deploy\"}` + "`" + ` notifies with ` + "`" + `\"signal\": \"sent\"` + "`" + ` and the ` + "`" + `message` + "`" + `, once\n- ` + "`" + `type` + "`" + ` ` + "`" + `watchdog` + "`" + ` notifies with ` + "`" + `\"signal\": \"expired\"` + "`" + ` and the ` + "`" + `time` + "`" + ` of every expiration\n- ` + "`" + `type` + "`" + ` ` + "`" + `barrier` + "`" + ` notifies with ` + "`" + `\"signal\": \"released\"` + "`" + `, once; an optional ` + "`" + `size` + "`" + ` creates it like a wait\n- ` + "`" + `type` + "`" + ` ` + "`" + `counter` + "`" + ` notifies with ` + "`" + `\"signal\": \"value\"` + "`" + ` and the ` + "`" + `value` + "`" + ` right away, then on every change\n- ` + "`" + `unsubscribe` + "`" + ` ends a subscription; subscribing again to the same object replaces it\n- A subscription that fails sends a notification with a ` + "`" + `status` + "`" + ` and an ` + "`" + `error` + "`" + `\n\n### Semaphores\n- ` + "`" + `{\"op\": \"acquire\", \"type\": \"semaphore\", \"name\": \"db\", \"size\": 2}` + "`" + ` replies with the ` + "`" + `key` + "`" + ` and ` + "`" + `fencing_token` + "`" + ` once acquired\n- Waits up to ` + "`" + `maxwait` + "`" + ` milliseconds, or indefinitely if unset, while other requests are served\n- The lock is held until ` + "`" + `release` + "`" + ` with its ` + "`" + `key` + "`" + `, or until the connection closes\n- The lock is renewed every third of ` + "`" + `expires` + "`" + ` milliseconds, 60000 by default\n- If renewing fails, a notification with ` + "`" + `\"signal\": \"lost\"` + "`" + ` and the ` + "`" + `key` + "`" + ` is sent\n\n### Usage Tips\n- Browsers can only connect from the same origin\n- Authenticate with the ` + "`" + `Authorization` + "`" + ` header, as for any other endpoint\n- Subscriptions need the same grants as the ` + "`" + `wait` + "`" + ` calls, or ` + "`" + `value` + "`" + ` for counters\n",
+                "tags": [
+                    "Stream"
+                ],
+                "summary": "Subscribe and hold semaphores over a WebSocket",
+                "responses": {
+                    "101": {
+                        "description": "Switching Protocols"
+                    },
+                    "400": {
+                        "description": "Bad Request - not a WebSocket handshake",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable - server is shutting down",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/stream/sse": {
+            "get": {
+                "description": "Stream notifications for any number of objects as Server-Sent Events.\n\n### Basic Operation\n- Subscribe with ` + "`" + `event` + "`" + `, ` + "`" + `watchdog` + "`" + `, ` + "`" + `barrier` + "`" + ` and ` + "`" + `counter` + "`" + ` parameters, each repeatable\n- Each notification is an SSE event named after the type, with the JSON notification as data\n- Events notify with ` + "`" + `\"signal\": \"sent\"` + "`" + ` and the ` + "`" + `message` + "`" + `, once\n- Watchdogs notify with ` + "`" + `\"signal\": \"expired\"` + "`" + ` and the ` + "`" + `time` + "`" + ` of every expiration\n- Barriers notify with ` + "`" + `\"signal\": \"released\"` + "`" + `, once\n- Counters notify with ` + "`" + `\"signal\": \"value\"` + "`" + ` and the ` + "`" + `value` + "`" + ` right away, then on every change\n- The stream ends once every subscription is done, with an ` + "`" + `error` + "`" + ` event if one failed\n\n### Usage Tips\n- Use ` + "`" + `EventSource` + "`" + ` in browsers, or ` + "`" + `curl -N` + "`" + ` to test\n- Comments are sent every 30 seconds to keep idle streams open\n- Prefix with ` + "`" + `/ns/\u003cnamespace\u003e` + "`" + ` to subscribe to objects of a namespace\n",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "Stream"
+                ],
+                "summary": "Stream notifications as Server-Sent Events",
+                "parameters": [
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Event names, notified once sent",
+                        "name": "event",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Watchdog names, notified on every expiration",
+                        "name": "watchdog",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Barrier names, notified once released",
+                        "name": "barrier",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Counter names, notified of the value and every change",
+                        "name": "counter",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Stream of notifications",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.StreamMessage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - invalid parameters",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found - object not found, in strict mode",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/tokenbucket/": {
+            "get": {
+                "description": "List existing token buckets with their configuration and stats, ordered by name",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "TokenBucket"
+                ],
+                "summary": "List token buckets",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only list names starting with prefix",
+                        "name": "prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 100,
+                        "description": "Maximum number of items",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only list names after this one, as returned in 'next'",
+                        "name": "after",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Token buckets",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.ResourceList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - invalid parameters",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/tokenbucket/{name}": {
+            "get": {
+                "description": "Get the current configuration of a token bucket",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "TokenBucket"
+                ],
+                "summary": "Get token bucket configuration",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Token bucket name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Token bucket configuration",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.TokenBucketConfig"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found - token bucket not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Create a token bucket, or change its size and refill interval.\n\n### Basic Operation\n- The body is a JSON object like ` + "`" + `{\"size\": 20, \"interval\": 1000}` + "`" + `, and missing fields take their defaults\n- Returns 201 with the configuration if the bucket was created, or 200 if it already existed\n- The new size takes effect on the next refill\n- Once configured, the ` + "`" + `size` + "`" + ` and ` + "`" + `interval` + "`" + ` passed to ` + "`" + `acquire` + "`" + ` and ` + "`" + `check` + "`" + ` are ignored\n- In strict mode (` + "`" + `BOUNCER_STRICT=true` + "`" + `) this is the only way to create a token bucket\n",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "TokenBucket"
+                ],
+                "summary": "Create or reconfigure a token bucket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Token bucket name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Token bucket configuration",
+                        "name": "config",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.TokenBucketConfig"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Token bucket reconfigured",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.TokenBucketConfig"
+                        }
+                    },
+                    "201": {
+                        "description": "Token bucket created",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.TokenBucketConfig"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - invalid configuration",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Remove a token bucket",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "TokenBucket"
+                ],
+                "summary": "Delete a token bucket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Token bucket name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Token bucket deleted successfully"
+                    },
+                    "404": {
+                        "description": "Not Found - token bucket not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/tokenbucket/{name}/acquire": {
+            "get": {
+                "description": "Rate limiting endpoint that implements the Token Bucket algorithm.\n\n### Basic Operation\n- Each request consumes ` + "`" + `count` + "`" + ` tokens, one by default\n- A request for several tokens takes them all at once, or none\n- Waiting requests are served in order, so a large ` + "`" + `count` + "`" + ` isn't starved by smaller ones\n- ` + "`" + `count` + "`" + ` larger than ` + "`" + `size` + "`" + ` is rejected with 400\n- Bucket is refilled with ` + "`" + `size` + "`" + ` tokens every ` + "`" + `interval` + "`" + ` milliseconds\n- Waits up to ` + "`" + `maxwait` + "`" + ` milliseconds for available token\n- If ` + "`" + `maxwait` + "`" + ` is negative, waits indefinitely\n- If ` + "`" + `maxwait` + "`" + ` is 0, returns immediately\n\n### Usage Tips\n- For N operations per second, set ` + "`" + `size=N` + "`" + ` and ` + "`" + `interval=1000` + "`" + `\n- For fractional rates, reduce the fraction:\n  - 10 ops/minute: use ` + "`" + `size=1\u0026interval=6000` + "`" + `\n  - Not ` + "`" + `size=10\u0026interval=60000` + "`" + ` (causes long waits)\n- For bandwidth or cost limits, set ` + "`" + `size` + "`" + ` to the budget per ` + "`" + `interval` + "`" + ` and\n  ` + "`" + `count` + "`" + ` to the cost of each request\n  - 1 MB/s in bytes: use ` + "`" + `size=1048576\u0026interval=1000\u0026count=\u003cbytes\u003e` + "`" + `\n- To prevent burst behavior (thundering herd):\n  - Reduce size and interval proportionally\n  - Example: ` + "`" + `size=1\u0026interval=50` + "`" + ` instead of ` + "`" + `size=20\u0026interval=1000` + "`" + `\n  - Note: Very high rates with small intervals increase CPU load",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "TokenBucket"
                 ],
                 "summary": "Acquire a token from a token bucket",
                 "parameters": [
@@ -813,6 +2679,13 @@ const docTemplate = `{
                         "name": "interval",
                         "in": "query"
                     },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Number of tokens to take, at most size",
+                        "name": "count",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
                         "default": -1,
@@ -851,6 +2724,112 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "409": {
+                        "description": "Conflict - parameters differ from the configured ones",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable - server is shutting down",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/tokenbucket/{name}/check": {
+            "get": {
+                "description": "Non-blocking version of ` + "`" + `acquire` + "`" + `, for API gateways and other callers that can't hold a connection while waiting.\n\n### Basic Operation\n- Takes ` + "`" + `count` + "`" + ` tokens if they're available right now, and never waits\n- Returns 200 if the tokens were taken, or 429 if not\n- Tokens aren't taken ahead of clients already waiting in ` + "`" + `acquire` + "`" + `\n- The bucket is created and configured with ` + "`" + `size` + "`" + ` and ` + "`" + `interval` + "`" + ` as in ` + "`" + `acquire` + "`" + `\n\n### Response\nThe body reports the state of the bucket after the check:\n- ` + "`" + `allowed` + "`" + `: whether the tokens were taken\n- ` + "`" + `limit` + "`" + `: the bucket size\n- ` + "`" + `remaining` + "`" + `: tokens left\n- ` + "`" + `reset` + "`" + `: milliseconds until the next refill\n- ` + "`" + `retry_after` + "`" + `: milliseconds until the tokens are expected to be available, on denial\n\nThe same is set in the standard headers, in seconds, so they can be forwarded as they are:\n- ` + "`" + `RateLimit-Limit` + "`" + `, ` + "`" + `RateLimit-Remaining` + "`" + `, ` + "`" + `RateLimit-Reset` + "`" + `\n- ` + "`" + `Retry-After` + "`" + `, on denial\n",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "TokenBucket"
+                ],
+                "summary": "Take tokens from a token bucket without waiting",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Token bucket name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Bucket size",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1000,
+                        "description": "Refill interval",
+                        "name": "interval",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Number of tokens to take, at most size",
+                        "name": "count",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Optional request identifier for logging",
+                        "name": "id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Tokens taken",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.TokenBucketCheck"
+                        },
+                        "headers": {
+                            "RateLimit-Limit": {
+                                "type": "integer",
+                                "description": "Bucket size"
+                            },
+                            "RateLimit-Remaining": {
+                                "type": "integer",
+                                "description": "Tokens left"
+                            },
+                            "RateLimit-Reset": {
+                                "type": "integer",
+                                "description": "Seconds until the next refill"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - invalid parameters",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict - parameters differ from the configured ones",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests - not enough tokens",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.TokenBucketCheck"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Seconds until the tokens are available"
+                            }
+                        }
                     }
                 }
             }
@@ -877,29 +2856,159 @@ const docTemplate = `{
                         "description": "Token bucket statistics",
                         "name": "name",
                         "in": "body",
-                        "required": true,
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.TokenBucketStats"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Token bucket statistics",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.TokenBucketStats"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found - token bucket not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/watchdog/": {
+            "get": {
+                "description": "List existing watchdogs with their configuration and stats, ordered by name",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Watchdog"
+                ],
+                "summary": "List watchdogs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only list names starting with prefix",
+                        "name": "prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 100,
+                        "description": "Maximum number of items",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only list names after this one, as returned in 'next'",
+                        "name": "after",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Watchdogs",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.ResourceList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - invalid parameters",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/watchdog/{name}": {
+            "get": {
+                "description": "Get the current configuration of a watchdog",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Watchdog"
+                ],
+                "summary": "Get watchdog configuration",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Watchdog name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Watchdog configuration",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.WatchdogConfig"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found - watchdog not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Create a watchdog.\n\n### Basic Operation\n- The body is a JSON object like ` + "`" + `{\"expires\": 60000}` + "`" + `, the milliseconds until the new watchdog expires\n- Returns 201 with the configuration if the watchdog was created, or 200 if it already existed\n- Each kick sets its own expiration, so an existing watchdog is left as it is\n- In strict mode (` + "`" + `BOUNCER_STRICT=true` + "`" + `) this is the only way to create a watchdog\n",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Watchdog"
+                ],
+                "summary": "Create or reconfigure a watchdog",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Watchdog name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Watchdog configuration",
+                        "name": "config",
+                        "in": "body",
                         "schema": {
-                            "$ref": "#/definitions/bouncermain.TokenBucketStats"
+                            "$ref": "#/definitions/bouncermain.WatchdogConfig"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Token bucket statistics",
+                        "description": "Watchdog reconfigured",
                         "schema": {
-                            "$ref": "#/definitions/bouncermain.TokenBucketStats"
+                            "$ref": "#/definitions/bouncermain.WatchdogConfig"
                         }
                     },
-                    "404": {
-                        "description": "Not Found - token bucket not found",
+                    "201": {
+                        "description": "Watchdog created",
+                        "schema": {
+                            "$ref": "#/definitions/bouncermain.WatchdogConfig"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - invalid configuration",
                         "schema": {
                             "type": "string"
                         }
                     }
                 }
-            }
-        },
-        "/watchdog/{name}": {
+            },
             "delete": {
                 "description": "Remove a watchdog",
                 "produces": [
@@ -1070,18 +3179,35 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable - server is shutting down",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             }
         }
     },
     "definitions": {
+        "bouncermain.BarrierConfig": {
+            "type": "object",
+            "properties": {
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
         "bouncermain.BarrierStats": {
             "type": "object",
             "properties": {
                 "average_wait_time": {
                     "type": "number"
                 },
+                "canceled": {
+                    "type": "integer"
+                },
                 "created_at": {
                     "type": "string"
                 },
@@ -1105,6 +3231,26 @@ const docTemplate = `{
                 }
             }
         },
+        "bouncermain.ClusterStatus": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "leader": {
+                    "type": "string"
+                },
+                "peers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/bouncermain.clusterPeer"
+                    }
+                },
+                "state": {
+                    "type": "string"
+                }
+            }
+        },
         "bouncermain.CounterStats": {
             "type": "object",
             "properties": {
@@ -1128,6 +3274,9 @@ const docTemplate = `{
                 "average_wait_time": {
                     "type": "number"
                 },
+                "canceled": {
+                    "type": "integer"
+                },
                 "created_at": {
                     "type": "string"
                 },
@@ -1145,6 +3294,184 @@ const docTemplate = `{
                 }
             }
         },
+        "bouncermain.JanitorStats": {
+            "type": "object",
+            "properties": {
+                "evicted": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "last_run": {
+                    "type": "string"
+                },
+                "runs": {
+                    "type": "integer"
+                }
+            }
+        },
+        "bouncermain.NamespaceList": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/bouncermain.NamespaceStats"
+                    }
+                },
+                "next": {
+                    "description": "pass as 'after' to get the next page",
+                    "type": "string"
+                }
+            }
+        },
+        "bouncermain.NamespaceStats": {
+            "type": "object",
+            "properties": {
+                "max_objects": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "objects": {
+                    "type": "integer"
+                },
+                "types": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "bouncermain.RWLockConfig": {
+            "type": "object",
+            "properties": {
+                "readers": {
+                    "type": "integer"
+                },
+                "writer": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "bouncermain.RWLockStats": {
+            "type": "object",
+            "properties": {
+                "average_wait_time": {
+                    "type": "number"
+                },
+                "canceled": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "expired": {
+                    "type": "integer"
+                },
+                "max_ever_readers": {
+                    "type": "integer"
+                },
+                "reacquired": {
+                    "type": "integer"
+                },
+                "read_acquired": {
+                    "type": "integer"
+                },
+                "released": {
+                    "type": "integer"
+                },
+                "timed_out": {
+                    "type": "integer"
+                },
+                "total_wait_time": {
+                    "type": "integer"
+                },
+                "write_acquired": {
+                    "type": "integer"
+                }
+            }
+        },
+        "bouncermain.RateLimiterConfig": {
+            "type": "object",
+            "properties": {
+                "algo": {
+                    "type": "string"
+                },
+                "burst": {
+                    "type": "integer"
+                },
+                "per": {
+                    "description": "milliseconds",
+                    "type": "integer"
+                },
+                "rate": {
+                    "type": "integer"
+                }
+            }
+        },
+        "bouncermain.RateLimiterStats": {
+            "type": "object",
+            "properties": {
+                "acquired": {
+                    "type": "integer"
+                },
+                "average_wait_time": {
+                    "type": "number"
+                },
+                "canceled": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "timed_out": {
+                    "type": "integer"
+                },
+                "total_wait_time": {
+                    "type": "integer"
+                }
+            }
+        },
+        "bouncermain.ResourceInfo": {
+            "type": "object",
+            "properties": {
+                "config": {},
+                "name": {
+                    "type": "string"
+                },
+                "stats": {},
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "bouncermain.ResourceList": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/bouncermain.ResourceInfo"
+                    }
+                },
+                "next": {
+                    "description": "pass as 'after' to get the next page",
+                    "type": "string"
+                }
+            }
+        },
+        "bouncermain.SemaphoreConfig": {
+            "type": "object",
+            "properties": {
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
         "bouncermain.SemaphoreStats": {
             "type": "object",
             "properties": {
@@ -1154,6 +3481,9 @@ const docTemplate = `{
                 "average_wait_time": {
                     "type": "number"
                 },
+                "canceled": {
+                    "type": "integer"
+                },
                 "created_at": {
                     "type": "string"
                 },
@@ -1169,6 +3499,9 @@ const docTemplate = `{
                 "released": {
                     "type": "integer"
                 },
+                "renewed": {
+                    "type": "integer"
+                },
                 "timed_out": {
                     "type": "integer"
                 },
@@ -1177,6 +3510,85 @@ const docTemplate = `{
                 }
             }
         },
+        "bouncermain.StreamMessage": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "fencing_token": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "message": {
+                    "description": "of a sent event",
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "signal": {
+                    "description": "sent, expired, released, value or lost",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "HTTP status of a reply, or of a failed subscription",
+                    "type": "integer"
+                },
+                "time": {
+                    "description": "Unix milliseconds of a watchdog expiration",
+                    "type": "integer"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "value": {
+                    "description": "of a counter",
+                    "type": "integer"
+                }
+            }
+        },
+        "bouncermain.TokenBucketCheck": {
+            "type": "object",
+            "properties": {
+                "allowed": {
+                    "type": "boolean"
+                },
+                "limit": {
+                    "description": "bucket size",
+                    "type": "integer"
+                },
+                "remaining": {
+                    "description": "tokens left after the check",
+                    "type": "integer"
+                },
+                "reset": {
+                    "description": "milliseconds until the next refill",
+                    "type": "integer"
+                },
+                "retry_after": {
+                    "description": "milliseconds until count tokens are available, if denied",
+                    "type": "integer"
+                }
+            }
+        },
+        "bouncermain.TokenBucketConfig": {
+            "type": "object",
+            "properties": {
+                "interval": {
+                    "description": "milliseconds",
+                    "type": "integer"
+                },
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
         "bouncermain.TokenBucketStats": {
             "type": "object",
             "properties": {
@@ -1186,20 +3598,44 @@ const docTemplate = `{
                 "average_wait_time": {
                     "type": "number"
                 },
+                "canceled": {
+                    "type": "integer"
+                },
                 "created_at": {
                     "type": "string"
                 },
+                "denied": {
+                    "type": "integer"
+                },
                 "timed_out": {
                     "type": "integer"
                 },
+                "tokens": {
+                    "type": "integer"
+                },
                 "total_wait_time": {
                     "type": "integer"
                 }
             }
         },
+        "bouncermain.WatchdogConfig": {
+            "type": "object",
+            "properties": {
+                "expires": {
+                    "description": "milliseconds, as given in the last kick",
+                    "type": "integer"
+                },
+                "expires_at": {
+                    "type": "string"
+                }
+            }
+        },
         "bouncermain.WatchdogStats": {
             "type": "object",
             "properties": {
+                "canceled": {
+                    "type": "integer"
+                },
                 "created_at": {
                     "type": "string"
                 },
@@ -1216,6 +3652,20 @@ const docTemplate = `{
                     "type": "integer"
                 }
             }
+        },
+        "bouncermain.clusterPeer": {
+            "type": "object",
+            "properties": {
+                "http_addr": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "raft_addr": {
+                    "type": "string"
+                }
+            }
         }
     },
     "tags": [
@@ -1223,10 +3673,18 @@ const docTemplate = `{
             "description": "Rate limiting and traffic shaping",
             "name": "TokenBucket"
         },
+        {
+            "description": "GCRA and sliding-window rate limiting",
+            "name": "RateLimit"
+        },
         {
             "description": "Resource access control and concurrency limits",
             "name": "Semaphore"
         },
+        {
+            "description": "Shared and exclusive access to resources",
+            "name": "RWLock"
+        },
         {
             "description": "One-time broadcast notifications",
             "name": "Event"
@@ -1243,6 +3701,18 @@ const docTemplate = `{
             "description": "Multi-client synchronization points",
             "name": "Barrier"
         },
+        {
+            "description": "Subscriptions over WebSocket and Server-Sent Events",
+            "name": "Stream"
+        },
+        {
+            "description": "Discovery of existing objects",
+            "name": "Resources"
+        },
+        {
+            "description": "Isolated sets of objects, under /ns/{namespace}",
+            "name": "Namespace"
+        },
         {
             "description": "Service health checks",
             "name": "Health"
@@ -1257,7 +3727,7 @@ var SwaggerInfo = &swag.Spec{
 	BasePath:         "/",
 	Schemes:          []string{},
 	Title:            "Bouncer API",
-	Description:      "A lightweight RPC service for distributed application control. Provides primitives for rate limiting, resource synchronization, and process coordination.\n\n### General Concepts\n- Endpoints use GET method with query parameters\n- Clients block until the operation is completed or `maxwait` is reached\n- Resources are created automatically on first use\n- All time values are in milliseconds\n- All numeric parameters are integers\n\n### Quick Tips\n- Test endpoints easily with `curl`, `ab` or your browser\n- Use `maxwait=0` to test resource availability without blocking\n- Monitor resource usage with the `/stats` endpoints\n- Check server readiness at `/.well-known/ready`\n- All endpoints accept an optional `id` parameter for logging\n\n### Status Codes\n- `204 No Content`: Operation completed successfully\n- `200 OK`: Operation completed with data returned\n- `408 Request Timeout`: The `maxwait` time was exceeded\n- `409 Conflict`: Operation conflicts with current state\n\n\n",
+	Description:      "A lightweight RPC service for distributed application control. Provides primitives for rate limiting, resource synchronization, and process coordination.\n\n### General Concepts\n- Endpoints use GET method with query parameters\n- Clients block until the operation is completed or `maxwait` is reached\n- Resources are created automatically on first use\n- All time values are in milliseconds\n- All numeric parameters are integers\n\n### Quick Tips\n- Test endpoints easily with `curl`, `ab` or your browser\n- Use `maxwait=0` to test resource availability without blocking\n- Monitor resource usage with the `/stats` endpoints, or scrape all of them at `/metrics`\n- List existing objects at `/<type>/` or `/resources`, paging with `limit` and `after`\n- Prefix any object path with `/ns/<namespace>` to keep it apart from other namespaces\n- Check server readiness at `/.well-known/ready`\n- All endpoints accept an optional `id` parameter for logging\n\n### Status Codes\n- `204 No Content`: Operation completed successfully\n- `200 OK`: Operation completed with data returned\n- `408 Request Timeout`: The `maxwait` time was exceeded\n- `409 Conflict`: Operation conflicts with current state\n- `429 Too Many Requests`: The namespace has reached its object limit\n- `503 Service Unavailable`: The server is shutting down, or the cluster has no leader\n\n\n",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
 	LeftDelim:        "{{",
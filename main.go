@@ -8,20 +8,46 @@ import (
 
 	"flag"
 	"os"
-	"os/signal"
 	"runtime/pprof"
 
 	"github.com/rs/zerolog/log"
 )
 
 var (
-	cpuprofile  = flag.String("cpuprofile", "", "write cpu profile to file")
-	versionFlag = flag.Bool("version", false, "print version information")
-	version     = "dev"
+	version = "dev"
 )
 
 func main() {
-	flag.Parse()
+	command, args := splitCommand(os.Args[1:])
+
+	switch command {
+	case "serve":
+		serve(args)
+	case "help":
+		fmt.Print(usage)
+	default:
+		ctx, stop := interruptible()
+		code := runCLI(ctx, command, args, os.Stdout, os.Stderr)
+		stop()
+		os.Exit(code)
+	}
+}
+
+// splitCommand returns the command to run and its arguments. Anything but a
+// command runs the server, which ignores positional arguments as it always
+// did.
+func splitCommand(args []string) (command string, rest []string) {
+	if len(args) > 0 && (args[0] == "serve" || args[0] == "help" || cliCommands[args[0]]) {
+		return args[0], args[1:]
+	}
+	return "serve", args
+}
+
+func serve(args []string) {
+	flags := flag.NewFlagSet("bouncer serve", flag.ExitOnError)
+	cpuprofile := flags.String("cpuprofile", "", "write cpu profile to file")
+	versionFlag := flags.Bool("version", false, "print version information")
+	flags.Parse(args)
 
 	if *versionFlag {
 		fmt.Printf("Version: %s\n",
@@ -38,11 +64,7 @@ func main() {
 		pprof.StartCPUProfile(f)
 	}
 
-	go bouncermain.Main()
-
-	terminate := make(chan os.Signal, 1)
-	signal.Notify(terminate, os.Interrupt)
-	<-terminate
+	bouncermain.Main()
 
 	pprof.StopCPUProfile()
 